@@ -21,7 +21,11 @@ func main() {
 	}
 
 	dialog := &editdialog.WebViewEditDialog{}
-	result, ok := dialog.EditEnvs("/test/app/path", input)
+	result, ok := dialog.EditEnvs(editdialog.Request{
+		ApplicationPath: "/test/app/path",
+		Profile:         "default",
+		Values:          input,
+	})
 
 	if !ok {
 		fmt.Fprintln(os.Stderr, "Canceled")
@@ -9,15 +9,18 @@ import (
 func main() {
 	dialog := &permissiondialog.WebViewPermissionDialog{}
 
-	allowed := dialog.AskPermission(
-		"/usr/local/bin/my-secure-app",
-		[]string{"--config", "/etc/myapp.conf", "--verbose"},
-		[]string{"DATABASE_URL", "API_KEY", "SECRET_TOKEN"},
-		permissiondialog.CallerInfo{
+	allowed := dialog.AskPermission(permissiondialog.Request{
+		ApplicationPath: "/usr/local/bin/my-secure-app",
+		Args:            []string{"--config", "/etc/myapp.conf", "--verbose"},
+		EnvNames:        []string{"DATABASE_URL", "API_KEY", "SECRET_TOKEN"},
+		Caller: permissiondialog.CallerInfo{
 			Name: "terminal",
 			PID:  12345,
 		},
-	)
+		Profile:    "prod",
+		Production: true,
+		Warnings:   []string{"API_KEY is already set by the caller and will be overridden"},
+	})
 
 	if allowed {
 		fmt.Println("Permission granted")
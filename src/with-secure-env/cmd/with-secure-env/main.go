@@ -3,17 +3,39 @@
 package main
 
 import (
+	"bufio"
+	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
 	"syscall"
+	"time"
 
 	ps "github.com/mitchellh/go-ps"
 
+	"github.com/kfischer-okarin/with-secure-env/internal/agent"
+	"github.com/kfischer-okarin/with-secure-env/internal/dockercredential"
 	"github.com/kfischer-okarin/with-secure-env/internal/editdialog"
+	"github.com/kfischer-okarin/with-secure-env/internal/envfile"
+	"github.com/kfischer-okarin/with-secure-env/internal/execcredential"
+	"github.com/kfischer-okarin/with-secure-env/internal/gitcredential"
 	"github.com/kfischer-okarin/with-secure-env/internal/keychain"
 	"github.com/kfischer-okarin/with-secure-env/internal/launcher"
 	"github.com/kfischer-okarin/with-secure-env/internal/permissiondialog"
+	"github.com/kfischer-okarin/with-secure-env/internal/procpath"
+	"github.com/kfischer-okarin/with-secure-env/internal/projectfile"
+	"github.com/kfischer-okarin/with-secure-env/internal/pty"
+	"github.com/kfischer-okarin/with-secure-env/internal/redact"
+	"github.com/kfischer-okarin/with-secure-env/internal/secretgen"
+	"github.com/kfischer-okarin/with-secure-env/internal/secretmeta"
+	"github.com/kfischer-okarin/with-secure-env/internal/supervisor"
 )
 
 func main() {
@@ -22,6 +44,12 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Docker runs credential helpers as docker-credential-<name> <operation>,
+	// so a symlink with that name acts like the docker-credential command.
+	if strings.HasPrefix(filepath.Base(os.Args[0]), "docker-credential-") {
+		os.Args = append([]string{os.Args[0], "docker-credential"}, os.Args[1:]...)
+	}
+
 	command := os.Args[1]
 	switch command {
 	case "init":
@@ -30,6 +58,54 @@ func main() {
 		runEdit()
 	case "launch":
 		runLaunch()
+	case "move":
+		runMove()
+	case "copy":
+		runCopy()
+	case "alias":
+		runAlias()
+	case "unalias":
+		runUnalias()
+	case "audit":
+		runAudit()
+	case "config":
+		runConfig()
+	case "list":
+		runList()
+	case "import":
+		runImport()
+	case "export":
+		runExport()
+	case "generate":
+		runGenerate()
+	case "identity":
+		runIdentity()
+	case "share":
+		runShare()
+	case "import-bundle":
+		runImportBundle()
+	case "project":
+		runProject()
+	case "merge-driver":
+		runMergeDriver()
+	case "history":
+		runHistory()
+	case "rollback":
+		runRollback()
+	case "shim":
+		runShim()
+	case "agent":
+		runAgent()
+	case "fetch":
+		runFetch()
+	case "git-credential":
+		runGitCredential()
+	case "docker-credential":
+		runDockerCredential()
+	case "aws-credential-process":
+		runAWSCredentialProcess()
+	case "k8s-credential":
+		runK8sCredential()
 	default:
 		printUsage()
 		os.Exit(1)
@@ -40,9 +116,51 @@ func printUsage() {
 	fmt.Fprintln(os.Stderr, `Usage: with-secure-env <command> [arguments]
 
 Commands:
-  init                      Generate and store encryption key in keychain
-  edit <path/to/app>        Edit environment variables for an application
-  launch <path/to/app> ...  Launch application with injected environment variables`)
+  init                              Generate and store encryption key in keychain
+  edit [--profile p] <app>          Edit environment variables for an application
+  edit --group <name>               Edit a shared group used by several applications
+  edit --project                    Edit the project file (.secure-env) found from the working directory
+  launch [options] <app> ...        Launch application with injected environment variables
+                                    (--profile p, --supervised, --explain)
+  move <old> <new>                  Move environment variables to a new application path
+  copy <src> <dst> [VAR...]         Copy (selected) environment variables to another application
+  alias [<path> <target>]           Let path use the environment variables of target (list without arguments)
+  unalias <path>                    Remove an alias
+  audit [--verify] [options]        Show (--app, --action, --since) or verify the audit log
+  import [options] <app> <file>     Import a dotenv, JSON or YAML file (--profile p, --format f,
+                                    --yes to skip confirmation, --delete to shred the file)
+  export <app> [options]            Print envs as plaintext after approval (--format f, --output file,
+                                    --profile p, --name n, --force to write to a terminal)
+  generate [options] <app> VAR      Store a random secret without showing it (--type t, --length n,
+                                    --charset c, --chars s, --profile p, --group g instead of app, --force)
+  identity                          Show the age recipient and signing fingerprint others share to
+  share <app> --to <recipient>...   Print envs as bundle encrypted for age recipients after approval
+                                    (--profile p, --output file)
+  import-bundle [options] <app> <f> Decrypt a shared bundle and import it (--profile p, --from fingerprint,
+                                    --identity age-key-file, --yes to skip confirmation)
+  project init [--to recipient]...  Create an encrypted .secure-env file to commit in the working directory
+  project [show]                    Show the project file found from the working directory
+  project trust                     Let launches from within the project inject its envs
+  project add-recipient <recipient> Let another age recipient decrypt the project file
+  merge-driver <base> <cur> <other> Merge .secure-env files line by line, used as git merge driver
+  history [options] <app> VAR       List previous values of a variable (--profile p, --group g instead of app)
+  rollback [options] <app> VAR [v]  Restore version v (default: the previous value) of a variable
+                                    (--profile p, --group g instead of app)
+  shim install [options] <app>      Install a wrapper launching app (--dir d, default ~/.local/bin, --name n)
+  shim list                         List installed shims and warn about shadowed ones
+  shim remove <name|path>           Remove an installed shim
+  agent [--idle d]                  Run the agent keeping the key unlocked for launches and fetches
+  agent status|lock|stop            Show agent state, forget the key or stop the agent
+  fetch <app> VAR...                Print requested values as JSON (base64) after approval, used by
+                                    pkg/secureenv (--force to write to a terminal)
+  git-credential get|store|erase    Act as git credential helper storing credentials encrypted
+  docker-credential <operation>     Act as docker credential helper (get, store, erase, list), also
+                                    when run as docker-credential-secure-env
+  aws-credential-process <profile>  Print credential_process JSON of entry aws:<profile> after approval
+  k8s-credential [--ttl d] <name>   Print a kubectl ExecCredential of entry k8s:<name> after approval,
+                                    cached until expiry (default 10m)
+  list                              List applications, profiles and groups with env names
+  config <app> [key [value]]        Show or change application settings (empty value resets)`)
 }
 
 func createLauncher() *launcher.Launcher {
@@ -52,6 +170,7 @@ func createLauncher() *launcher.Launcher {
 		PermissionDialog: &permissiondialog.WebViewPermissionDialog{},
 		ConfigDirPath:    configDir(),
 		Exec:             execProcess,
+		Caller:           getCallerInfo(),
 	}
 }
 
@@ -62,31 +181,989 @@ func runInit() {
 }
 
 func runEdit() {
-	if len(os.Args) < 3 {
+	flags := flag.NewFlagSet("edit", flag.ExitOnError)
+	profile := flags.String("profile", "", "profile to edit instead of the default profile")
+	group := flags.String("group", "", "shared group to edit instead of an application")
+	project := flags.Bool("project", false, "edit the project file found from the working directory")
+	flags.Parse(os.Args[2:])
+
+	if *project {
+		ensureConfigDir()
+		l := createLauncher()
+		exitOnError(l.EditProject())
+		return
+	}
+	if *group != "" {
+		ensureConfigDir()
+		l := createLauncher()
+		exitOnError(l.EditGroup(*group))
+		return
+	}
+	if flags.NArg() < 1 {
 		fmt.Fprintln(os.Stderr, "Error: edit requires an application path")
 		printUsage()
 		os.Exit(1)
 	}
 
 	ensureConfigDir()
-	appPath := resolveAbsolutePath(os.Args[2])
+	appPath := resolveAbsolutePath(flags.Arg(0))
 	l := createLauncher()
-	l.EditEnvs(appPath)
+	exitOnError(l.EditProfile(appPath, *profile))
 }
 
 func runLaunch() {
-	if len(os.Args) < 3 {
+	flags := flag.NewFlagSet("launch", flag.ExitOnError)
+	supervised := flags.Bool("supervised", false, "run the application as supervised child process")
+	profile := flags.String("profile", "", "profile to inject instead of the default profile")
+	explain := flags.Bool("explain", false, "show which entries match the application instead of launching it")
+	flags.Parse(os.Args[2:])
+
+	if flags.NArg() < 1 {
 		fmt.Fprintln(os.Stderr, "Error: launch requires an application path")
 		printUsage()
 		os.Exit(1)
 	}
 
+	appPath := resolveAbsolutePath(flags.Arg(0))
+	args := flags.Args()[1:]
+	options := launcher.LaunchOptions{Supervised: *supervised, Profile: *profile}
+
+	l := createLauncher()
+	if *explain {
+		printExplanation(l.Explain(appPath))
+		return
+	}
+	client := &agent.Client{SocketPath: agentSocketPath()}
+	if _, err := client.Status(); err == nil {
+		l.LaunchAuthorizer = agentLaunchAuthorizer(client)
+	}
+	status, err := l.LaunchWithOptions(appPath, args, l.Caller, options)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	}
+	exitWith(status)
+}
+
+// agentLaunchAuthorizer lets a running agent ask for permission and decrypt
+// the envs of launches, so the keychain isn't queried each time.
+func agentLaunchAuthorizer(client *agent.Client) func(string, []string, permissiondialog.CallerInfo, launcher.LaunchOptions) (map[string]string, error) {
+	return func(applicationPath string, args []string, caller permissiondialog.CallerInfo, options launcher.LaunchOptions) (map[string]string, error) {
+		dir, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+		return client.Launch(agent.Request{App: applicationPath, Args: args, Profile: options.Profile, Env: os.Environ(), Dir: dir})
+	}
+}
+
+func printExplanation(explanation launcher.Explanation) {
+	fmt.Println(explanation.Path)
+	if explanation.AliasOf != "" {
+		fmt.Printf("  alias of %s\n", explanation.AliasOf)
+	}
+	if len(explanation.Matches) == 0 {
+		fmt.Println("  no matching entry, no secrets would be injected")
+		return
+	}
+	for i, match := range explanation.Matches {
+		marker := "   "
+		reason := "less specific"
+		if i == 0 {
+			marker = "  *"
+			reason = "selected"
+		}
+		fmt.Printf("%s %s (%s, %d literal characters) %s\n", marker, match.Entry, match.Kind, match.Specificity, reason)
+	}
+}
+
+// exitWith exits the same way the supervised application did.
+func exitWith(status launcher.ExitStatus) {
+	if status.Signal != 0 {
+		signal.Reset(status.Signal)
+		syscall.Kill(os.Getpid(), status.Signal)
+		os.Exit(128 + int(status.Signal))
+	}
+	os.Exit(status.Code)
+}
+
+func runMove() {
+	if len(os.Args) < 4 {
+		fmt.Fprintln(os.Stderr, "Error: move requires an old and a new application path")
+		printUsage()
+		os.Exit(1)
+	}
+
+	l := createLauncher()
+	exitOnError(l.Move(resolveAbsolutePath(os.Args[2]), resolveAbsolutePath(os.Args[3])))
+}
+
+func runCopy() {
+	if len(os.Args) < 4 {
+		fmt.Fprintln(os.Stderr, "Error: copy requires a source and a destination application path")
+		printUsage()
+		os.Exit(1)
+	}
+
+	l := createLauncher()
+	exitOnError(l.Copy(resolveAbsolutePath(os.Args[2]), resolveAbsolutePath(os.Args[3]), os.Args[4:]))
+}
+
+func runAlias() {
+	l := createLauncher()
+	if len(os.Args) == 2 {
+		aliases := l.Aliases()
+		for _, alias := range sortedKeys(aliases) {
+			fmt.Printf("%s -> %s\n", alias, aliases[alias])
+		}
+		return
+	}
+	if len(os.Args) < 4 {
+		fmt.Fprintln(os.Stderr, "Error: alias requires an alias path and a target application path")
+		printUsage()
+		os.Exit(1)
+	}
+
+	exitOnError(l.Alias(resolveAbsolutePath(os.Args[2]), resolveAbsolutePath(os.Args[3])))
+}
+
+func runUnalias() {
+	if len(os.Args) < 3 {
+		fmt.Fprintln(os.Stderr, "Error: unalias requires an alias path")
+		printUsage()
+		os.Exit(1)
+	}
+
+	l := createLauncher()
+	exitOnError(l.Unalias(resolveAbsolutePath(os.Args[2])))
+}
+
+func runAudit() {
+	flags := flag.NewFlagSet("audit", flag.ExitOnError)
+	app := flags.String("app", "", "only show entries for this application path")
+	action := flags.String("action", "", "only show entries with this action")
+	since := flags.String("since", "", "only show entries since this date (YYYY-MM-DD)")
+	verify := flags.Bool("verify", false, "verify the hash chain of the audit log")
+	flags.Parse(os.Args[2:])
+
+	l := createLauncher()
+	if *verify {
+		exitOnError(l.VerifyAuditLog())
+		fmt.Println("Audit log is intact")
+		return
+	}
+
+	filter := launcher.AuditFilter{Action: *action}
+	if *app != "" {
+		filter.App = resolveAbsolutePath(*app)
+	}
+	if *since != "" {
+		sinceTime, err := time.ParseInLocation("2006-01-02", *since, time.Local)
+		exitOnError(err)
+		filter.Since = sinceTime
+	}
+
+	entries, err := l.AuditEntries(filter)
+	exitOnError(err)
+	for _, entry := range entries {
+		fmt.Printf("%s %s %s %s", entry.Time.Format(time.RFC3339), entry.Action, entry.Result, entry.App)
+		if entry.Profile != "" {
+			fmt.Printf(" profile=%s", entry.Profile)
+		}
+		if entry.Group != "" {
+			fmt.Printf(" group=%s", entry.Group)
+		}
+		if len(entry.Args) > 0 {
+			fmt.Printf(" args=%q", entry.Args)
+		}
+		if len(entry.EnvNames) > 0 {
+			fmt.Printf(" envs=%s", strings.Join(entry.EnvNames, ","))
+		}
+		if len(entry.Caller) > 0 {
+			fmt.Printf(" caller=%s", strings.Join(entry.Caller, "<"))
+		}
+		fmt.Println()
+	}
+}
+
+func runConfig() {
+	if len(os.Args) < 3 {
+		fmt.Fprintln(os.Stderr, "Error: config requires an application path")
+		printUsage()
+		os.Exit(1)
+	}
+
 	appPath := resolveAbsolutePath(os.Args[2])
-	args := os.Args[3:]
-	caller := getCallerInfo()
+	l := createLauncher()
+	if len(os.Args) == 3 {
+		output, _ := json.MarshalIndent(l.Settings(appPath), "", "  ")
+		fmt.Println(string(output))
+		return
+	}
 
+	value := ""
+	if len(os.Args) > 4 {
+		value = os.Args[4]
+	}
+	exitOnError(l.Configure(appPath, os.Args[3], value))
+}
+
+func runList() {
 	l := createLauncher()
-	l.Launch(appPath, args, caller)
+	fmt.Println("Applications:")
+	for _, app := range l.Applications() {
+		fmt.Printf("  %s\n", app.Path)
+		for _, profile := range sortedKeys(app.Profiles) {
+			fmt.Printf("    %s: %s\n", profile, strings.Join(app.Profiles[profile], ", "))
+			printMetadata("      ", app.Profiles[profile], app.Metadata[profile])
+		}
+		if len(app.Groups) > 0 {
+			fmt.Printf("    groups: %s\n", strings.Join(app.Groups, ", "))
+		}
+	}
+	fmt.Println("Groups:")
+	for _, group := range l.Groups() {
+		fmt.Printf("  %s: %s\n", group.Name, strings.Join(group.EnvNames, ", "))
+		printMetadata("    ", group.EnvNames, group.Metadata)
+		for _, path := range group.UsedBy {
+			fmt.Printf("    used by %s\n", path)
+		}
+	}
+}
+
+// printMetadata prints a line per env with metadata.
+func printMetadata(indent string, envNames []string, metadata map[string]secretmeta.Metadata) {
+	now := time.Now()
+	for _, name := range envNames {
+		m, ok := metadata[name]
+		if !ok {
+			continue
+		}
+		var details []string
+		if m.Description != "" {
+			details = append(details, m.Description)
+		}
+		if m.Source != "" {
+			details = append(details, "source "+m.Source)
+		}
+		if !m.Updated.IsZero() {
+			details = append(details, "updated "+m.Updated.Local().Format("2006-01-02"))
+		}
+		if m.Expired(now) {
+			details = append(details, "EXPIRED "+m.Expires.Local().Format("2006-01-02"))
+		} else if !m.Expires.IsZero() {
+			details = append(details, "expires "+m.Expires.Local().Format("2006-01-02"))
+		}
+		fmt.Printf("%s%s: %s\n", indent, name, strings.Join(details, ", "))
+	}
+}
+
+func runImport() {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	profile := flags.String("profile", "", "profile to import into instead of the default profile")
+	format := flags.String("format", "", "dotenv, json or yaml (detected from the file extension by default)")
+	yes := flags.Bool("yes", false, "import without asking for confirmation")
+	deleteFile := flags.Bool("delete", false, "securely overwrite and delete the file after importing without asking")
+	flags.Parse(os.Args[2:])
+
+	if flags.NArg() < 2 {
+		fmt.Fprintln(os.Stderr, "Error: import requires an application path and a file")
+		printUsage()
+		os.Exit(1)
+	}
+
+	appPath := resolveAbsolutePath(flags.Arg(0))
+	file := flags.Arg(1)
+	if *format == "" {
+		*format = envfile.DetectFormat(file)
+	}
+	data, err := os.ReadFile(file)
+	exitOnError(err)
+	values, err := envfile.Parse(*format, data)
+	exitOnError(err)
+
+	ensureConfigDir()
+	l := createLauncher()
+	plan, err := l.PlanImport(appPath, *profile, values)
+	exitOnError(err)
+	fmt.Printf("Importing %d variables into %s (profile %s)\n", len(values), plan.ApplicationPath, plan.Profile)
+	for _, name := range plan.Added {
+		fmt.Printf("  + %s\n", name)
+	}
+	for _, name := range plan.Replaced {
+		fmt.Printf("  ~ %s (replaces current value)\n", name)
+	}
+	if !*yes && !confirm("Import?") {
+		fmt.Println("Import canceled")
+		return
+	}
+	exitOnError(l.Import(appPath, *profile, values))
+
+	if *deleteFile || confirm(fmt.Sprintf("Securely overwrite and delete %s?", file)) {
+		exitOnError(envfile.Shred(file))
+		fmt.Printf("Deleted %s (copies in backups, snapshots or editor swap files are not affected)\n", file)
+	}
+}
+
+var stdin = bufio.NewReader(os.Stdin)
+
+func runExport() {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	format := flags.String("format", envfile.FormatDotenv, strings.Join(envfile.ExportFormats, ", "))
+	output := flags.String("output", "", "write to this file (mode 0600) instead of stdout")
+	profile := flags.String("profile", "", "profile to export instead of the default profile")
+	name := flags.String("name", "", "name of the k8s-secret (defaults to the application's file name)")
+	force := flags.Bool("force", false, "allow writing plaintext secrets to a terminal")
+	positional := parseInterspersed(flags, os.Args[2:])
+
+	if len(positional) < 1 {
+		fmt.Fprintln(os.Stderr, "Error: export requires an application path")
+		printUsage()
+		os.Exit(1)
+	}
+	exitOnError(envfile.ValidateExportFormat(*format))
+	if *output == "" && pty.IsTerminal(os.Stdout) && !*force {
+		exitOnError(fmt.Errorf("refusing to write plaintext secrets to a terminal (use --output or --force)"))
+	}
+
+	destination := "stdout"
+	if *output != "" {
+		destination = resolveAbsolutePath(*output)
+	}
+	l := createLauncher()
+	data, err := l.Export(resolveAbsolutePath(positional[0]), l.Caller, launcher.ExportOptions{
+		Format:      *format,
+		Profile:     *profile,
+		Name:        *name,
+		Destination: destination,
+	})
+	exitOnError(err)
+
+	out := os.Stdout
+	if *output != "" {
+		out, err = os.OpenFile(*output, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+		exitOnError(err)
+		defer out.Close()
+		if pty.IsTerminal(out) && !*force {
+			exitOnError(fmt.Errorf("refusing to write plaintext secrets to a terminal (use --force)"))
+		}
+		exitOnError(out.Chmod(0600))
+	}
+	_, err = out.Write(data)
+	exitOnError(err)
+}
+
+func runGenerate() {
+	flags := flag.NewFlagSet("generate", flag.ExitOnError)
+	kind := flags.String("type", secretgen.KindPassword, strings.Join(secretgen.Kinds, ", "))
+	length := flags.Int("length", 0, "password characters, token bytes or RSA bits")
+	charset := flags.String("charset", "alnum", "password charset: alnum, alpha, numeric or symbols")
+	chars := flags.String("chars", "", "password characters, overriding --charset")
+	profile := flags.String("profile", "", "profile to store into instead of the default profile")
+	group := flags.String("group", "", "shared group to store into instead of an application")
+	force := flags.Bool("force", false, "replace existing values")
+	positional := parseInterspersed(flags, os.Args[2:])
+
+	target := launcher.GenerateTarget{Profile: *profile, Group: *group, Replace: *force}
+	if *group == "" {
+		if len(positional) < 2 {
+			fmt.Fprintln(os.Stderr, "Error: generate requires an application path and an env name")
+			printUsage()
+			os.Exit(1)
+		}
+		target.ApplicationPath = resolveAbsolutePath(positional[0])
+		positional = positional[1:]
+	}
+	if len(positional) != 1 {
+		fmt.Fprintln(os.Stderr, "Error: generate requires exactly one env name")
+		printUsage()
+		os.Exit(1)
+	}
+
+	ensureConfigDir()
+	l := createLauncher()
+	values, err := l.Generate(target, positional[0], secretgen.Options{Kind: *kind, Length: *length, Charset: *charset, Characters: *chars})
+	exitOnError(err)
+	fmt.Fprintf(os.Stderr, "Generated %s\n", strings.Join(sortedKeys(values), ", "))
+	// Public keys are no secrets and usually needed elsewhere.
+	if publicKey, ok := values[positional[0]+secretgen.PublicKeySuffix]; ok {
+		fmt.Print(publicKey)
+	}
+}
+
+func runIdentity() {
+	ensureConfigDir()
+	l := createLauncher()
+	identity, err := l.Identity()
+	exitOnError(err)
+	fmt.Printf("Recipient:   %s\n", identity.Recipient)
+	fmt.Printf("Fingerprint: %s\n", identity.Fingerprint)
+}
+
+// stringList is a flag that can be given multiple times.
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringList) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+func runShare() {
+	flags := flag.NewFlagSet("share", flag.ExitOnError)
+	var recipients stringList
+	flags.Var(&recipients, "to", "age recipient (age1...) able to open the bundle, can be repeated")
+	output := flags.String("output", "", "write to this file instead of stdout")
+	profile := flags.String("profile", "", "profile to share instead of the default profile")
+	positional := parseInterspersed(flags, os.Args[2:])
+
+	if len(positional) != 1 || len(recipients) == 0 {
+		fmt.Fprintln(os.Stderr, "Error: share requires an application path and at least one --to recipient")
+		printUsage()
+		os.Exit(1)
+	}
+
+	destination := "stdout"
+	if *output != "" {
+		destination = resolveAbsolutePath(*output)
+	}
+	ensureConfigDir()
+	l := createLauncher()
+	data, err := l.Share(resolveAbsolutePath(positional[0]), l.Caller, launcher.ShareOptions{
+		Profile:     *profile,
+		Recipients:  recipients,
+		Destination: destination,
+	})
+	exitOnError(err)
+
+	if *output != "" {
+		exitOnError(os.WriteFile(*output, data, 0600))
+		return
+	}
+	fmt.Println(string(data))
+}
+
+func runImportBundle() {
+	flags := flag.NewFlagSet("import-bundle", flag.ExitOnError)
+	profile := flags.String("profile", "", "profile to import into instead of the default profile")
+	from := flags.String("from", "", "require the bundle to be signed with this fingerprint")
+	identityFile := flags.String("identity", "", "age identity file to decrypt with instead of the local identity")
+	yes := flags.Bool("yes", false, "import without asking for confirmation")
+	positional := parseInterspersed(flags, os.Args[2:])
+
+	if len(positional) != 2 {
+		fmt.Fprintln(os.Stderr, "Error: import-bundle requires an application path and a bundle file")
+		printUsage()
+		os.Exit(1)
+	}
+	appPath := resolveAbsolutePath(positional[0])
+	data, err := os.ReadFile(positional[1])
+	exitOnError(err)
+	var options launcher.OpenOptions
+	options.From = *from
+	if *identityFile != "" {
+		identity, err := os.ReadFile(*identityFile)
+		exitOnError(err)
+		options.Identity = string(identity)
+	}
+
+	ensureConfigDir()
+	l := createLauncher()
+	opened, err := l.OpenBundle(data, options)
+	exitOnError(err)
+	plan, err := l.PlanImport(appPath, *profile, opened.Values)
+	exitOnError(err)
+	fmt.Printf("Bundle signed by %s\n", opened.Sender)
+	if *from == "" {
+		fmt.Println("  (compare the fingerprint with the one the sender sees in with-secure-env identity)")
+	}
+	fmt.Printf("Importing %d variables into %s (profile %s)\n", len(opened.Values), plan.ApplicationPath, plan.Profile)
+	for _, name := range plan.Added {
+		fmt.Printf("  + %s\n", name)
+	}
+	for _, name := range plan.Replaced {
+		fmt.Printf("  ~ %s (replaces current value)\n", name)
+	}
+	if !*yes && !confirm("Import?") {
+		fmt.Println("Import canceled")
+		return
+	}
+	exitOnError(l.Import(appPath, *profile, opened.Values))
+}
+
+func runProject() {
+	subcommand := "show"
+	if len(os.Args) > 2 {
+		subcommand = os.Args[2]
+	}
+
+	ensureConfigDir()
+	l := createLauncher()
+	switch subcommand {
+	case "init":
+		flags := flag.NewFlagSet("project init", flag.ExitOnError)
+		var recipients stringList
+		flags.Var(&recipients, "to", "additional age recipient (age1...) able to decrypt, can be repeated")
+		flags.Parse(os.Args[3:])
+		path, err := l.ProjectInit(recipients)
+		exitOnError(err)
+		fmt.Printf("Created %s, add variables with: with-secure-env edit --project\n", path)
+	case "show":
+		info, err := l.Project()
+		exitOnError(err)
+		printProject(info)
+	case "trust":
+		info, err := l.Project()
+		exitOnError(err)
+		printProject(info)
+		if info.Trusted {
+			return
+		}
+		if !confirm("Inject these variables into launches from within the project?") {
+			fmt.Println("Project not trusted")
+			return
+		}
+		exitOnError(l.TrustProject())
+	case "add-recipient":
+		if len(os.Args) != 4 {
+			fmt.Fprintln(os.Stderr, "Error: project add-recipient requires an age recipient")
+			printUsage()
+			os.Exit(1)
+		}
+		exitOnError(l.ProjectAddRecipient(os.Args[3]))
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown project subcommand %s\n", subcommand)
+		printUsage()
+		os.Exit(1)
+	}
+}
+
+func printProject(info launcher.ProjectInfo) {
+	trusted := "not trusted"
+	if info.Trusted {
+		trusted = "trusted"
+	}
+	fmt.Printf("%s (%s)\n", info.Path, trusted)
+	fmt.Println("  Recipients:")
+	for _, recipient := range info.Recipients {
+		fmt.Printf("    %s\n", recipient)
+	}
+	fmt.Printf("  Envs: %s\n", strings.Join(info.EnvNames, ", "))
+}
+
+// runMergeDriver merges project files as configured in .gitattributes. Git
+// expects the result in the current version's file and a non-zero exit status
+// if conflicts remain.
+func runMergeDriver() {
+	if len(os.Args) != 5 {
+		fmt.Fprintln(os.Stderr, "Error: merge-driver requires the files of the base, current and other version")
+		printUsage()
+		os.Exit(1)
+	}
+	var versions [3][]byte
+	for i, path := range os.Args[2:] {
+		data, err := os.ReadFile(path)
+		exitOnError(err)
+		versions[i] = data
+	}
+
+	merged, conflicts, err := projectfile.Merge(versions[0], versions[1], versions[2])
+	exitOnError(err)
+	exitOnError(os.WriteFile(os.Args[3], merged, 0644))
+	if len(conflicts) > 0 {
+		fmt.Fprintf(os.Stderr, "Conflicting changes of %s\n", strings.Join(conflicts, ", "))
+		os.Exit(1)
+	}
+}
+
+func runHistory() {
+	flags := flag.NewFlagSet("history", flag.ExitOnError)
+	profile := flags.String("profile", "", "profile instead of the default profile")
+	group := flags.String("group", "", "shared group instead of an application")
+	target, positional := parseHistoryTarget("history", flags, profile, group)
+	if len(positional) != 1 {
+		fmt.Fprintln(os.Stderr, "Error: history requires exactly one env name")
+		printUsage()
+		os.Exit(1)
+	}
+
+	l := createLauncher()
+	versions, err := l.History(target, positional[0])
+	exitOnError(err)
+	if len(versions) == 0 {
+		fmt.Printf("No previous values of %s\n", positional[0])
+		return
+	}
+	for i := len(versions) - 1; i >= 0; i-- {
+		fmt.Printf("  %d  replaced %s\n", versions[i].Version, versions[i].Replaced.Local().Format("2006-01-02 15:04"))
+	}
+}
+
+func runRollback() {
+	flags := flag.NewFlagSet("rollback", flag.ExitOnError)
+	profile := flags.String("profile", "", "profile instead of the default profile")
+	group := flags.String("group", "", "shared group instead of an application")
+	target, positional := parseHistoryTarget("rollback", flags, profile, group)
+	if len(positional) < 1 || len(positional) > 2 {
+		fmt.Fprintln(os.Stderr, "Error: rollback requires an env name and an optional version")
+		printUsage()
+		os.Exit(1)
+	}
+	version := 0
+	if len(positional) == 2 {
+		var err error
+		version, err = strconv.Atoi(positional[1])
+		if err != nil || version <= 0 {
+			exitOnError(fmt.Errorf("invalid version %s", positional[1]))
+		}
+	}
+
+	ensureConfigDir()
+	l := createLauncher()
+	restored, err := l.Rollback(target, positional[0], version)
+	exitOnError(err)
+	fmt.Fprintf(os.Stderr, "Restored version %d of %s\n", restored, positional[0])
+}
+
+// parseHistoryTarget parses the flags of history and rollback and returns the
+// selected target and the remaining arguments.
+func parseHistoryTarget(command string, flags *flag.FlagSet, profile *string, group *string) (launcher.HistoryTarget, []string) {
+	positional := parseInterspersed(flags, os.Args[2:])
+	target := launcher.HistoryTarget{Profile: *profile, Group: *group}
+	if *group == "" {
+		if len(positional) < 2 {
+			fmt.Fprintf(os.Stderr, "Error: %s requires an application path and an env name\n", command)
+			printUsage()
+			os.Exit(1)
+		}
+		target.ApplicationPath = resolveAbsolutePath(positional[0])
+		positional = positional[1:]
+	}
+	return target, positional
+}
+
+func runShim() {
+	if len(os.Args) < 3 {
+		fmt.Fprintln(os.Stderr, "Error: shim requires a subcommand (install, list or remove)")
+		printUsage()
+		os.Exit(1)
+	}
+
+	l := createLauncher()
+	switch os.Args[2] {
+	case "install":
+		home, _ := os.UserHomeDir()
+		flags := flag.NewFlagSet("shim install", flag.ExitOnError)
+		dir := flags.String("dir", filepath.Join(home, ".local", "bin"), "directory to install the shim to")
+		name := flags.String("name", "", "file name of the shim (defaults to the application's file name)")
+		positional := parseInterspersed(flags, os.Args[3:])
+		if len(positional) < 1 {
+			fmt.Fprintln(os.Stderr, "Error: shim install requires an application path")
+			printUsage()
+			os.Exit(1)
+		}
+
+		executable, err := os.Executable()
+		exitOnError(err)
+		shim, err := l.InstallShim(resolveAbsolutePath(positional[0]), launcher.ShimOptions{
+			BinDir:     resolveAbsolutePath(*dir),
+			Name:       *name,
+			Executable: executable,
+		})
+		exitOnError(err)
+		fmt.Printf("Installed %s -> %s\n", shim.Path, shim.ApplicationPath)
+		printShimWarnings(shim)
+	case "list":
+		for _, shim := range l.Shims() {
+			fmt.Printf("%s -> %s\n", shim.Path, shim.ApplicationPath)
+			printShimWarnings(shim)
+		}
+	case "remove":
+		if len(os.Args) < 4 {
+			fmt.Fprintln(os.Stderr, "Error: shim remove requires a shim name or path")
+			printUsage()
+			os.Exit(1)
+		}
+		pathOrName := os.Args[3]
+		if strings.ContainsRune(pathOrName, '/') {
+			pathOrName = resolveAbsolutePath(pathOrName)
+		}
+		exitOnError(l.RemoveShim(pathOrName))
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown shim subcommand %s\n", os.Args[2])
+		printUsage()
+		os.Exit(1)
+	}
+}
+
+func runFetch() {
+	flags := flag.NewFlagSet("fetch", flag.ExitOnError)
+	force := flags.Bool("force", false, "allow writing plaintext secrets to a terminal")
+	flags.Parse(os.Args[2:])
+
+	if flags.NArg() < 2 {
+		fmt.Fprintln(os.Stderr, "Error: fetch requires an application path and env names")
+		printUsage()
+		os.Exit(1)
+	}
+	if pty.IsTerminal(os.Stdout) && !*force {
+		exitOnError(fmt.Errorf("refusing to write plaintext secrets to a terminal (use --force)"))
+	}
+
+	l := createLauncher()
+	values, err := l.Fetch(resolveAbsolutePath(flags.Arg(0)), flags.Args()[1:], l.Caller)
+	exitOnError(err)
+	secrets := map[string][]byte{}
+	for name, value := range values {
+		secrets[name] = []byte(value)
+	}
+	exitOnError(json.NewEncoder(os.Stdout).Encode(secrets))
+}
+
+func runGitCredential() {
+	if len(os.Args) < 3 {
+		fmt.Fprintln(os.Stderr, "Error: git-credential requires an operation (get, store or erase)")
+		printUsage()
+		os.Exit(1)
+	}
+	credential, err := gitcredential.Read(os.Stdin)
+	exitOnError(err)
+
+	l := createLauncher()
+	switch os.Args[2] {
+	case "get":
+		stored, ok, err := l.GitCredentialGet(credential, l.Caller)
+		exitOnError(err)
+		if ok {
+			exitOnError(gitcredential.Write(os.Stdout, stored))
+		}
+	case "store":
+		ensureConfigDir()
+		exitOnError(l.GitCredentialStore(credential))
+	case "erase":
+		exitOnError(l.GitCredentialErase(credential))
+	}
+	// Other operations are ignored as required by the protocol.
+}
+
+func runDockerCredential() {
+	if len(os.Args) < 3 {
+		fmt.Fprintln(os.Stderr, "Error: docker-credential requires an operation (get, store, erase or list)")
+		printUsage()
+		os.Exit(1)
+	}
+
+	l := createLauncher()
+	var err error
+	switch os.Args[2] {
+	case "get":
+		var serverURL string
+		serverURL, err = dockercredential.ReadServerURL(os.Stdin)
+		if err == nil {
+			var credentials dockercredential.Credentials
+			credentials, err = l.DockerCredentialGet(serverURL, l.Caller)
+			if err == nil {
+				err = json.NewEncoder(os.Stdout).Encode(credentials)
+			}
+		}
+	case "store":
+		var credentials dockercredential.Credentials
+		credentials, err = dockercredential.ReadCredentials(os.Stdin)
+		if err == nil {
+			ensureConfigDir()
+			err = l.DockerCredentialStore(credentials)
+		}
+	case "erase":
+		var serverURL string
+		serverURL, err = dockercredential.ReadServerURL(os.Stdin)
+		if err == nil {
+			err = l.DockerCredentialErase(serverURL)
+		}
+	case "list":
+		err = json.NewEncoder(os.Stdout).Encode(l.DockerCredentials())
+	default:
+		err = fmt.Errorf("unknown operation %s", os.Args[2])
+	}
+	// Docker reads errors from stdout and recognizes
+	// dockercredential.ErrNotFound by its message.
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+func runAWSCredentialProcess() {
+	if len(os.Args) < 3 {
+		fmt.Fprintln(os.Stderr, "Error: aws-credential-process requires an AWS profile name")
+		printUsage()
+		os.Exit(1)
+	}
+
+	l := createLauncher()
+	credentials, err := l.AWSCredentialProcess(os.Args[2], l.Caller)
+	exitOnError(err)
+	exitOnError(json.NewEncoder(os.Stdout).Encode(credentials))
+}
+
+func runK8sCredential() {
+	flags := flag.NewFlagSet("k8s-credential", flag.ExitOnError)
+	ttl := flags.Duration("ttl", 10*time.Minute, "how long an approval is cached (0 disables)")
+	flags.Parse(os.Args[2:])
+
+	if flags.NArg() < 1 {
+		fmt.Fprintln(os.Stderr, "Error: k8s-credential requires a credential name")
+		printUsage()
+		os.Exit(1)
+	}
+	request, err := execcredential.ParseRequest(os.Getenv(execcredential.EnvName))
+	exitOnError(err)
+
+	ensureConfigDir()
+	l := createLauncher()
+	status, err := l.K8sCredential(flags.Arg(0), l.Caller, *ttl)
+	exitOnError(err)
+	response, err := execcredential.Response(request, status)
+	exitOnError(err)
+	exitOnError(json.NewEncoder(os.Stdout).Encode(response))
+}
+
+func runAgent() {
+	client := &agent.Client{SocketPath: agentSocketPath()}
+	if len(os.Args) > 2 && !strings.HasPrefix(os.Args[2], "-") {
+		switch os.Args[2] {
+		case "status":
+			status, err := client.Status()
+			exitOnError(err)
+			state := "locked"
+			if status.Unlocked {
+				state = "unlocked"
+			}
+			fmt.Printf("Agent running (pid %d), key %s\n", status.PID, state)
+		case "lock":
+			exitOnError(client.Lock())
+		case "stop":
+			exitOnError(client.Stop())
+		default:
+			fmt.Fprintf(os.Stderr, "Error: unknown agent subcommand %s\n", os.Args[2])
+			printUsage()
+			os.Exit(1)
+		}
+		return
+	}
+
+	flags := flag.NewFlagSet("agent", flag.ExitOnError)
+	idle := flags.Duration("idle", 15*time.Minute, "lock the key after this long without requests (0 disables)")
+	flags.Parse(os.Args[2:])
+
+	ensureConfigDir()
+	server := &agent.Server{
+		Keychain:     &keychain.MacOSKeychain{},
+		IdleTimeout:  *idle,
+		ScreenLocked: agent.ScreenLocked,
+	}
+
+	// Dialogs have to run on the main thread, so fetch and launch requests are
+	// handed over to it while the server runs in the background.
+	runtime.LockOSThread()
+	mainThread := make(chan func())
+	onMainThread := func(task func()) {
+		done := make(chan struct{})
+		mainThread <- func() {
+			task()
+			close(done)
+		}
+		<-done
+	}
+	l := createLauncher()
+	l.Keychain = server.UnlockedKeychain()
+	server.Fetch = func(applicationPath string, envNames []string, peerPID int) (values map[string]string, err error) {
+		onMainThread(func() {
+			values, err = l.Fetch(applicationPath, envNames, callerChainOf(peerPID))
+		})
+		return values, err
+	}
+	server.Launch = func(request agent.Request, peerPID int) (values map[string]string, err error) {
+		// The launch happens in the environment and directory of the CLI,
+		// whose parent is the caller.
+		cli := *l
+		cli.Environ = func() []string { return request.Env }
+		cli.WorkingDir = func() (string, error) { return request.Dir, nil }
+		caller := callerChainOf(parentPID(peerPID))
+		onMainThread(func() {
+			values, err = cli.AuthorizeLaunch(request.App, request.Args, caller, launcher.LaunchOptions{Profile: request.Profile})
+		})
+		return values, err
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-signals
+		client.Stop()
+	}()
+	serverErr := make(chan error, 1)
+	go func() { serverErr <- server.ListenAndServe(agentSocketPath()) }()
+	fmt.Printf("Agent listening on %s\n", agentSocketPath())
+	for {
+		select {
+		case task := <-mainThread:
+			task()
+		case err := <-serverErr:
+			exitOnError(err)
+			return
+		}
+	}
+}
+
+func agentSocketPath() string {
+	return filepath.Join(configDir(), "agent.sock")
+}
+
+func printShimWarnings(shim launcher.Shim) {
+	for _, warning := range shim.Warnings {
+		fmt.Printf("  Warning: %s\n", warning)
+	}
+}
+
+// parseInterspersed parses flags that may appear before and after positional
+// arguments and returns the positional arguments.
+func parseInterspersed(flags *flag.FlagSet, args []string) []string {
+	var positional []string
+	for {
+		flags.Parse(args)
+		args = flags.Args()
+		if len(args) == 0 {
+			return positional
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// confirm asks a yes/no question on the terminal, defaulting to no.
+func confirm(question string) bool {
+	fmt.Printf("%s [y/N] ", question)
+	answer, _ := stdin.ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+func exitOnError(err error) {
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
 }
 
 func configDir() string {
@@ -98,30 +1175,85 @@ func ensureConfigDir() {
 	os.MkdirAll(configDir(), 0700)
 }
 
+// resolveAbsolutePath makes path absolute, keeping a trailing slash which marks
+// a directory entry.
 func resolveAbsolutePath(path string) string {
+	if launcher.IsCredentialEntry(path) {
+		return path
+	}
 	abs, err := filepath.Abs(path)
 	if err != nil {
 		return path
 	}
+	if strings.HasSuffix(path, "/") && abs != "/" {
+		abs += "/"
+	}
 	return abs
 }
 
 func getCallerInfo() permissiondialog.CallerInfo {
-	ppid := os.Getppid()
+	return callerChainOf(os.Getppid())
+}
+
+// callerChainOf returns the process with its ancestors.
+func callerChainOf(callerPID int) permissiondialog.CallerInfo {
+	caller := processInfo(callerPID)
+
+	pid := caller.PID
+	for len(caller.Parents) < 10 {
+		proc, err := ps.FindProcess(pid)
+		if err != nil || proc == nil || proc.PPid() <= 1 {
+			break
+		}
+		pid = proc.PPid()
+		caller.Parents = append(caller.Parents, processInfo(pid))
+	}
+
+	return caller
+}
+
+func parentPID(pid int) int {
+	proc, err := ps.FindProcess(pid)
+	if err != nil || proc == nil {
+		return 0
+	}
+	return proc.PPid()
+}
+
+func processInfo(pid int) permissiondialog.CallerInfo {
 	name := "unknown"
 
-	if proc, err := ps.FindProcess(ppid); err == nil && proc != nil {
+	if proc, err := ps.FindProcess(pid); err == nil && proc != nil {
 		name = proc.Executable()
 	}
 
+	executable, _ := procpath.Executable(pid)
 	return permissiondialog.CallerInfo{
-		Name: name,
-		PID:  ppid,
+		Name:       name,
+		PID:        pid,
+		Executable: executable,
 	}
 }
 
-func execProcess(path string, args []string, env []string) error {
-	fullArgs := append([]string{path}, args...)
-	fullEnv := append(os.Environ(), env...)
-	return syscall.Exec(path, fullArgs, fullEnv)
+func execProcess(process launcher.Process) (launcher.ExitStatus, error) {
+	if !process.Supervised {
+		fullArgs := append([]string{process.Path}, process.Args...)
+		return launcher.ExitStatus{Code: 1}, syscall.Exec(process.Path, fullArgs, process.Env)
+	}
+
+	options := supervisor.Options{}
+	if len(process.Redact) > 0 {
+		options.OutputFilter = func(out io.Writer) io.WriteCloser {
+			return redact.NewWriter(out, process.Redact)
+		}
+	}
+
+	status, err := supervisor.Run(process.Path, process.Args, process.Env, options)
+	if err != nil {
+		return launcher.ExitStatus{Code: 1}, err
+	}
+	if status.Signaled() {
+		return launcher.ExitStatus{Code: 128 + int(status.Signal()), Signal: status.Signal()}, nil
+	}
+	return launcher.ExitStatus{Code: status.ExitStatus()}, nil
 }
@@ -0,0 +1,331 @@
+// Package agent keeps the encryption key unlocked in a long-running process
+// and serves launches and fetches of secrets to clients of the same user over
+// a Unix socket, so they don't have to query the keychain and start a dialog
+// process each time. The key itself never leaves the agent and values are
+// only returned after the permission dialog was approved.
+//
+// The protocol is one JSON Request per line answered by one JSON Response per
+// line. Clients are authenticated by the user id of the peer process;
+// launching, locking and stopping is only accepted from the with-secure-env
+// binary, identified by the executable of the peer process.
+package agent
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/kfischer-okarin/with-secure-env/internal/keychain"
+	"github.com/kfischer-okarin/with-secure-env/internal/procpath"
+)
+
+// Operations supported by the agent.
+const (
+	// OpStatus reports whether the key is unlocked.
+	OpStatus = "status"
+	// OpLaunch asks for permission and returns the resolved envs of a launch
+	// by the with-secure-env CLI, see Server.Launch.
+	OpLaunch = "launch"
+	// OpFetch asks for permission and returns the values of Request.Names
+	// for Request.App, which must be the executable of the requesting
+	// process, see Server.Fetch.
+	OpFetch = "fetch"
+	// OpLock forgets the key until the next request needs it.
+	OpLock = "lock"
+	// OpStop shuts the agent down.
+	OpStop = "stop"
+)
+
+// ErrAlreadyRunning is returned by ListenAndServe if another agent serves the
+// socket.
+var ErrAlreadyRunning = errors.New("agent is already running")
+
+// Request is sent by clients.
+type Request struct {
+	Op string `json:"op"`
+	// App and Names select the envs for OpFetch.
+	App   string   `json:"app,omitempty"`
+	Names []string `json:"names,omitempty"`
+	// Args and Profile complete App for OpLaunch. Env and Dir are the
+	// environment and working directory of the launching CLI.
+	Args    []string `json:"args,omitempty"`
+	Profile string   `json:"profile,omitempty"`
+	Env     []string `json:"env,omitempty"`
+	Dir     string   `json:"dir,omitempty"`
+}
+
+// Response answers a Request.
+type Response struct {
+	Error    string            `json:"error,omitempty"`
+	Unlocked bool              `json:"unlocked,omitempty"`
+	PID      int               `json:"pid,omitempty"`
+	Values   map[string]string `json:"values,omitempty"`
+}
+
+// Server holds the key in locked memory and answers requests.
+type Server struct {
+	Keychain keychain.Keychain
+	// IdleTimeout locks the key when no request used it for this long. Zero
+	// disables the idle lock.
+	IdleTimeout time.Duration
+	// ScreenLocked is polled to lock the key when the screen gets locked.
+	ScreenLocked func() bool
+	// PollInterval is how often the lock conditions are checked. Defaults to
+	// 5 seconds.
+	PollInterval time.Duration
+	// Fetch serves OpFetch requests, including asking for permission, after
+	// applicationPath was verified to be the executable of the requesting
+	// process peerPID. OpFetch is rejected if nil.
+	Fetch func(applicationPath string, envNames []string, peerPID int) (map[string]string, error)
+	// Launch serves OpLaunch requests, including asking for permission.
+	// peerPID identifies the launching CLI. OpLaunch is rejected if nil.
+	Launch func(request Request, peerPID int) (map[string]string, error)
+	// Executable is the with-secure-env binary, the only peer allowed to lock
+	// and stop the agent. Defaults to the agent's own executable.
+	Executable string
+
+	mu       sync.Mutex
+	key      []byte
+	lastUsed time.Time
+	listener net.Listener
+}
+
+// ListenAndServe creates the socket (mode 0600) and serves requests until the
+// agent is stopped.
+func (s *Server) ListenAndServe(socketPath string) error {
+	if conn, err := net.Dial("unix", socketPath); err == nil {
+		conn.Close()
+		return ErrAlreadyRunning
+	}
+	os.Remove(socketPath)
+
+	oldMask := syscall.Umask(0177)
+	listener, err := net.Listen("unix", socketPath)
+	syscall.Umask(oldMask)
+	if err != nil {
+		return err
+	}
+	if err := os.Chmod(socketPath, 0600); err != nil {
+		listener.Close()
+		return err
+	}
+	return s.Serve(listener)
+}
+
+// Serve answers requests on the listener until the agent is stopped.
+func (s *Server) Serve(listener net.Listener) error {
+	s.mu.Lock()
+	s.listener = listener
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	defer close(done)
+	go s.autoLock(done)
+	defer s.Lock()
+
+	for {
+		conn, err := listener.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		go s.handle(conn)
+	}
+}
+
+// Lock zeroes and forgets the key.
+func (s *Server) Lock() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lockKey()
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	encoder := json.NewEncoder(conn)
+
+	unixConn, ok := conn.(*net.UnixConn)
+	if !ok {
+		encoder.Encode(Response{Error: "unsupported connection"})
+		return
+	}
+	uid, pid, err := peerCredentials(unixConn)
+	if err != nil || uid != os.Getuid() {
+		encoder.Encode(Response{Error: "unauthorized"})
+		return
+	}
+	trusted := s.isWithSecureEnv(pid)
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var request Request
+		if err := json.Unmarshal(scanner.Bytes(), &request); err != nil {
+			encoder.Encode(Response{Error: "invalid request"})
+			return
+		}
+		if request.Op == OpFetch {
+			encoder.Encode(s.fetch(request, pid))
+			continue
+		}
+		if (request.Op == OpLaunch || request.Op == OpLock || request.Op == OpStop) && !trusted {
+			encoder.Encode(Response{Error: "unauthorized"})
+			continue
+		}
+		if request.Op == OpLaunch {
+			encoder.Encode(s.launch(request, pid))
+			continue
+		}
+		encoder.Encode(s.respond(request))
+		if request.Op == OpStop {
+			return
+		}
+	}
+}
+
+func (s *Server) respond(request Request) Response {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch request.Op {
+	case OpStatus:
+		return Response{Unlocked: s.key != nil, PID: os.Getpid()}
+	case OpLock:
+		s.lockKey()
+		return Response{}
+	case OpStop:
+		s.lockKey()
+		if s.listener != nil {
+			s.listener.Close()
+		}
+		return Response{}
+	}
+	return Response{Error: fmt.Sprintf("unknown operation %s", request.Op)}
+}
+
+// isWithSecureEnv reports whether the process pid runs the with-secure-env
+// binary.
+func (s *Server) isWithSecureEnv(pid int) bool {
+	executable := s.Executable
+	if executable == "" {
+		var err error
+		if executable, err = os.Executable(); err != nil {
+			return false
+		}
+	}
+	return procpath.SameExecutable(pid, executable)
+}
+
+// fetch runs without holding s.mu, since Fetch shows a dialog and uses
+// UnlockedKeychain.
+func (s *Server) fetch(request Request, peerPID int) Response {
+	if s.Fetch == nil {
+		return Response{Error: "fetch is not supported by this agent"}
+	}
+	if !procpath.SameExecutable(peerPID, request.App) {
+		return Response{Error: fmt.Sprintf("%s is not the executable of the requesting process", request.App)}
+	}
+	values, err := s.Fetch(request.App, request.Names, peerPID)
+	if err != nil {
+		return Response{Error: err.Error()}
+	}
+	return Response{Values: values}
+}
+
+// launch runs without holding s.mu like fetch.
+func (s *Server) launch(request Request, peerPID int) Response {
+	if s.Launch == nil {
+		return Response{Error: "launch is not supported by this agent"}
+	}
+	values, err := s.Launch(request, peerPID)
+	if err != nil {
+		return Response{Error: err.Error()}
+	}
+	return Response{Values: values}
+}
+
+// UnlockedKeychain returns a keychain serving the agent's key, unlocking it if
+// necessary, for use by Fetch and Launch.
+func (s *Server) UnlockedKeychain() keychain.Keychain {
+	return serverKeychain{s}
+}
+
+type serverKeychain struct {
+	server *Server
+}
+
+func (k serverKeychain) StoreEncryptionKey(key []byte) error {
+	return errors.New("the agent can't store keys")
+}
+
+func (k serverKeychain) RetrieveEncryptionKey() ([]byte, error) {
+	k.server.mu.Lock()
+	defer k.server.mu.Unlock()
+	if err := k.server.unlockKey(); err != nil {
+		return nil, err
+	}
+	return append([]byte(nil), k.server.key...), nil
+}
+
+// unlockKey retrieves the key from the keychain unless it is unlocked already.
+// Must be called with s.mu held.
+func (s *Server) unlockKey() error {
+	s.lastUsed = time.Now()
+	if s.key != nil {
+		return nil
+	}
+	key, err := s.Keychain.RetrieveEncryptionKey()
+	if err != nil {
+		return err
+	}
+	s.key = make([]byte, len(key))
+	syscall.Mlock(s.key)
+	copy(s.key, key)
+	return nil
+}
+
+// lockKey must be called with s.mu held.
+func (s *Server) lockKey() {
+	if s.key == nil {
+		return
+	}
+	clear(s.key)
+	syscall.Munlock(s.key)
+	s.key = nil
+}
+
+func (s *Server) autoLock(done <-chan struct{}) {
+	interval := s.PollInterval
+	if interval == 0 {
+		interval = 5 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+
+		s.mu.Lock()
+		unlocked := s.key != nil
+		idle := s.IdleTimeout > 0 && time.Since(s.lastUsed) > s.IdleTimeout
+		s.mu.Unlock()
+		if !unlocked {
+			continue
+		}
+		if idle || (s.ScreenLocked != nil && s.ScreenLocked()) {
+			s.Lock()
+		}
+	}
+}
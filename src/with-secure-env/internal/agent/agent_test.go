@@ -0,0 +1,283 @@
+package agent
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kfischer-okarin/with-secure-env/internal/envcrypt"
+)
+
+func TestAgent_UnlocksKeyFromKeychainOnce(t *testing.T) {
+	kc := &stubKeychain{key: testKey()}
+	client := startAgent(t, newServer(kc))
+
+	first, err := client.Fetch(executable(t), []string{"API_KEY"})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	second, _ := client.Fetch(executable(t), []string{"API_KEY"})
+
+	if first["API_KEY"] != "secret" || second["API_KEY"] != "secret" {
+		t.Errorf("expected values to be decrypted, got %v and %v", first, second)
+	}
+	if kc.retrieveCount != 1 {
+		t.Errorf("expected one keychain access, got %d", kc.retrieveCount)
+	}
+}
+
+func TestAgent_HandsOutNeitherKeyNorDecryptions(t *testing.T) {
+	client := startAgent(t, newServer(&stubKeychain{key: testKey()}))
+
+	for _, op := range []string{"key", "decrypt"} {
+		if response, err := client.call(Request{Op: op}); err == nil {
+			t.Errorf("expected %s to be rejected, got %+v", op, response)
+		}
+	}
+}
+
+func TestAgent_LockForgetsKey(t *testing.T) {
+	kc := &stubKeychain{key: testKey()}
+	client := startAgent(t, newServer(kc))
+	client.Fetch(executable(t), []string{"API_KEY"})
+
+	client.Lock()
+
+	status, _ := client.Status()
+	if status.Unlocked {
+		t.Error("expected agent to be locked")
+	}
+	client.Fetch(executable(t), []string{"API_KEY"})
+	if kc.retrieveCount != 2 {
+		t.Errorf("expected key to be retrieved again, got %d accesses", kc.retrieveCount)
+	}
+}
+
+func TestAgent_OnlyWithSecureEnvMayLockAndStop(t *testing.T) {
+	server := newServer(&stubKeychain{key: testKey()})
+	server.Executable = filepath.Join(t.TempDir(), "with-secure-env")
+	os.WriteFile(server.Executable, nil, 0755)
+	client := startAgent(t, server)
+	client.Fetch(executable(t), []string{"API_KEY"})
+
+	if err := client.Lock(); err == nil {
+		t.Error("expected lock from another executable to be rejected")
+	}
+	if err := client.Stop(); err == nil {
+		t.Error("expected stop from another executable to be rejected")
+	}
+	if status, _ := client.Status(); !status.Unlocked {
+		t.Error("expected agent to stay unlocked")
+	}
+	server.Lock()
+	server.listener.Close()
+}
+
+func TestAgent_LocksAfterIdleTimeout(t *testing.T) {
+	server := newServer(&stubKeychain{key: testKey()})
+	server.IdleTimeout, server.PollInterval = 20*time.Millisecond, 5*time.Millisecond
+	client := startAgent(t, server)
+	client.Fetch(executable(t), []string{"API_KEY"})
+
+	waitUntilLocked(t, client)
+}
+
+func TestAgent_LocksWhenScreenIsLocked(t *testing.T) {
+	var screenLocked atomic.Bool
+	server := newServer(&stubKeychain{key: testKey()})
+	server.ScreenLocked, server.PollInterval = screenLocked.Load, 5*time.Millisecond
+	client := startAgent(t, server)
+	client.Fetch(executable(t), []string{"API_KEY"})
+
+	screenLocked.Store(true)
+
+	waitUntilLocked(t, client)
+}
+
+func TestAgent_SocketIsOnlyAccessibleByOwner(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "agent.sock")
+	server := &Server{Keychain: &stubKeychain{key: testKey()}}
+	go server.ListenAndServe(socketPath)
+	client := &Client{SocketPath: socketPath}
+	waitForAgent(t, client)
+	t.Cleanup(func() { client.Stop() })
+
+	info, err := os.Stat(socketPath)
+	if err != nil {
+		t.Fatalf("expected socket to exist, got %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("expected socket mode 0600, got %v", info.Mode().Perm())
+	}
+	if err := server.ListenAndServe(socketPath); !errors.Is(err, ErrAlreadyRunning) {
+		t.Errorf("expected ErrAlreadyRunning, got %v", err)
+	}
+}
+
+func TestAgent_FetchPassesRequestAndPeerPID(t *testing.T) {
+	var receivedApp string
+	var receivedPID int
+	server := &Server{
+		Keychain: &stubKeychain{key: testKey()},
+		Fetch: func(applicationPath string, envNames []string, peerPID int) (map[string]string, error) {
+			receivedApp, receivedPID = applicationPath, peerPID
+			return map[string]string{envNames[0]: "secret"}, nil
+		},
+	}
+	client := startAgent(t, server)
+
+	values, err := client.Fetch(executable(t), []string{"API_KEY"})
+
+	if err != nil || values["API_KEY"] != "secret" {
+		t.Errorf("expected API_KEY=secret, got %v, %v", values, err)
+	}
+	if receivedApp != executable(t) || receivedPID != os.Getpid() {
+		t.Errorf("expected request for %s from pid %d, got %s from %d", executable(t), os.Getpid(), receivedApp, receivedPID)
+	}
+}
+
+func TestAgent_FetchRejectsAppOfOtherExecutable(t *testing.T) {
+	fetched := false
+	server := &Server{
+		Keychain: &stubKeychain{key: testKey()},
+		Fetch: func(applicationPath string, envNames []string, peerPID int) (map[string]string, error) {
+			fetched = true
+			return nil, nil
+		},
+	}
+	client := startAgent(t, server)
+
+	_, err := client.Fetch("/usr/bin/env", []string{"API_KEY"})
+
+	if err == nil || fetched {
+		t.Errorf("expected request for another executable to be rejected, got %v", err)
+	}
+}
+
+func TestAgent_LaunchPassesRequestOfWithSecureEnv(t *testing.T) {
+	var received Request
+	server := newServer(&stubKeychain{key: testKey()})
+	server.Launch = func(request Request, peerPID int) (map[string]string, error) {
+		received = request
+		return map[string]string{"API_KEY": "secret"}, nil
+	}
+	client := startAgent(t, server)
+
+	values, err := client.Launch(Request{App: "/path/to/app", Args: []string{"--flag"}, Profile: "staging", Env: []string{"HOME=/home/user"}, Dir: "/work"})
+
+	if err != nil || values["API_KEY"] != "secret" {
+		t.Errorf("expected API_KEY=secret, got %v, %v", values, err)
+	}
+	if received.App != "/path/to/app" || received.Args[0] != "--flag" || received.Profile != "staging" || received.Env[0] != "HOME=/home/user" || received.Dir != "/work" {
+		t.Errorf("unexpected request %+v", received)
+	}
+}
+
+func TestAgent_RejectsLaunchFromOtherExecutables(t *testing.T) {
+	launched := false
+	server := newServer(&stubKeychain{key: testKey()})
+	server.Launch = func(request Request, peerPID int) (map[string]string, error) {
+		launched = true
+		return nil, nil
+	}
+	server.Executable = filepath.Join(t.TempDir(), "with-secure-env")
+	os.WriteFile(server.Executable, nil, 0755)
+	client := startAgent(t, server)
+	t.Cleanup(func() { server.listener.Close() })
+
+	_, err := client.Launch(Request{App: "/path/to/app"})
+
+	if err == nil || launched {
+		t.Errorf("expected launch from another executable to be rejected, got %v", err)
+	}
+}
+
+func TestAgent_UnlockedKeychainServesAgentKey(t *testing.T) {
+	kc := &stubKeychain{key: testKey()}
+	server := &Server{Keychain: kc}
+
+	key, err := server.UnlockedKeychain().RetrieveEncryptionKey()
+	server.UnlockedKeychain().RetrieveEncryptionKey()
+
+	if err != nil || string(key) != string(testKey()) {
+		t.Errorf("expected agent key, got %v, %v", key, err)
+	}
+	if kc.retrieveCount != 1 {
+		t.Errorf("expected one keychain access, got %d", kc.retrieveCount)
+	}
+}
+
+// newServer returns a server whose Fetch decrypts values with the agent's key.
+func newServer(kc *stubKeychain) *Server {
+	server := &Server{Keychain: kc}
+	encrypted := envcrypt.Encrypt(testKey(), "secret")
+	server.Fetch = func(applicationPath string, envNames []string, peerPID int) (map[string]string, error) {
+		key, err := server.UnlockedKeychain().RetrieveEncryptionKey()
+		if err != nil {
+			return nil, err
+		}
+		value, err := envcrypt.Decrypt(key, encrypted)
+		return map[string]string{envNames[0]: value}, err
+	}
+	return server
+}
+
+// executable returns the path of the test binary, the peer of all requests.
+func executable(t *testing.T) string {
+	path, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func startAgent(t *testing.T, server *Server) *Client {
+	socketPath := filepath.Join(t.TempDir(), "agent.sock")
+	go server.ListenAndServe(socketPath)
+	client := &Client{SocketPath: socketPath}
+	waitForAgent(t, client)
+	t.Cleanup(func() { client.Stop() })
+	return client
+}
+
+func waitForAgent(t *testing.T, client *Client) {
+	for i := 0; i < 100; i++ {
+		if _, err := client.Status(); err == nil {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("agent did not start")
+}
+
+func waitUntilLocked(t *testing.T, client *Client) {
+	for i := 0; i < 100; i++ {
+		if status, _ := client.Status(); !status.Unlocked {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Error("expected agent to lock the key")
+}
+
+func testKey() []byte {
+	return []byte("0123456789abcdef0123456789abcdef")
+}
+
+type stubKeychain struct {
+	key           []byte
+	retrieveCount int
+}
+
+func (s *stubKeychain) StoreEncryptionKey(key []byte) error {
+	s.key = key
+	return nil
+}
+
+func (s *stubKeychain) RetrieveEncryptionKey() ([]byte, error) {
+	s.retrieveCount++
+	return s.key, nil
+}
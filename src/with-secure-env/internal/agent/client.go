@@ -0,0 +1,68 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"time"
+)
+
+// ErrNotRunning is returned by Client when no agent listens on the socket.
+var ErrNotRunning = errors.New("agent is not running")
+
+// Client talks to an agent.
+type Client struct {
+	SocketPath string
+}
+
+// Status reports whether the agent has the key unlocked.
+func (c *Client) Status() (Response, error) {
+	return c.call(Request{Op: OpStatus})
+}
+
+// Fetch asks the agent for permission to get the values of envNames for the
+// application.
+func (c *Client) Fetch(applicationPath string, envNames []string) (map[string]string, error) {
+	response, err := c.call(Request{Op: OpFetch, App: applicationPath, Names: envNames})
+	return response.Values, err
+}
+
+// Launch asks the agent for permission to launch request.App and returns the
+// resolved envs.
+func (c *Client) Launch(request Request) (map[string]string, error) {
+	request.Op = OpLaunch
+	response, err := c.call(request)
+	return response.Values, err
+}
+
+// Lock makes the agent forget the key.
+func (c *Client) Lock() error {
+	_, err := c.call(Request{Op: OpLock})
+	return err
+}
+
+// Stop shuts the agent down.
+func (c *Client) Stop() error {
+	_, err := c.call(Request{Op: OpStop})
+	return err
+}
+
+func (c *Client) call(request Request) (Response, error) {
+	conn, err := net.DialTimeout("unix", c.SocketPath, time.Second)
+	if err != nil {
+		return Response{}, ErrNotRunning
+	}
+	defer conn.Close()
+
+	if err := json.NewEncoder(conn).Encode(request); err != nil {
+		return Response{}, err
+	}
+	var response Response
+	if err := json.NewDecoder(conn).Decode(&response); err != nil {
+		return Response{}, err
+	}
+	if response.Error != "" {
+		return response, errors.New("agent: " + response.Error)
+	}
+	return response, nil
+}
@@ -0,0 +1,55 @@
+package agent
+
+import (
+	"net"
+	"syscall"
+	"unsafe"
+)
+
+// From <sys/un.h> and <sys/ucred.h>.
+const (
+	solLocal      = 0
+	localPeerCred = 0x001
+	localPeerPID  = 0x002
+)
+
+type xucred struct {
+	Version uint32
+	UID     uint32
+	Ngroups int16
+	Groups  [16]uint32
+}
+
+// peerCredentials returns the user id and pid of the process on the other
+// end of conn.
+func peerCredentials(conn *net.UnixConn) (uid int, pid int, err error) {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return -1, 0, err
+	}
+	var cred xucred
+	var peerPID int32
+	var credErr error
+	err = raw.Control(func(fd uintptr) {
+		credErr = getsockopt(fd, localPeerCred, unsafe.Pointer(&cred), unsafe.Sizeof(cred))
+		if credErr == nil {
+			credErr = getsockopt(fd, localPeerPID, unsafe.Pointer(&peerPID), unsafe.Sizeof(peerPID))
+		}
+	})
+	if err != nil {
+		return -1, 0, err
+	}
+	if credErr != nil {
+		return -1, 0, credErr
+	}
+	return int(cred.UID), int(peerPID), nil
+}
+
+func getsockopt(fd uintptr, option uintptr, value unsafe.Pointer, size uintptr) error {
+	length := uint32(size)
+	_, _, errno := syscall.Syscall6(syscall.SYS_GETSOCKOPT, fd, solLocal, option, uintptr(value), uintptr(unsafe.Pointer(&length)), 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
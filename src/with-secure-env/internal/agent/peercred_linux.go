@@ -0,0 +1,27 @@
+package agent
+
+import (
+	"net"
+	"syscall"
+)
+
+// peerCredentials returns the user id and pid of the process on the other
+// end of conn.
+func peerCredentials(conn *net.UnixConn) (uid int, pid int, err error) {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return -1, 0, err
+	}
+	var cred *syscall.Ucred
+	var credErr error
+	err = raw.Control(func(fd uintptr) {
+		cred, credErr = syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	})
+	if err != nil {
+		return -1, 0, err
+	}
+	if credErr != nil {
+		return -1, 0, credErr
+	}
+	return int(cred.Uid), int(cred.Pid), nil
+}
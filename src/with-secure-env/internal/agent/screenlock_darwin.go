@@ -0,0 +1,12 @@
+package agent
+
+import (
+	"os/exec"
+	"strings"
+)
+
+// ScreenLocked reports whether the login session's screen is locked.
+func ScreenLocked() bool {
+	output, err := exec.Command("/usr/sbin/ioreg", "-n", "Root", "-d1").Output()
+	return err == nil && strings.Contains(string(output), `"CGSSessionScreenIsLocked"=Yes`)
+}
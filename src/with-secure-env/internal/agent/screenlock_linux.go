@@ -0,0 +1,12 @@
+package agent
+
+import (
+	"os/exec"
+	"strings"
+)
+
+// ScreenLocked reports whether the login session's screen is locked.
+func ScreenLocked() bool {
+	output, err := exec.Command("loginctl", "show-session", "auto", "--property=LockedHint", "--value").Output()
+	return err == nil && strings.TrimSpace(string(output)) == "yes"
+}
@@ -0,0 +1,111 @@
+package bundle
+
+import (
+	"errors"
+	"strings"
+)
+
+// Bech32 (BIP 173) as used by age for keys, without the 90 character limit.
+
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+var bech32Generator = []uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+
+func bech32Polymod(values []byte) uint32 {
+	checksum := uint32(1)
+	for _, v := range values {
+		top := checksum >> 25
+		checksum = (checksum&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if (top>>i)&1 == 1 {
+				checksum ^= bech32Generator[i]
+			}
+		}
+	}
+	return checksum
+}
+
+func bech32HRPExpand(hrp string) []byte {
+	expanded := make([]byte, 0, len(hrp)*2+1)
+	for i := 0; i < len(hrp); i++ {
+		expanded = append(expanded, hrp[i]>>5)
+	}
+	expanded = append(expanded, 0)
+	for i := 0; i < len(hrp); i++ {
+		expanded = append(expanded, hrp[i]&31)
+	}
+	return expanded
+}
+
+// convertBits regroups data from fromBits to toBits wide groups.
+func convertBits(data []byte, fromBits uint, toBits uint, pad bool) ([]byte, error) {
+	var result []byte
+	acc, bits := uint32(0), uint(0)
+	maxValue := uint32(1)<<toBits - 1
+	for _, b := range data {
+		if uint32(b)>>fromBits != 0 {
+			return nil, errors.New("invalid data range")
+		}
+		acc = acc<<fromBits | uint32(b)
+		bits += fromBits
+		for bits >= toBits {
+			bits -= toBits
+			result = append(result, byte(acc>>bits&maxValue))
+		}
+	}
+	if pad {
+		if bits > 0 {
+			result = append(result, byte(acc<<(toBits-bits)&maxValue))
+		}
+	} else if bits >= fromBits || acc<<(toBits-bits)&maxValue != 0 {
+		return nil, errors.New("invalid padding")
+	}
+	return result, nil
+}
+
+// bech32Encode encodes data with the lowercase human readable part hrp.
+func bech32Encode(hrp string, data []byte) string {
+	values, _ := convertBits(data, 8, 5, true)
+	checksumInput := append(bech32HRPExpand(hrp), values...)
+	polymod := bech32Polymod(append(checksumInput, 0, 0, 0, 0, 0, 0)) ^ 1
+
+	var encoded strings.Builder
+	encoded.WriteString(hrp)
+	encoded.WriteByte('1')
+	for _, v := range values {
+		encoded.WriteByte(bech32Charset[v])
+	}
+	for i := 0; i < 6; i++ {
+		encoded.WriteByte(bech32Charset[polymod>>(5*(5-i))&31])
+	}
+	return encoded.String()
+}
+
+// bech32Decode returns the lowercase human readable part and the data.
+func bech32Decode(s string) (string, []byte, error) {
+	if strings.ToLower(s) != s && strings.ToUpper(s) != s {
+		return "", nil, errors.New("mixed case")
+	}
+	s = strings.ToLower(s)
+	separator := strings.LastIndexByte(s, '1')
+	if separator < 1 || separator+7 > len(s) {
+		return "", nil, errors.New("invalid separator position")
+	}
+	hrp := s[:separator]
+	var values []byte
+	for i := separator + 1; i < len(s); i++ {
+		v := strings.IndexByte(bech32Charset, s[i])
+		if v < 0 {
+			return "", nil, errors.New("invalid character")
+		}
+		values = append(values, byte(v))
+	}
+	if bech32Polymod(append(bech32HRPExpand(hrp), values...)) != 1 {
+		return "", nil, errors.New("invalid checksum")
+	}
+	data, err := convertBits(values[:len(values)-6], 5, 8, false)
+	if err != nil {
+		return "", nil, err
+	}
+	return hrp, data, nil
+}
@@ -0,0 +1,153 @@
+// Package bundle encrypts env values for sharing with teammates. A bundle can
+// only be opened by its recipients, identified by age X25519 keys, and is
+// signed with the sender's Ed25519 key.
+//
+// A random file key encrypts the values (AES-256-GCM). For each recipient the
+// file key is wrapped with a key derived (HKDF-SHA256) from an X25519
+// exchange with a fresh ephemeral key, like age does. Bundles use age keys but
+// are no age files.
+package bundle
+
+import (
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+
+	"github.com/kfischer-okarin/with-secure-env/internal/envcrypt"
+)
+
+// Version is the bundle format version.
+const Version = 1
+
+const wrapInfo = "with-secure-env bundle v1"
+
+var (
+	// ErrInvalidBundle is returned by Open for malformed bundles.
+	ErrInvalidBundle = errors.New("invalid bundle")
+	// ErrInvalidSignature is returned by Open if the bundle was modified
+	// after signing.
+	ErrInvalidSignature = errors.New("invalid bundle signature")
+	// ErrNotRecipient is returned by Open if the identity is none of the
+	// bundle's recipients.
+	ErrNotRecipient = errors.New("bundle is not encrypted for this identity")
+)
+
+// bundle is the JSON format of a bundle. Signature covers the JSON encoding
+// of all other fields.
+type bundle struct {
+	Version    int      `json:"version"`
+	Sender     []byte   `json:"sender"`
+	Recipients []Stanza `json:"recipients"`
+	Payload    string   `json:"payload"`
+	Signature  []byte   `json:"signature,omitempty"`
+}
+
+// Stanza holds a file key wrapped for one recipient.
+type Stanza struct {
+	Ephemeral  []byte `json:"ephemeral"`
+	WrappedKey string `json:"wrappedKey"`
+}
+
+// Seal encrypts values for the recipients and signs the bundle.
+func Seal(values map[string]string, recipients []*ecdh.PublicKey, sender ed25519.PrivateKey) ([]byte, error) {
+	if len(recipients) == 0 {
+		return nil, errors.New("no recipients")
+	}
+	plaintext, err := json.Marshal(values)
+	if err != nil {
+		return nil, err
+	}
+	fileKey := make([]byte, 32)
+	rand.Read(fileKey)
+
+	b := bundle{Version: Version, Sender: sender.Public().(ed25519.PublicKey), Payload: envcrypt.Encrypt(fileKey, string(plaintext))}
+	for _, recipient := range recipients {
+		s, err := Wrap(fileKey, recipient)
+		if err != nil {
+			return nil, err
+		}
+		b.Recipients = append(b.Recipients, s)
+	}
+
+	signed, _ := json.Marshal(b)
+	b.Signature = ed25519.Sign(sender, signed)
+	return json.MarshalIndent(b, "", "  ")
+}
+
+// Open verifies the signature of a bundle and decrypts it with identity. It
+// returns the values and the sender's public key.
+func Open(data []byte, identity *ecdh.PrivateKey) (map[string]string, ed25519.PublicKey, error) {
+	var b bundle
+	if err := json.Unmarshal(data, &b); err != nil || b.Version != Version || len(b.Sender) != ed25519.PublicKeySize {
+		return nil, nil, ErrInvalidBundle
+	}
+	signature := b.Signature
+	b.Signature = nil
+	signed, _ := json.Marshal(b)
+	if !ed25519.Verify(b.Sender, signed, signature) {
+		return nil, nil, ErrInvalidSignature
+	}
+
+	fileKey, err := Unwrap(b.Recipients, identity)
+	if err != nil {
+		return nil, nil, err
+	}
+	plaintext, err := envcrypt.Decrypt(fileKey, b.Payload)
+	if err != nil {
+		return nil, nil, ErrInvalidBundle
+	}
+	var values map[string]string
+	if err := json.Unmarshal([]byte(plaintext), &values); err != nil {
+		return nil, nil, ErrInvalidBundle
+	}
+	return values, b.Sender, nil
+}
+
+// Wrap encrypts a 32 byte file key for recipient.
+func Wrap(fileKey []byte, recipient *ecdh.PublicKey) (Stanza, error) {
+	ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return Stanza{}, err
+	}
+	wrapKey, err := deriveWrapKey(ephemeral, recipient, ephemeral.PublicKey(), recipient)
+	if err != nil {
+		return Stanza{}, err
+	}
+	return Stanza{Ephemeral: ephemeral.PublicKey().Bytes(), WrappedKey: envcrypt.Encrypt(wrapKey, string(fileKey))}, nil
+}
+
+// Unwrap returns the file key from the first of stanzas wrapped for
+// identity, or ErrNotRecipient.
+func Unwrap(stanzas []Stanza, identity *ecdh.PrivateKey) ([]byte, error) {
+	for _, s := range stanzas {
+		ephemeral, err := ecdh.X25519().NewPublicKey(s.Ephemeral)
+		if err != nil {
+			continue
+		}
+		wrapKey, err := deriveWrapKey(identity, ephemeral, ephemeral, identity.PublicKey())
+		if err != nil {
+			continue
+		}
+		fileKey, err := envcrypt.Decrypt(wrapKey, s.WrappedKey)
+		if err != nil {
+			continue
+		}
+		return []byte(fileKey), nil
+	}
+	return nil, ErrNotRecipient
+}
+
+// deriveWrapKey derives the key wrapping the file key from the X25519 shared
+// secret, bound to both public keys.
+func deriveWrapKey(privateKey *ecdh.PrivateKey, peer *ecdh.PublicKey, ephemeral *ecdh.PublicKey, recipient *ecdh.PublicKey) ([]byte, error) {
+	shared, err := privateKey.ECDH(peer)
+	if err != nil {
+		return nil, err
+	}
+	salt := append(ephemeral.Bytes(), recipient.Bytes()...)
+	return hkdf.Key(sha256.New, shared, salt, wrapInfo, 32)
+}
@@ -0,0 +1,104 @@
+package bundle
+
+import (
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSealOpen_RoundTripForEachRecipient(t *testing.T) {
+	_, sender, _ := ed25519.GenerateKey(rand.Reader)
+	alice, _ := ecdh.X25519().GenerateKey(rand.Reader)
+	bob, _ := ecdh.X25519().GenerateKey(rand.Reader)
+	values := map[string]string{"API_KEY": "secret", "MULTILINE": "a\nb"}
+
+	data, err := Seal(values, []*ecdh.PublicKey{alice.PublicKey(), bob.PublicKey()}, sender)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if strings.Contains(string(data), "secret") {
+		t.Error("expected values to be encrypted")
+	}
+
+	for _, identity := range []*ecdh.PrivateKey{alice, bob} {
+		opened, from, err := Open(data, identity)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if opened["API_KEY"] != "secret" || opened["MULTILINE"] != "a\nb" {
+			t.Errorf("expected original values, got %v", opened)
+		}
+		if !from.Equal(sender.Public()) {
+			t.Error("expected sender's public key")
+		}
+	}
+}
+
+func TestOpen_FailsForOtherIdentity(t *testing.T) {
+	_, sender, _ := ed25519.GenerateKey(rand.Reader)
+	alice, _ := ecdh.X25519().GenerateKey(rand.Reader)
+	eve, _ := ecdh.X25519().GenerateKey(rand.Reader)
+	data, _ := Seal(map[string]string{"API_KEY": "secret"}, []*ecdh.PublicKey{alice.PublicKey()}, sender)
+
+	if _, _, err := Open(data, eve); !errors.Is(err, ErrNotRecipient) {
+		t.Errorf("expected ErrNotRecipient, got %v", err)
+	}
+}
+
+func TestOpen_FailsForModifiedBundle(t *testing.T) {
+	_, sender, _ := ed25519.GenerateKey(rand.Reader)
+	_, impostor, _ := ed25519.GenerateKey(rand.Reader)
+	alice, _ := ecdh.X25519().GenerateKey(rand.Reader)
+	data, _ := Seal(map[string]string{"API_KEY": "secret"}, []*ecdh.PublicKey{alice.PublicKey()}, sender)
+
+	var b bundle
+	json.Unmarshal(data, &b)
+	b.Sender = impostor.Public().(ed25519.PublicKey)
+	modified, _ := json.Marshal(b)
+
+	if _, _, err := Open(modified, alice); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected ErrInvalidSignature, got %v", err)
+	}
+}
+
+func TestRecipientAndIdentity_RoundTrip(t *testing.T) {
+	identity, _ := ecdh.X25519().GenerateKey(rand.Reader)
+
+	recipient := FormatRecipient(identity.PublicKey())
+	formatted := FormatIdentity(identity)
+
+	if !strings.HasPrefix(recipient, "age1") || !strings.HasPrefix(formatted, "AGE-SECRET-KEY-1") {
+		t.Errorf("expected age key formats, got %s and %s", recipient, formatted)
+	}
+	parsedRecipient, err := ParseRecipient(recipient)
+	if err != nil || !parsedRecipient.Equal(identity.PublicKey()) {
+		t.Errorf("expected recipient to round-trip, got %v", err)
+	}
+	parsedIdentity, err := ParseIdentityFile([]byte("# created: 2026-10-18\n# public key: " + recipient + "\n" + formatted + "\n"))
+	if err != nil || !parsedIdentity.Equal(identity) {
+		t.Errorf("expected identity to round-trip, got %v", err)
+	}
+}
+
+func TestParseRecipient_AcceptsAgeKeygenOutput(t *testing.T) {
+	// Test vector from the age specification.
+	identity, err := ParseIdentity("AGE-SECRET-KEY-1GFPYYSJZGFPYYSJZGFPYYSJZGFPYYSJZGFPYYSJZGFPYYSJZGFPQ4EGAEX")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if recipient := FormatRecipient(identity.PublicKey()); recipient != "age1zvkyg2lqzraa2lnjvqej32nkuu0ues2s82hzrye869xeexvn73equnujwj" {
+		t.Errorf("unexpected recipient %s", recipient)
+	}
+}
+
+func TestParseRecipient_RejectsInvalidRecipients(t *testing.T) {
+	for _, recipient := range []string{"", "age1invalid", "ssh-ed25519 AAAA", "AGE-SECRET-KEY-1GFPYYSJZGFPYYSJZGFPYYSJZGFPYYSJZGFPYYSJZGFPYYSJZGFPQ4EGAEX"} {
+		if _, err := ParseRecipient(recipient); err == nil {
+			t.Errorf("expected error for %q", recipient)
+		}
+	}
+}
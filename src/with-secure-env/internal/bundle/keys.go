@@ -0,0 +1,75 @@
+package bundle
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+const (
+	recipientHRP = "age"
+	identityHRP  = "age-secret-key-"
+)
+
+// FormatRecipient encodes a public key like an age X25519 recipient
+// ("age1...").
+func FormatRecipient(publicKey *ecdh.PublicKey) string {
+	return bech32Encode(recipientHRP, publicKey.Bytes())
+}
+
+// ParseRecipient decodes an age X25519 recipient.
+func ParseRecipient(recipient string) (*ecdh.PublicKey, error) {
+	hrp, data, err := bech32Decode(recipient)
+	if err != nil || hrp != recipientHRP {
+		return nil, fmt.Errorf("invalid age recipient %s", recipient)
+	}
+	publicKey, err := ecdh.X25519().NewPublicKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid age recipient %s", recipient)
+	}
+	return publicKey, nil
+}
+
+// FormatIdentity encodes a private key like an age X25519 identity
+// ("AGE-SECRET-KEY-1...").
+func FormatIdentity(privateKey *ecdh.PrivateKey) string {
+	return strings.ToUpper(bech32Encode(identityHRP, privateKey.Bytes()))
+}
+
+// ParseIdentity decodes an age X25519 identity.
+func ParseIdentity(identity string) (*ecdh.PrivateKey, error) {
+	hrp, data, err := bech32Decode(identity)
+	if err != nil || hrp != identityHRP {
+		return nil, fmt.Errorf("invalid age identity")
+	}
+	privateKey, err := ecdh.X25519().NewPrivateKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid age identity")
+	}
+	return privateKey, nil
+}
+
+// ParseIdentityFile returns the first identity of a file as written by
+// age-keygen, ignoring comments and empty lines.
+func ParseIdentityFile(data []byte) (*ecdh.PrivateKey, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		return ParseIdentity(line)
+	}
+	return nil, fmt.Errorf("no age identity found")
+}
+
+// Fingerprint identifies a sender's signing key, e.g. "SHA256:3q2+7w...".
+func Fingerprint(publicKey ed25519.PublicKey) string {
+	sum := sha256.Sum256(publicKey)
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:])
+}
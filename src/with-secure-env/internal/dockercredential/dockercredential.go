@@ -0,0 +1,60 @@
+// Package dockercredential reads and writes the messages of the docker
+// credential helper protocol (see docker/docker-credential-helpers): the
+// server URL as plain text for get and erase, and JSON credentials for get
+// and store.
+package dockercredential
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ErrNotFound is reported by get if no credentials are stored. Docker
+// recognizes this exact message.
+var ErrNotFound = errors.New("credentials not found in native keychain")
+
+// Credentials are exchanged with docker for get and store.
+type Credentials struct {
+	ServerURL string
+	Username  string
+	Secret    string
+}
+
+// ReadServerURL reads the server URL sent for get and erase.
+func ReadServerURL(r io.Reader) (string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	serverURL := strings.TrimSpace(string(data))
+	if serverURL == "" {
+		return "", errors.New("no credentials server URL")
+	}
+	return serverURL, nil
+}
+
+// ReadCredentials reads the credentials sent for store.
+func ReadCredentials(r io.Reader) (Credentials, error) {
+	var credentials Credentials
+	if err := json.NewDecoder(r).Decode(&credentials); err != nil {
+		return Credentials{}, fmt.Errorf("invalid credentials: %w", err)
+	}
+	if credentials.ServerURL == "" {
+		return Credentials{}, errors.New("no credentials server URL")
+	}
+	return credentials, nil
+}
+
+// Host returns the registry host of a server URL, which may or may not
+// include a scheme and path.
+func Host(serverURL string) string {
+	host := serverURL
+	if _, rest, ok := strings.Cut(host, "://"); ok {
+		host = rest
+	}
+	host, _, _ = strings.Cut(host, "/")
+	return host
+}
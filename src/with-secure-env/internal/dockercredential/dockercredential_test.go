@@ -0,0 +1,37 @@
+package dockercredential
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadServerURL_TrimsNewline(t *testing.T) {
+	serverURL, err := ReadServerURL(strings.NewReader("https://index.docker.io/v1/\n"))
+
+	if err != nil || serverURL != "https://index.docker.io/v1/" {
+		t.Errorf("expected server URL, got %q, %v", serverURL, err)
+	}
+}
+
+func TestReadCredentials_RequiresServerURL(t *testing.T) {
+	credentials, err := ReadCredentials(strings.NewReader(`{"ServerURL":"ghcr.io","Username":"octocat","Secret":"token"}`))
+	if err != nil || credentials != (Credentials{ServerURL: "ghcr.io", Username: "octocat", Secret: "token"}) {
+		t.Errorf("expected credentials, got %+v, %v", credentials, err)
+	}
+
+	if _, err := ReadCredentials(strings.NewReader(`{"Username":"octocat"}`)); err == nil {
+		t.Error("expected error for missing server URL")
+	}
+}
+
+func TestHost_StripsSchemeAndPath(t *testing.T) {
+	for serverURL, expected := range map[string]string{
+		"https://index.docker.io/v1/": "index.docker.io",
+		"ghcr.io":                     "ghcr.io",
+		"localhost:5000":              "localhost:5000",
+	} {
+		if host := Host(serverURL); host != expected {
+			t.Errorf("expected %s for %s, got %s", expected, serverURL, host)
+		}
+	}
+}
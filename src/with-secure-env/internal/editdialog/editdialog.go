@@ -1,9 +1,42 @@
 package editdialog
 
+import (
+	"github.com/kfischer-okarin/with-secure-env/internal/secretgen"
+	"github.com/kfischer-okarin/with-secure-env/internal/secretmeta"
+)
+
+// Request describes the environment variables to edit.
+type Request struct {
+	ApplicationPath string
+	// Profile is the name of the edited profile of the application.
+	Profile string
+	// Group is set instead of ApplicationPath when editing a shared group.
+	Group string
+	// UsedBy lists the applications using the edited group.
+	UsedBy []string
+	// Values are the current values by env name.
+	Values map[string]string
+	// Metadata is the current metadata by env name.
+	Metadata map[string]secretmeta.Metadata
+	// Generate creates random values for the dialog's generator, which is
+	// hidden if nil.
+	Generate func(name string, options secretgen.Options) (map[string]string, error)
+	// Validate returns the problems of edited values by env name, or nil if
+	// they can be saved. Missing required variables are reported too.
+	Validate func(values map[string]string) map[string]string
+}
+
+// Result is the outcome of a saved edit.
+type Result struct {
+	Values map[string]string
+	// Metadata holds the edited description, source and expiry by env name.
+	Metadata map[string]secretmeta.Metadata
+}
+
 // EditDialog provides a user interface for editing environment variables.
 type EditDialog interface {
 	// EditEnvs opens an editor for the environment variables of the given application.
-	// It receives the current values and returns the updated values.
+	// It receives the current values and returns the updated values and metadata.
 	// The bool return value is false if the user canceled the edit.
-	EditEnvs(applicationPath string, currentValues map[string]string) (map[string]string, bool)
+	EditEnvs(request Request) (Result, bool)
 }
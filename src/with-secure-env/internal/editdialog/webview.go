@@ -5,23 +5,25 @@ package editdialog
 import (
 	"encoding/json"
 	"runtime"
+	"strings"
 
+	"github.com/kfischer-okarin/with-secure-env/internal/secretgen"
 	webview "github.com/webview/webview_go"
 )
 
 type WebViewEditDialog struct{}
 
-func (d *WebViewEditDialog) EditEnvs(applicationPath string, currentValues map[string]string) (map[string]string, bool) {
+func (d *WebViewEditDialog) EditEnvs(request Request) (Result, bool) {
 	runtime.LockOSThread()
 
-	var result map[string]string
+	var result Result
 	ok := false
 
 	w := webview.New(false)
 	defer w.Destroy()
 
 	w.SetTitle("Edit Environment Variables")
-	w.SetSize(500, 400, webview.HintNone)
+	w.SetSize(560, 480, webview.HintNone)
 
 	w.Bind("save", func(data string) {
 		json.Unmarshal([]byte(data), &result)
@@ -34,8 +36,36 @@ func (d *WebViewEditDialog) EditEnvs(applicationPath string, currentValues map[s
 		w.Terminate()
 	})
 
-	initialData, _ := json.Marshal(currentValues)
-	html := buildHTML(applicationPath, string(initialData))
+	if request.Generate != nil {
+		w.Bind("generate", func(name string, optionsJSON string) (map[string]string, error) {
+			var options secretgen.Options
+			if err := json.Unmarshal([]byte(optionsJSON), &options); err != nil {
+				return nil, err
+			}
+			return request.Generate(name, options)
+		})
+	}
+
+	if request.Validate != nil {
+		w.Bind("validate", func(valuesJSON string) (map[string]string, error) {
+			var values map[string]string
+			if err := json.Unmarshal([]byte(valuesJSON), &values); err != nil {
+				return nil, err
+			}
+			return request.Validate(values), nil
+		})
+	}
+
+	initialData, _ := json.Marshal(request.Values)
+	metadataData, _ := json.Marshal(request.Metadata)
+	subtitle, badge := request.ApplicationPath, request.Profile
+	if request.Group != "" {
+		subtitle, badge = "Shared group, used by: "+strings.Join(request.UsedBy, ", "), "group: "+request.Group
+		if len(request.UsedBy) == 0 {
+			subtitle = "Shared group, not used by any application yet"
+		}
+	}
+	html := buildHTML(subtitle, badge, string(initialData), string(metadataData), request.Generate != nil)
 	w.SetHtml(html)
 
 	w.Run()
@@ -43,7 +73,11 @@ func (d *WebViewEditDialog) EditEnvs(applicationPath string, currentValues map[s
 	return result, ok
 }
 
-func buildHTML(applicationPath string, initialJSON string) string {
+func buildHTML(subtitle string, badge string, initialJSON string, metadataJSON string, generator bool) string {
+	generatorDisplay := "none"
+	if generator {
+		generatorDisplay = "flex"
+	}
 	return `<!DOCTYPE html>
 <html>
 <head>
@@ -61,6 +95,16 @@ h2 { font-size: 14px; color: #666; margin-bottom: 10px; }
 	margin-bottom: 20px;
 	word-break: break-all;
 }
+.profile {
+	display: inline-block;
+	font-size: 11px;
+	font-weight: 600;
+	background: #e5e5ea;
+	color: #1d1d1f;
+	padding: 2px 6px;
+	border-radius: 4px;
+	margin-left: 6px;
+}
 .env-list { margin-bottom: 20px; }
 .env-entry { margin-bottom: 10px; }
 .env-header {
@@ -90,6 +134,21 @@ textarea.value {
 	resize: vertical;
 	min-height: 50px;
 }
+.metadata {
+	display: flex;
+	gap: 6px;
+	align-items: center;
+	flex-wrap: wrap;
+	margin-top: 5px;
+	font-size: 12px;
+}
+.metadata input { padding: 4px 6px; border: 1px solid #ddd; border-radius: 6px; font-size: 12px; }
+.metadata input.description { flex: 1; min-width: 120px; }
+.timestamps { color: #999; font-size: 11px; margin-top: 3px; }
+.timestamps:empty { display: none; }
+.expired { color: #ff3b30; font-weight: 600; }
+.problem { color: #ff3b30; font-size: 12px; margin-top: 3px; }
+.problem:empty { display: none; }
 hr {
 	border: none;
 	border-top: 1px solid #ddd;
@@ -104,22 +163,56 @@ button {
 }
 .remove-btn { background: #ff3b30; color: white; padding: 6px 12px; }
 .add-btn { background: #34c759; color: white; margin-bottom: 20px; }
+.generator {
+	display: ` + generatorDisplay + `;
+	gap: 6px;
+	align-items: center;
+	flex-wrap: wrap;
+	margin-bottom: 20px;
+	font-size: 12px;
+}
+.generator input, .generator select { padding: 4px 6px; border: 1px solid #ddd; border-radius: 6px; }
+.generator input.name { width: 140px; }
+.generator input.length { width: 60px; }
+.generate-btn { background: #5856d6; color: white; padding: 6px 12px; }
 .buttons { display: flex; gap: 10px; justify-content: flex-end; }
 .cancel-btn { background: #8e8e93; color: white; }
 .save-btn { background: #007aff; color: white; }
 </style>
 </head>
 <body>
-<h2>Environment Variables</h2>
-<div class="app-path">` + applicationPath + `</div>
+<h2>Environment Variables<span class="profile">` + badge + `</span></h2>
+<div class="app-path">` + subtitle + `</div>
 <div class="env-list" id="envList"></div>
 <button class="add-btn" onclick="addRow()">+ Add Variable</button>
+<div class="generator">
+	<label>Generate</label>
+	<input class="name" id="generateName" placeholder="VAR_NAME">
+	<select id="generateKind" onchange="updateGenerator()">
+		<option value="password">Password</option>
+		<option value="hex">Hex token</option>
+		<option value="base64">Base64 token</option>
+		<option value="uuid">UUID</option>
+		<option value="ed25519">Ed25519 key pair</option>
+		<option value="rsa">RSA key pair</option>
+	</select>
+	<input class="length" id="generateLength" type="number" min="1" placeholder="32" title="Length (characters, token bytes or RSA bits)">
+	<select id="generateCharset">
+		<option value="alnum">A-Z a-z 0-9</option>
+		<option value="symbols">+ symbols</option>
+		<option value="alpha">A-Z a-z</option>
+		<option value="numeric">0-9</option>
+	</select>
+	<button class="generate-btn" onclick="doGenerate()">Generate</button>
+</div>
 <div class="buttons">
 	<button class="cancel-btn" onclick="doCancel()">Cancel</button>
 	<button class="save-btn" onclick="doSave()">Save</button>
 </div>
 <script>
 let envs = ` + initialJSON + `;
+let metadata = ` + metadataJSON + ` || {};
+let problems = {};
 
 function escapeHtml(str) {
 	return str.replace(/&/g, '&amp;').replace(/</g, '&lt;').replace(/>/g, '&gt;').replace(/"/g, '&quot;');
@@ -140,6 +233,14 @@ function render() {
 			</div>
 			<label>Value</label>
 			<textarea class="value" oninput="updateValue('${escapeHtml(key)}', this.value)" placeholder="value">${escapeHtml(value)}</textarea>
+			<div class="metadata">
+				<input class="description" value="${escapeHtml(meta(key).description || '')}" oninput="updateMetadata('${escapeHtml(key)}', 'description', this.value)" placeholder="Description">
+				<input class="source" value="${escapeHtml(meta(key).source || '')}" oninput="updateMetadata('${escapeHtml(key)}', 'source', this.value)" placeholder="Source (e.g. token page URL)">
+				<label>Expires</label>
+				<input class="expires" type="date" value="${dateOf(meta(key).expires)}" onchange="updateExpires('${escapeHtml(key)}', this.value)">
+			</div>
+			<div class="timestamps">${timestamps(key)}</div>
+			<div class="problem">${escapeHtml(problems[key] || '')}</div>
 		` + "`" + `;
 		list.appendChild(entry);
 		if (index < entries.length - 1) {
@@ -148,6 +249,37 @@ function render() {
 	});
 }
 
+function meta(key) {
+	return metadata[key] || {};
+}
+
+function dateOf(timestamp) {
+	return timestamp ? timestamp.slice(0, 10) : '';
+}
+
+function timestamps(key) {
+	const m = meta(key);
+	const parts = [];
+	if (m.created) parts.push('created ' + dateOf(m.created));
+	if (m.updated) parts.push('updated ' + dateOf(m.updated));
+	if (m.expires && new Date(m.expires) <= new Date()) parts.push('<span class="expired">expired</span>');
+	return parts.join(' · ');
+}
+
+function updateMetadata(key, field, value) {
+	metadata[key] = Object.assign({}, meta(key), {[field]: value});
+}
+
+function updateExpires(key, date) {
+	const m = Object.assign({}, meta(key));
+	if (date) {
+		m.expires = new Date(date + 'T00:00:00Z').toISOString();
+	} else {
+		delete m.expires;
+	}
+	metadata[key] = m;
+}
+
 function addRow() {
 	let i = 1;
 	while (envs['NEW_VAR_' + i]) i++;
@@ -157,6 +289,7 @@ function addRow() {
 
 function removeRow(key) {
 	delete envs[key];
+	delete metadata[key];
 	render();
 }
 
@@ -165,6 +298,10 @@ function updateKey(input, oldKey) {
 	if (newKey && newKey !== oldKey) {
 		envs[newKey] = envs[oldKey];
 		delete envs[oldKey];
+		if (metadata[oldKey]) {
+			metadata[newKey] = metadata[oldKey];
+			delete metadata[oldKey];
+		}
 		render();
 	}
 }
@@ -173,8 +310,44 @@ function updateValue(key, value) {
 	envs[key] = value;
 }
 
+function updateGenerator() {
+	const kind = document.getElementById('generateKind').value;
+	document.getElementById('generateCharset').style.display = kind === 'password' ? '' : 'none';
+	document.getElementById('generateLength').style.display = ['uuid', 'ed25519'].includes(kind) ? 'none' : '';
+}
+
+function doGenerate() {
+	const name = document.getElementById('generateName').value;
+	if (!name) {
+		return;
+	}
+	const options = {
+		kind: document.getElementById('generateKind').value,
+		length: parseInt(document.getElementById('generateLength').value) || 0,
+		charset: document.getElementById('generateCharset').value,
+	};
+	window.generate(name, JSON.stringify(options)).then(values => {
+		const existing = Object.keys(values).filter(key => envs[key]);
+		if (existing.length > 0 && !confirm('Replace ' + existing.join(', ') + '?')) {
+			return;
+		}
+		Object.assign(envs, values);
+		render();
+	}).catch(error => alert(error));
+}
+
 function doSave() {
-	window.save(JSON.stringify(envs)).then(() => {});
+	const validated = window.validate ? window.validate(JSON.stringify(envs)) : Promise.resolve(null);
+	validated.then(result => {
+		problems = result || {};
+		if (Object.keys(problems).length > 0) {
+			// Add rows for missing required variables so they can be filled in.
+			Object.keys(problems).filter(key => !(key in envs)).forEach(key => envs[key] = '');
+			render();
+			return;
+		}
+		window.save(JSON.stringify({Values: envs, Metadata: metadata})).then(() => {});
+	}).catch(error => alert(error));
 }
 
 function doCancel() {
@@ -182,6 +355,7 @@ function doCancel() {
 }
 
 render();
+updateGenerator();
 </script>
 </body>
 </html>`
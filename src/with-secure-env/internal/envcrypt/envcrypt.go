@@ -0,0 +1,49 @@
+// Package envcrypt encrypts single env values with AES-256-GCM. Each value
+// gets its own random nonce and is stored as base64(nonce || ciphertext || tag).
+package envcrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+)
+
+// ErrInvalidCiphertext is returned by Decrypt for malformed or tampered values.
+var ErrInvalidCiphertext = errors.New("invalid ciphertext")
+
+// Encrypt encrypts plaintext with the 32 byte key.
+func Encrypt(key []byte, plaintext string) string {
+	block, _ := aes.NewCipher(key)
+	gcm, _ := cipher.NewGCM(block)
+
+	nonce := make([]byte, gcm.NonceSize())
+	rand.Read(nonce)
+
+	ciphertext := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.StdEncoding.EncodeToString(ciphertext)
+}
+
+// Decrypt decrypts a value produced by Encrypt.
+func Decrypt(key []byte, encryptedBase64 string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(encryptedBase64)
+	if err != nil {
+		return "", ErrInvalidCiphertext
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	gcm, _ := cipher.NewGCM(block)
+	nonceSize := gcm.NonceSize()
+	if len(data) < nonceSize {
+		return "", ErrInvalidCiphertext
+	}
+	nonce, ciphertext := data[:nonceSize], data[nonceSize:]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", ErrInvalidCiphertext
+	}
+	return string(plaintext), nil
+}
@@ -0,0 +1,31 @@
+package envcrypt
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestEncrypt_RoundTripsWithUniqueNonces(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+
+	first := Encrypt(key, "secret")
+	second := Encrypt(key, "secret")
+
+	if first == second {
+		t.Error("expected different ciphertexts for the same value")
+	}
+	if value, err := Decrypt(key, first); err != nil || value != "secret" {
+		t.Errorf("expected 'secret', got %q, %v", value, err)
+	}
+}
+
+func TestDecrypt_RejectsTamperedValues(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	otherKey := []byte("fedcba9876543210fedcba9876543210")
+
+	for _, encrypted := range []string{"not base64!", "c2hvcnQ=", Encrypt(otherKey, "secret")} {
+		if _, err := Decrypt(key, encrypted); !errors.Is(err, ErrInvalidCiphertext) {
+			t.Errorf("expected ErrInvalidCiphertext for %q, got %v", encrypted, err)
+		}
+	}
+}
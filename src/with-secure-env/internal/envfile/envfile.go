@@ -0,0 +1,283 @@
+// Package envfile parses plaintext secret files (dotenv, JSON and YAML maps)
+// for importing them.
+package envfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Supported formats.
+const (
+	FormatDotenv = "dotenv"
+	FormatJSON   = "json"
+	FormatYAML   = "yaml"
+)
+
+var keyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+
+// DetectFormat guesses the format from the file name, defaulting to dotenv.
+func DetectFormat(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return FormatJSON
+	case ".yaml", ".yml":
+		return FormatYAML
+	}
+	return FormatDotenv
+}
+
+// Parse parses data in the given format into a map of env names to values.
+func Parse(format string, data []byte) (map[string]string, error) {
+	switch format {
+	case FormatDotenv:
+		return ParseDotenv(data)
+	case FormatJSON:
+		return ParseJSON(data)
+	case FormatYAML:
+		return ParseYAML(data)
+	}
+	return nil, fmt.Errorf("unknown format %s (expected dotenv, json or yaml)", format)
+}
+
+// ParseDotenv parses KEY=value lines. Supported are comments, an optional
+// export prefix, single quotes (literal), double quotes (with \n, \t, \", \\
+// and \$ escapes) and quoted values spanning multiple lines. Variables are not
+// expanded.
+func ParseDotenv(data []byte) (map[string]string, error) {
+	values := map[string]string{}
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		lineNumber := i + 1
+		line := strings.TrimSpace(lines[i])
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimSpace(strings.TrimPrefix(line, "export "))
+
+		key, rest, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || !keyPattern.MatchString(key) {
+			return nil, fmt.Errorf("line %d: expected KEY=value", lineNumber)
+		}
+		rest = strings.TrimLeft(rest, " \t")
+
+		if rest == "" || (rest[0] != '"' && rest[0] != '\'') {
+			values[key] = stripComment(rest)
+			continue
+		}
+
+		quote := rest[0]
+		raw := rest[1:]
+		end := closingQuote(raw, quote)
+		for end < 0 {
+			i++
+			if i == len(lines) {
+				return nil, fmt.Errorf("line %d: unterminated quoted value", lineNumber)
+			}
+			raw += "\n" + lines[i]
+			end = closingQuote(raw, quote)
+		}
+		if trailing := strings.TrimSpace(raw[end+1:]); trailing != "" && !strings.HasPrefix(trailing, "#") {
+			return nil, fmt.Errorf("line %d: unexpected characters after quoted value", lineNumber)
+		}
+		raw = raw[:end]
+		if quote == '"' {
+			raw = unescapeDoubleQuoted(raw)
+		}
+		values[key] = raw
+	}
+	return values, nil
+}
+
+// ParseJSON parses an object with string, number or boolean values.
+func ParseJSON(data []byte) (map[string]string, error) {
+	var object map[string]any
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&object); err != nil {
+		return nil, fmt.Errorf("expected a JSON object: %w", err)
+	}
+
+	values := map[string]string{}
+	for key, value := range object {
+		if !keyPattern.MatchString(key) {
+			return nil, fmt.Errorf("invalid variable name %q", key)
+		}
+		switch v := value.(type) {
+		case string:
+			values[key] = v
+		case json.Number, bool:
+			values[key] = fmt.Sprint(v)
+		default:
+			return nil, fmt.Errorf("%s: only string, number and boolean values are supported", key)
+		}
+	}
+	return values, nil
+}
+
+// ParseYAML parses a flat YAML mapping with plain, quoted and block scalar
+// (| and >) values. Nested mappings and lists are not supported.
+func ParseYAML(data []byte) (map[string]string, error) {
+	values := map[string]string{}
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		lineNumber := i + 1
+		line := strings.TrimRight(lines[i], " \t")
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || trimmed == "---" {
+			continue
+		}
+		if line != trimmed {
+			return nil, fmt.Errorf("line %d: nested values are not supported", lineNumber)
+		}
+
+		key, rest, ok := strings.Cut(line, ":")
+		key = unquoteYAML(strings.TrimSpace(key))
+		if !ok || !keyPattern.MatchString(key) {
+			return nil, fmt.Errorf("line %d: expected key: value", lineNumber)
+		}
+		rest = strings.TrimSpace(rest)
+
+		switch {
+		case rest == "|" || rest == "|-" || rest == ">" || rest == ">-":
+			var block []string
+			for i+1 < len(lines) && (strings.TrimSpace(lines[i+1]) == "" || strings.HasPrefix(lines[i+1], " ")) {
+				i++
+				block = append(block, lines[i])
+			}
+			values[key] = blockScalar(block, rest)
+		case strings.HasPrefix(rest, "\"") || strings.HasPrefix(rest, "'"):
+			end := closingQuote(rest[1:], rest[0])
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated quoted value", lineNumber)
+			}
+			values[key] = unquoteYAML(rest[:end+2])
+		case rest == "" || strings.HasPrefix(rest, "- ") || strings.HasPrefix(rest, "{") || strings.HasPrefix(rest, "["):
+			return nil, fmt.Errorf("line %d: nested values are not supported", lineNumber)
+		default:
+			values[key] = stripComment(rest)
+		}
+	}
+	return values, nil
+}
+
+// Shred overwrites the file with zeros, syncs it to disk and removes it. On
+// copy-on-write file systems and SSDs old blocks may still survive.
+func Shred(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(make([]byte, info.Size()))
+	if err == nil {
+		err = file.Sync()
+	}
+	file.Close()
+	if err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// closingQuote returns the index of the unescaped closing quote in s or -1.
+// Backslash escapes only apply to double quotes.
+func closingQuote(s string, quote byte) int {
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && quote == '"':
+			i++
+		case s[i] == quote:
+			if quote == '\'' && i+1 < len(s) && s[i+1] == '\'' {
+				i++
+				continue
+			}
+			return i
+		}
+	}
+	return -1
+}
+
+func unescapeDoubleQuoted(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case '"', '\\', '$', '\'':
+			b.WriteByte(s[i])
+		default:
+			b.WriteByte('\\')
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+func unquoteYAML(s string) string {
+	if len(s) < 2 {
+		return s
+	}
+	switch {
+	case s[0] == '"' && s[len(s)-1] == '"':
+		return unescapeDoubleQuoted(s[1 : len(s)-1])
+	case s[0] == '\'' && s[len(s)-1] == '\'':
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'")
+	}
+	return s
+}
+
+// stripComment removes a trailing " # comment" from an unquoted value.
+func stripComment(s string) string {
+	if i := strings.Index(s, " #"); i >= 0 {
+		s = s[:i]
+	}
+	return strings.TrimSpace(s)
+}
+
+// blockScalar joins the lines of a literal (|) or folded (>) block, removing
+// the indentation of the first line. The - indicator strips the final newline.
+func blockScalar(lines []string, indicator string) string {
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	indent := len(lines[0]) - len(strings.TrimLeft(lines[0], " "))
+	for i, line := range lines {
+		if len(line) >= indent {
+			lines[i] = line[indent:]
+		} else {
+			lines[i] = strings.TrimSpace(line)
+		}
+	}
+
+	separator := "\n"
+	if strings.HasPrefix(indicator, ">") {
+		separator = " "
+	}
+	value := strings.Join(lines, separator)
+	if !strings.HasSuffix(indicator, "-") {
+		value += "\n"
+	}
+	return value
+}
@@ -0,0 +1,134 @@
+package envfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseDotenv(t *testing.T) {
+	data := `# comment
+export API_KEY=abc123
+PLAIN = value with spaces # trailing comment
+EMPTY=
+SINGLE='literal \n $HOME # not a comment'
+DOUBLE="line1\nline2 \"quoted\" \$HOME"
+MULTI="-----BEGIN KEY-----
+abc
+-----END KEY-----"
+URL=https://example.com/?a=b#anchor
+`
+
+	values, err := ParseDotenv([]byte(data))
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	expected := map[string]string{
+		"API_KEY": "abc123",
+		"PLAIN":   "value with spaces",
+		"EMPTY":   "",
+		"SINGLE":  `literal \n $HOME # not a comment`,
+		"DOUBLE":  "line1\nline2 \"quoted\" $HOME",
+		"MULTI":   "-----BEGIN KEY-----\nabc\n-----END KEY-----",
+		"URL":     "https://example.com/?a=b#anchor",
+	}
+	assertValues(t, expected, values)
+}
+
+func TestParseDotenv_RejectsInvalidLines(t *testing.T) {
+	for _, data := range []string{"NO_EQUALS", "1BAD=x", "OPEN=\"never closed\n", "A=\"x\" trailing"} {
+		if _, err := ParseDotenv([]byte(data)); err == nil {
+			t.Errorf("expected error for %q", data)
+		}
+	}
+}
+
+func TestParseJSON(t *testing.T) {
+	values, err := ParseJSON([]byte(`{"API_KEY": "abc", "PORT": 8080, "DEBUG": true}`))
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	assertValues(t, map[string]string{"API_KEY": "abc", "PORT": "8080", "DEBUG": "true"}, values)
+
+	if _, err := ParseJSON([]byte(`{"NESTED": {"A": "b"}}`)); err == nil {
+		t.Error("expected error for nested object")
+	}
+}
+
+func TestParseYAML(t *testing.T) {
+	data := `---
+# comment
+API_KEY: abc123
+PLAIN: value # comment
+SINGLE: 'it''s'
+DOUBLE: "a\tb"
+URL: https://example.com:8443/path
+CERT: |
+  line1
+  line2
+FOLDED: >-
+  folded
+  text
+`
+
+	values, err := ParseYAML([]byte(data))
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	expected := map[string]string{
+		"API_KEY": "abc123",
+		"PLAIN":   "value",
+		"SINGLE":  "it's",
+		"DOUBLE":  "a\tb",
+		"URL":     "https://example.com:8443/path",
+		"CERT":    "line1\nline2\n",
+		"FOLDED":  "folded text",
+	}
+	assertValues(t, expected, values)
+}
+
+func TestParseYAML_RejectsNestedValues(t *testing.T) {
+	for _, data := range []string{"DB:\n  USER: x\n", "LIST:\n- a\n", "INLINE: {a: b}\n"} {
+		if _, err := ParseYAML([]byte(data)); err == nil {
+			t.Errorf("expected error for %q", data)
+		}
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	cases := map[string]string{".env": FormatDotenv, "secrets.json": FormatJSON, "a.YML": FormatYAML, "config.yaml": FormatYAML}
+	for path, expected := range cases {
+		if format := DetectFormat(path); format != expected {
+			t.Errorf("expected %s for %s, got %s", expected, path, format)
+		}
+	}
+}
+
+func TestShred_RemovesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env")
+	os.WriteFile(path, []byte("API_KEY=secret"), 0600)
+
+	err := Shred(path)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected file to be removed, got %v", err)
+	}
+}
+
+func assertValues(t *testing.T, expected map[string]string, actual map[string]string) {
+	t.Helper()
+	if len(actual) != len(expected) {
+		t.Errorf("expected %d values, got %d: %q", len(expected), len(actual), actual)
+	}
+	for key, value := range expected {
+		if actual[key] != value {
+			t.Errorf("expected %s=%q, got %q", key, value, actual[key])
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package envfile
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Export formats besides FormatDotenv and FormatJSON.
+const (
+	// FormatShell are export statements to eval or source in a POSIX shell.
+	FormatShell = "shell"
+	// FormatDockerEnv is the file format of docker run --env-file.
+	FormatDockerEnv = "docker-env"
+	// FormatSystemdEnv is the file format of systemd's EnvironmentFile=.
+	FormatSystemdEnv = "systemd-env"
+	// FormatK8sSecret is a Kubernetes Secret manifest.
+	FormatK8sSecret = "k8s-secret"
+)
+
+// ExportFormats lists all formats supported by Render.
+var ExportFormats = []string{FormatDotenv, FormatJSON, FormatShell, FormatDockerEnv, FormatSystemdEnv, FormatK8sSecret}
+
+var invalidK8sNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// ValidateExportFormat returns an error if Render does not support the format.
+func ValidateExportFormat(format string) error {
+	for _, supported := range ExportFormats {
+		if format == supported {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown export format %s (expected %s)", format, strings.Join(ExportFormats, ", "))
+}
+
+// Render writes values in the given format. name is the metadata.name of a
+// k8s-secret and ignored by the other formats.
+func Render(format string, values map[string]string, name string) ([]byte, error) {
+	if err := ValidateExportFormat(format); err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(values))
+	for key := range values {
+		names = append(names, key)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	switch format {
+	case FormatJSON:
+		data, _ := json.MarshalIndent(values, "", "  ")
+		return append(data, '\n'), nil
+	case FormatK8sSecret:
+		fmt.Fprintf(&b, "apiVersion: v1\nkind: Secret\nmetadata:\n  name: %s\ntype: Opaque\ndata:\n", K8sName(name))
+		for _, key := range names {
+			fmt.Fprintf(&b, "  %s: %s\n", key, base64.StdEncoding.EncodeToString([]byte(values[key])))
+		}
+		return []byte(b.String()), nil
+	}
+
+	for _, key := range names {
+		value := values[key]
+		switch format {
+		case FormatDotenv:
+			fmt.Fprintf(&b, "%s=%s\n", key, doubleQuote(value))
+		case FormatShell:
+			fmt.Fprintf(&b, "export %s='%s'\n", key, strings.ReplaceAll(value, "'", `'\''`))
+		case FormatDockerEnv, FormatSystemdEnv:
+			if strings.ContainsAny(value, "\n\r") {
+				return nil, fmt.Errorf("%s: multiline values are not supported by %s", key, format)
+			}
+			if format == FormatDockerEnv {
+				fmt.Fprintf(&b, "%s=%s\n", key, value)
+			} else {
+				fmt.Fprintf(&b, "%s=%s\n", key, doubleQuote(value))
+			}
+		}
+	}
+	return []byte(b.String()), nil
+}
+
+// K8sName turns name into a valid Kubernetes object name.
+func K8sName(name string) string {
+	name = strings.Trim(invalidK8sNameChars.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if len(name) > 253 {
+		name = strings.TrimRight(name[:253], "-")
+	}
+	if name == "" {
+		return "secrets"
+	}
+	return name
+}
+
+// doubleQuote quotes value so that ParseDotenv reads it back unchanged.
+func doubleQuote(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`, "\n", `\n`, "\r", `\r`)
+	return `"` + replacer.Replace(value) + `"`
+}
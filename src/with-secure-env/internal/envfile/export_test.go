@@ -0,0 +1,74 @@
+package envfile
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestRender_DotenvRoundTrips(t *testing.T) {
+	values := map[string]string{"A": `quote " backslash \ dollar $HOME`, "B": "line1\nline2", "C": ""}
+
+	data, err := Render(FormatDotenv, values, "")
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	parsed, err := ParseDotenv(data)
+	if err != nil {
+		t.Fatalf("expected rendered dotenv to parse, got %v", err)
+	}
+	assertValues(t, values, parsed)
+}
+
+func TestRender_JSON(t *testing.T) {
+	data, _ := Render(FormatJSON, map[string]string{"API_KEY": "abc"}, "")
+
+	var parsed map[string]string
+	json.Unmarshal(data, &parsed)
+	if parsed["API_KEY"] != "abc" {
+		t.Errorf("expected API_KEY=abc, got %s", data)
+	}
+}
+
+func TestRender_ShellQuotesSingleQuotes(t *testing.T) {
+	data, _ := Render(FormatShell, map[string]string{"A": "it's $x"}, "")
+
+	if string(data) != "export A='it'\\''s $x'\n" {
+		t.Errorf("unexpected shell output %q", data)
+	}
+}
+
+func TestRender_LineFormatsRejectMultilineValues(t *testing.T) {
+	for _, format := range []string{FormatDockerEnv, FormatSystemdEnv} {
+		if _, err := Render(format, map[string]string{"A": "line1\nline2"}, ""); err == nil {
+			t.Errorf("expected error for multiline value in %s", format)
+		}
+	}
+
+	data, _ := Render(FormatDockerEnv, map[string]string{"A": "raw \"value\""}, "")
+	if string(data) != "A=raw \"value\"\n" {
+		t.Errorf("unexpected docker-env output %q", data)
+	}
+}
+
+func TestRender_K8sSecret(t *testing.T) {
+	data, err := Render(FormatK8sSecret, map[string]string{"API_KEY": "abc"}, "My_App")
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	output := string(data)
+	if !strings.Contains(output, "kind: Secret") || !strings.Contains(output, "name: my-app") {
+		t.Errorf("expected Secret named my-app, got %s", output)
+	}
+	if !strings.Contains(output, "  API_KEY: YWJj\n") {
+		t.Errorf("expected base64 encoded data, got %s", output)
+	}
+}
+
+func TestRender_RejectsUnknownFormat(t *testing.T) {
+	if _, err := Render("xml", map[string]string{}, ""); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
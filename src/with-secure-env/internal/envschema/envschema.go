@@ -0,0 +1,221 @@
+// Package envschema validates env names and the values an application
+// expects.
+//
+// A schema is JSON, declared inline in the application's settings or in a
+// manifest file next to the binary or the project file:
+//
+//	{
+//	  "variables": {
+//	    "DATABASE_URL": {"required": true, "type": "url"},
+//	    "PORT": {"type": "int"},
+//	    "GITHUB_TOKEN": {"pattern": "^gh[ps]_[A-Za-z0-9]{36}$"}
+//	  }
+//	}
+package envschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kfischer-okarin/with-secure-env/internal/envtemplate"
+)
+
+// ManifestSuffix is appended to the path of a binary to get the path of its
+// manifest, e.g. /usr/local/bin/app.secure-env-schema.json.
+const ManifestSuffix = ".secure-env-schema.json"
+
+// ProjectManifestName is the name of a project's manifest, next to its
+// project file.
+const ProjectManifestName = ".secure-env-schema.json"
+
+// Value types.
+const (
+	TypeURL  = "url"
+	TypeInt  = "int"
+	TypeJSON = "json"
+	TypePEM  = "pem"
+)
+
+var namePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ValidateName checks that name can be used as environment variable name,
+// i.e. in a NAME=value entry of an environment.
+func ValidateName(name string) error {
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("invalid env name %q (letters, digits and '_' only, not starting with a digit)", name)
+	}
+	return nil
+}
+
+// ValidateNames checks all names with ValidateName.
+func ValidateNames(names []string) error {
+	for _, name := range names {
+		if err := ValidateName(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Schema declares the variables an application expects.
+type Schema struct {
+	Variables map[string]Variable `json:"variables"`
+}
+
+// Variable declares the expectations for one variable. All fields are
+// optional.
+type Variable struct {
+	// Required variables must be present with a non-empty value.
+	Required bool `json:"required,omitempty"`
+	// Type is url, int, json or pem.
+	Type string `json:"type,omitempty"`
+	// Pattern is a regular expression the value must match.
+	Pattern     string `json:"pattern,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// Parse parses and checks a schema.
+func Parse(data []byte) (Schema, error) {
+	var schema Schema
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&schema); err != nil {
+		return Schema{}, fmt.Errorf("invalid schema: %w", err)
+	}
+	for name, variable := range schema.Variables {
+		if err := ValidateName(name); err != nil {
+			return Schema{}, err
+		}
+		switch variable.Type {
+		case "", TypeURL, TypeInt, TypeJSON, TypePEM:
+		default:
+			return Schema{}, fmt.Errorf("unknown type %s of %s (expected url, int, json or pem)", variable.Type, name)
+		}
+		if _, err := regexp.Compile(variable.Pattern); err != nil {
+			return Schema{}, fmt.Errorf("invalid pattern of %s: %w", name, err)
+		}
+	}
+	return schema, nil
+}
+
+// Merge returns the variables of both schemas, other's declarations replacing
+// those of s.
+func (s Schema) Merge(other Schema) Schema {
+	merged := Schema{Variables: map[string]Variable{}}
+	for name, variable := range s.Variables {
+		merged.Variables[name] = variable
+	}
+	for name, variable := range other.Variables {
+		merged.Variables[name] = variable
+	}
+	return merged
+}
+
+// IsEmpty reports whether the schema declares no variables.
+func (s Schema) IsEmpty() bool {
+	return len(s.Variables) == 0
+}
+
+// Missing returns the sorted required variables not in names.
+func (s Schema) Missing(names []string) []string {
+	present := map[string]bool{}
+	for _, name := range names {
+		present[name] = true
+	}
+	var missing []string
+	for name, variable := range s.Variables {
+		if variable.Required && !present[name] {
+			missing = append(missing, name)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// Check validates values and returns the problems by env name, or nil. Names
+// in provided are supplied elsewhere (e.g. by a group) and count as present.
+// Values containing references are only checked once resolved, so their
+// types and patterns are skipped.
+func (s Schema) Check(values map[string]string, provided []string) map[string]string {
+	problems := map[string]string{}
+	for name, value := range values {
+		if err := ValidateName(name); err != nil {
+			problems[name] = err.Error()
+			continue
+		}
+		if problem := s.Variables[name].check(value); problem != "" {
+			problems[name] = problem
+		}
+	}
+	names := append([]string{}, provided...)
+	for name := range values {
+		names = append(names, name)
+	}
+	for _, name := range s.Missing(names) {
+		problems[name] = "required"
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return problems
+}
+
+func (v Variable) check(value string) string {
+	if value == "" {
+		if v.Required {
+			return "required"
+		}
+		return ""
+	}
+	if envtemplate.HasReferences(value) {
+		return ""
+	}
+	if v.Type != "" && !validType(v.Type, value) {
+		return "expected " + v.Type
+	}
+	if v.Pattern != "" && !regexp.MustCompile(v.Pattern).MatchString(value) {
+		return "does not match " + v.Pattern
+	}
+	return ""
+}
+
+func validType(kind string, value string) bool {
+	switch kind {
+	case TypeURL:
+		parsed, err := url.Parse(value)
+		return err == nil && parsed.Scheme != "" && (parsed.Host != "" || parsed.Opaque != "")
+	case TypeInt:
+		_, err := strconv.ParseInt(value, 10, 64)
+		return err == nil
+	case TypeJSON:
+		return json.Valid([]byte(value))
+	case TypePEM:
+		block, rest := pem.Decode([]byte(value))
+		for block != nil && len(bytes.TrimSpace(rest)) > 0 {
+			block, rest = pem.Decode(rest)
+		}
+		return block != nil
+	}
+	return true
+}
+
+// Error summarizes problems returned by Check.
+func Error(problems map[string]string) error {
+	names := make([]string, 0, len(problems))
+	for name := range problems {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	messages := make([]string, len(names))
+	for i, name := range names {
+		messages[i] = name + ": " + problems[name]
+	}
+	return fmt.Errorf("invalid envs (%s)", strings.Join(messages, "; "))
+}
@@ -0,0 +1,101 @@
+package envschema
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidateName(t *testing.T) {
+	for _, name := range []string{"API_KEY", "_private", "a1"} {
+		if err := ValidateName(name); err != nil {
+			t.Errorf("expected %q to be valid, got %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "A=B", "API KEY", "1ST", "A-B", "A.B"} {
+		if err := ValidateName(name); err == nil {
+			t.Errorf("expected %q to be invalid", name)
+		}
+	}
+}
+
+func TestParse_RejectsInvalidDeclarations(t *testing.T) {
+	for _, data := range []string{
+		`{"variables": {"A B": {}}}`,
+		`{"variables": {"PORT": {"type": "number"}}}`,
+		`{"variables": {"TOKEN": {"pattern": "("}}}`,
+		`{"variables": {"PORT": {"requried": true}}}`,
+	} {
+		if _, err := Parse([]byte(data)); err == nil {
+			t.Errorf("expected error for %s", data)
+		}
+	}
+}
+
+func TestCheck_ValidatesTypesAndPatterns(t *testing.T) {
+	schema, err := Parse([]byte(`{"variables": {
+		"URL": {"type": "url"},
+		"PORT": {"type": "int"},
+		"CONFIG": {"type": "json"},
+		"CERT": {"type": "pem"},
+		"TOKEN": {"pattern": "^ghp_"}
+	}}`))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	cert := "-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----\n"
+
+	valid := map[string]string{"URL": "postgres://db/app", "PORT": "8080", "CONFIG": `{"a": 1}`, "CERT": cert + cert, "TOKEN": "ghp_abc"}
+	if problems := schema.Check(valid, nil); problems != nil {
+		t.Errorf("expected no problems, got %v", problems)
+	}
+
+	invalid := map[string]string{"URL": "db/app", "PORT": "80a", "CONFIG": "{", "CERT": "MIIB", "TOKEN": "abc"}
+	problems := schema.Check(invalid, nil)
+	if len(problems) != 5 {
+		t.Errorf("expected problems for all values, got %v", problems)
+	}
+	if problems["PORT"] != "expected int" {
+		t.Errorf("unexpected problem %q", problems["PORT"])
+	}
+}
+
+func TestCheck_ReportsMissingRequiredVariables(t *testing.T) {
+	schema := Schema{Variables: map[string]Variable{
+		"A": {Required: true},
+		"B": {Required: true},
+		"C": {Required: true},
+		"D": {},
+	}}
+
+	problems := schema.Check(map[string]string{"A": "1", "B": ""}, []string{"C"})
+
+	expected := map[string]string{"B": "required"}
+	if !reflect.DeepEqual(problems, expected) {
+		t.Errorf("expected %v, got %v", expected, problems)
+	}
+	if missing := schema.Missing([]string{"A"}); !reflect.DeepEqual(missing, []string{"B", "C"}) {
+		t.Errorf("expected B and C to be missing, got %v", missing)
+	}
+}
+
+func TestCheck_RejectsInvalidNamesAndSkipsTemplates(t *testing.T) {
+	schema := Schema{Variables: map[string]Variable{"PORT": {Type: TypeInt}}}
+
+	problems := schema.Check(map[string]string{"PORT": "${BASE_PORT}", "A=B": "x"}, nil)
+
+	if len(problems) != 1 || !strings.Contains(problems["A=B"], "invalid env name") {
+		t.Errorf("expected only the invalid name to be reported, got %v", problems)
+	}
+}
+
+func TestMerge_LaterDeclarationsWin(t *testing.T) {
+	inline := Schema{Variables: map[string]Variable{"A": {Type: TypeInt}, "B": {Required: true}}}
+	manifest := Schema{Variables: map[string]Variable{"A": {Type: TypeURL}}}
+
+	merged := inline.Merge(manifest)
+
+	if merged.Variables["A"].Type != TypeURL || !merged.Variables["B"].Required {
+		t.Errorf("unexpected merge result %v", merged)
+	}
+}
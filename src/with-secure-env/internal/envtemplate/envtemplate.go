@@ -0,0 +1,188 @@
+// Package envtemplate resolves references between env values.
+//
+// Only values marked as templates are expanded, all others are used
+// literally. In templates ${NAME} is replaced by the value of NAME in the same
+// scope (the entry, or the group the value belongs to) and ${group:NAME} by
+// the value of NAME in the named group. Referenced values may be templates
+// themselves. $${ is an escaped, literal ${.
+package envtemplate
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var referencePattern = regexp.MustCompile(`^(?:([A-Za-z0-9_.-]+):)?([A-Za-z_][A-Za-z0-9_.]*)$`)
+
+// Scope holds the plaintext values references are resolved among.
+type Scope struct {
+	Values map[string]string
+	// Templates are the names of the values to expand.
+	Templates map[string]bool
+	// Groups maps names whose values come from a group to that group, so
+	// their references resolve within the group.
+	Groups map[string]string
+}
+
+// GroupLookup returns the scope of a group.
+type GroupLookup func(group string) (Scope, bool)
+
+// Unescape returns a value without references as literal value.
+func Unescape(value string) string {
+	return strings.ReplaceAll(value, "$${", "${")
+}
+
+// HasReferences reports whether the value contains an unescaped ${.
+func HasReferences(value string) bool {
+	for i := 0; i < len(value); i++ {
+		if strings.HasPrefix(value[i:], "$${") {
+			i += 2
+			continue
+		}
+		if strings.HasPrefix(value[i:], "${") {
+			return true
+		}
+	}
+	return false
+}
+
+// Resolve returns the values of names in scope with references replaced and
+// escapes removed. Only templates involved in these values are expanded. It
+// fails for references to unknown names or groups, malformed references and
+// reference cycles.
+func Resolve(scope Scope, names []string, groups GroupLookup) (map[string]string, error) {
+	r := &resolver{
+		scopes:   map[string]Scope{"": scope},
+		groups:   groups,
+		resolved: map[reference]string{},
+		visiting: map[reference]bool{},
+	}
+	result := make(map[string]string, len(names))
+	for _, name := range names {
+		value, err := r.resolve(reference{name: name})
+		if err != nil {
+			return nil, err
+		}
+		result[name] = value
+	}
+	return result, nil
+}
+
+type reference struct {
+	// group is empty for the entry's own values.
+	group string
+	name  string
+}
+
+func (ref reference) String() string {
+	if ref.group == "" {
+		return ref.name
+	}
+	return ref.group + ":" + ref.name
+}
+
+type resolver struct {
+	scopes   map[string]Scope
+	groups   GroupLookup
+	resolved map[reference]string
+	visiting map[reference]bool
+	path     []reference
+}
+
+func (r *resolver) resolve(ref reference) (string, error) {
+	if value, ok := r.resolved[ref]; ok {
+		return value, nil
+	}
+	if r.visiting[ref] {
+		return "", fmt.Errorf("reference cycle: %s", r.cycle(ref))
+	}
+	scope, err := r.scope(ref.group)
+	if err != nil {
+		return "", fmt.Errorf("%w in ${%s}", err, ref)
+	}
+	if group, ok := scope.Groups[ref.name]; ok {
+		return r.resolve(reference{group: group, name: ref.name})
+	}
+	raw, ok := scope.Values[ref.name]
+	if !ok {
+		return "", fmt.Errorf("undefined variable ${%s}", ref)
+	}
+	if !scope.Templates[ref.name] {
+		r.resolved[ref] = raw
+		return raw, nil
+	}
+
+	r.visiting[ref] = true
+	r.path = append(r.path, ref)
+	value, err := r.expand(ref, raw)
+	r.path = r.path[:len(r.path)-1]
+	delete(r.visiting, ref)
+	if err != nil {
+		return "", err
+	}
+	r.resolved[ref] = value
+	return value, nil
+}
+
+func (r *resolver) scope(group string) (Scope, error) {
+	if scope, ok := r.scopes[group]; ok {
+		return scope, nil
+	}
+	var scope Scope
+	ok := false
+	if r.groups != nil {
+		scope, ok = r.groups(group)
+	}
+	if !ok {
+		return Scope{}, fmt.Errorf("unknown group %s", group)
+	}
+	r.scopes[group] = scope
+	return scope, nil
+}
+
+// expand replaces the references in the raw value of ref.
+func (r *resolver) expand(ref reference, raw string) (string, error) {
+	var result strings.Builder
+	for i := 0; i < len(raw); i++ {
+		if strings.HasPrefix(raw[i:], "$${") {
+			result.WriteString("${")
+			i += 2
+			continue
+		}
+		if !strings.HasPrefix(raw[i:], "${") {
+			result.WriteByte(raw[i])
+			continue
+		}
+		end := strings.IndexByte(raw[i:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("%s: unterminated ${ (write $${ for a literal ${)", ref)
+		}
+		match := referencePattern.FindStringSubmatch(raw[i+2 : i+end])
+		if match == nil {
+			return "", fmt.Errorf("%s: invalid reference %s (write $${ for a literal ${)", ref, raw[i:i+end+1])
+		}
+		target := reference{group: match[1], name: match[2]}
+		if target.group == "" {
+			target.group = ref.group
+		}
+		value, err := r.resolve(target)
+		if err != nil {
+			return "", err
+		}
+		result.WriteString(value)
+		i += end
+	}
+	return result.String(), nil
+}
+
+func (r *resolver) cycle(ref reference) string {
+	var names []string
+	for i := len(r.path) - 1; i >= 0; i-- {
+		names = append([]string{r.path[i].String()}, names...)
+		if r.path[i] == ref {
+			break
+		}
+	}
+	return strings.Join(append(names, ref.String()), " -> ")
+}
@@ -0,0 +1,97 @@
+// Package execcredential implements the ExecCredential objects of the
+// client.authentication.k8s.io API used by kubectl exec credential plugins.
+// The request is passed in the KUBERNETES_EXEC_INFO environment variable and
+// the response printed to stdout.
+package execcredential
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Supported API versions. Responses use the version of the request.
+const (
+	V1      = "client.authentication.k8s.io/v1"
+	V1beta1 = "client.authentication.k8s.io/v1beta1"
+)
+
+// Kind of all ExecCredential objects.
+const Kind = "ExecCredential"
+
+// EnvName is the environment variable holding the request.
+const EnvName = "KUBERNETES_EXEC_INFO"
+
+// ExecCredential is the request and response object.
+type ExecCredential struct {
+	APIVersion string  `json:"apiVersion"`
+	Kind       string  `json:"kind"`
+	Spec       Spec    `json:"spec"`
+	Status     *Status `json:"status,omitempty"`
+}
+
+// Spec describes the request.
+type Spec struct {
+	Cluster     *Cluster `json:"cluster,omitempty"`
+	Interactive bool     `json:"interactive"`
+}
+
+// Cluster is the cluster kubectl connects to, sent if provideClusterInfo is
+// set in the kubeconfig.
+type Cluster struct {
+	Server                   string          `json:"server"`
+	TLSServerName            string          `json:"tls-server-name,omitempty"`
+	InsecureSkipTLSVerify    bool            `json:"insecure-skip-tls-verify,omitempty"`
+	CertificateAuthorityData []byte          `json:"certificate-authority-data,omitempty"`
+	ProxyURL                 string          `json:"proxy-url,omitempty"`
+	DisableCompression       bool            `json:"disable-compression,omitempty"`
+	Config                   json.RawMessage `json:"config,omitempty"`
+}
+
+// Status holds the credential: a bearer token or a PEM encoded client
+// certificate and key.
+type Status struct {
+	ExpirationTimestamp   *time.Time `json:"expirationTimestamp,omitempty"`
+	Token                 string     `json:"token,omitempty"`
+	ClientCertificateData string     `json:"clientCertificateData,omitempty"`
+	ClientKeyData         string     `json:"clientKeyData,omitempty"`
+}
+
+// ParseRequest parses the value of KUBERNETES_EXEC_INFO. An empty value is
+// treated as a v1 request, for running the plugin by hand.
+func ParseRequest(execInfo string) (ExecCredential, error) {
+	if execInfo == "" {
+		return ExecCredential{APIVersion: V1, Kind: Kind}, nil
+	}
+	var request ExecCredential
+	if err := json.Unmarshal([]byte(execInfo), &request); err != nil {
+		return ExecCredential{}, fmt.Errorf("invalid %s: %w", EnvName, err)
+	}
+	if request.Kind != Kind {
+		return ExecCredential{}, fmt.Errorf("unexpected kind %q in %s", request.Kind, EnvName)
+	}
+	if request.APIVersion != V1 && request.APIVersion != V1beta1 {
+		return ExecCredential{}, fmt.Errorf("unsupported apiVersion %q", request.APIVersion)
+	}
+	return request, nil
+}
+
+// Response returns the response to request with the credential.
+func Response(request ExecCredential, status Status) (ExecCredential, error) {
+	if err := status.Validate(); err != nil {
+		return ExecCredential{}, err
+	}
+	return ExecCredential{APIVersion: request.APIVersion, Kind: Kind, Status: &status}, nil
+}
+
+// Validate checks that the status holds a token or both certificate and key.
+func (s Status) Validate() error {
+	if (s.ClientCertificateData == "") != (s.ClientKeyData == "") {
+		return errors.New("client certificate and key must be set together")
+	}
+	if s.Token == "" && s.ClientCertificateData == "" {
+		return errors.New("credential needs a token or a client certificate and key")
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+// Package gitcredential reads and writes the attribute format of the git
+// credential helper protocol (see gitcredentials(7)): key=value lines
+// terminated by a blank line or end of input.
+package gitcredential
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Credential holds the attributes used by with-secure-env. Other attributes
+// are ignored.
+type Credential struct {
+	Protocol string
+	Host     string
+	Path     string
+	Username string
+	Password string
+}
+
+// Read parses the attributes sent by git.
+func Read(r io.Reader) (Credential, error) {
+	var credential Credential
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			break
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return Credential{}, fmt.Errorf("invalid credential line %q", line)
+		}
+		switch key {
+		case "protocol":
+			credential.Protocol = value
+		case "host":
+			credential.Host = value
+		case "path":
+			credential.Path = value
+		case "username":
+			credential.Username = value
+		case "password":
+			credential.Password = value
+		case "url":
+			credential = fromURL(credential, value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return Credential{}, err
+	}
+	if credential.Protocol == "" || credential.Host == "" {
+		return Credential{}, fmt.Errorf("credential needs protocol and host")
+	}
+	return credential, nil
+}
+
+// Write sends username and password back to git. Empty attributes are
+// omitted.
+func Write(w io.Writer, credential Credential) error {
+	for _, attribute := range [][2]string{{"username", credential.Username}, {"password", credential.Password}} {
+		if attribute[1] == "" {
+			continue
+		}
+		if strings.ContainsAny(attribute[1], "\n\x00") {
+			return fmt.Errorf("%s contains a newline or NUL byte", attribute[0])
+		}
+		if _, err := fmt.Fprintf(w, "%s=%s\n", attribute[0], attribute[1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// URL returns protocol://host[/path], which identifies the credential.
+func (c Credential) URL() string {
+	url := c.Protocol + "://" + c.Host
+	if c.Path != "" {
+		url += "/" + strings.TrimPrefix(c.Path, "/")
+	}
+	return url
+}
+
+// fromURL fills protocol, host and path from a url attribute, which git
+// sends instead of the single attributes with credential.useHttpPath.
+func fromURL(credential Credential, url string) Credential {
+	protocol, rest, ok := strings.Cut(url, "://")
+	if !ok {
+		return credential
+	}
+	host, path, _ := strings.Cut(rest, "/")
+	if at := strings.LastIndex(host, "@"); at >= 0 {
+		if credential.Username == "" {
+			credential.Username, _, _ = strings.Cut(host[:at], ":")
+		}
+		host = host[at+1:]
+	}
+	credential.Protocol, credential.Host, credential.Path = protocol, host, path
+	return credential
+}
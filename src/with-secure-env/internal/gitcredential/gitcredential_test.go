@@ -0,0 +1,63 @@
+package gitcredential
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRead_ParsesAttributesUntilBlankLine(t *testing.T) {
+	input := "protocol=https\nhost=github.com\npath=org/repo.git\nusername=octocat\nwwwauth[]=Basic\n\nprotocol=ignored\n"
+
+	credential, err := Read(strings.NewReader(input))
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	expected := Credential{Protocol: "https", Host: "github.com", Path: "org/repo.git", Username: "octocat"}
+	if credential != expected {
+		t.Errorf("expected %+v, got %+v", expected, credential)
+	}
+	if credential.URL() != "https://github.com/org/repo.git" {
+		t.Errorf("unexpected URL %s", credential.URL())
+	}
+}
+
+func TestRead_SplitsURLAttribute(t *testing.T) {
+	credential, err := Read(strings.NewReader("url=https://octocat@github.com/org/repo.git\n"))
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	expected := Credential{Protocol: "https", Host: "github.com", Path: "org/repo.git", Username: "octocat"}
+	if credential != expected {
+		t.Errorf("expected %+v, got %+v", expected, credential)
+	}
+}
+
+func TestRead_FailsWithoutHost(t *testing.T) {
+	if _, err := Read(strings.NewReader("protocol=https\n")); err == nil {
+		t.Error("expected error for missing host")
+	}
+}
+
+func TestWrite_WritesUsernameAndPassword(t *testing.T) {
+	var output bytes.Buffer
+
+	err := Write(&output, Credential{Protocol: "https", Host: "github.com", Username: "octocat", Password: "token"})
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if output.String() != "username=octocat\npassword=token\n" {
+		t.Errorf("unexpected output %q", output.String())
+	}
+}
+
+func TestWrite_RejectsNewlines(t *testing.T) {
+	var output bytes.Buffer
+
+	if err := Write(&output, Credential{Password: "a\nhost=evil"}); err == nil {
+		t.Error("expected error for newline in password")
+	}
+}
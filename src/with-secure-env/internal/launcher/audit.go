@@ -0,0 +1,229 @@
+package launcher
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/kfischer-okarin/with-secure-env/internal/permissiondialog"
+)
+
+// AuditEntry records one security relevant operation. Values of secrets are
+// never recorded, only their names.
+//
+// Entries are hash-chained: Hash covers the entry including PrevHash, so
+// changing or deleting an entry breaks the chain for all following entries.
+// The hash is an HMAC keyed with a secret derived from the encryption key, so
+// a modified log cannot be re-chained without it.
+type AuditEntry struct {
+	Time     time.Time `json:"time"`
+	Action   string    `json:"action"`
+	Result   string    `json:"result,omitempty"`
+	Caller   []string  `json:"caller,omitempty"`
+	App      string    `json:"app,omitempty"`
+	Entry    string    `json:"entry,omitempty"`
+	Profile  string    `json:"profile,omitempty"`
+	Group    string    `json:"group,omitempty"`
+	Project  string    `json:"project,omitempty"`
+	Args     []string  `json:"args,omitempty"`
+	EnvNames []string  `json:"envNames,omitempty"`
+	PrevHash string    `json:"prevHash"`
+	Hash     string    `json:"hash"`
+}
+
+// AuditFilter selects audit entries. Zero values match everything.
+type AuditFilter struct {
+	App    string
+	Action string
+	Since  time.Time
+}
+
+// AuditEntries returns the audit entries matching the filter, oldest first.
+func (l *Launcher) AuditEntries(filter AuditFilter) ([]AuditEntry, error) {
+	entries, err := l.loadAuditEntries()
+	if err != nil {
+		return nil, err
+	}
+	var result []AuditEntry
+	for _, entry := range entries {
+		if filter.App != "" && entry.App != filter.App {
+			continue
+		}
+		if filter.Action != "" && entry.Action != filter.Action {
+			continue
+		}
+		if !filter.Since.IsZero() && entry.Time.Before(filter.Since) {
+			continue
+		}
+		result = append(result, entry)
+	}
+	return result, nil
+}
+
+// VerifyAuditLog checks the hash chain of the audit log and returns an error
+// describing the first entry that was modified, inserted or deleted.
+func (l *Launcher) VerifyAuditLog() error {
+	entries, err := l.loadAuditEntries()
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+	key, err := l.auditKey()
+	if err != nil {
+		return err
+	}
+	prevHash := ""
+	for i, entry := range entries {
+		if entry.PrevHash != prevHash {
+			return fmt.Errorf("audit entry %d does not follow entry %d (deleted or reordered entries)", i+1, i)
+		}
+		if entry.Hash == "" {
+			return fmt.Errorf("audit entry %d was written without the encryption key and cannot be verified", i+1)
+		}
+		if !hmac.Equal([]byte(entry.Hash), []byte(hashAuditEntry(key, entry))) {
+			return fmt.Errorf("audit entry %d was modified", i+1)
+		}
+		prevHash = entry.Hash
+	}
+	return nil
+}
+
+// audit appends an entry to the log. The log is locked while the previous
+// hash is read and the entry appended, so concurrent processes don't fork the
+// chain. Without the encryption key the entry is appended without hash.
+func (l *Launcher) audit(entry AuditEntry) {
+	key, keyErr := l.auditKey()
+	file, err := os.OpenFile(l.auditLogPath(), os.O_APPEND|os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX); err != nil {
+		return
+	}
+	defer syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+
+	last, err := lastLine(file)
+	if err != nil {
+		return
+	}
+	if len(last) > 0 {
+		var previous AuditEntry
+		json.Unmarshal(last, &previous)
+		entry.PrevHash = previous.Hash
+	}
+	entry.Time = l.now()
+	if keyErr == nil {
+		entry.Hash = hashAuditEntry(key, entry)
+	}
+
+	line, _ := json.Marshal(entry)
+	file.Write(append(line, '\n'))
+}
+
+// lastLine returns the last line of file without its newline, reading
+// backwards from the end.
+func lastLine(file *os.File) ([]byte, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	end := info.Size()
+	var line []byte
+	chunk := make([]byte, 4096)
+	for offset := end; offset > 0; {
+		size := min(int64(len(chunk)), offset)
+		offset -= size
+		if _, err := file.ReadAt(chunk[:size], offset); err != nil {
+			return nil, err
+		}
+		line = append(append([]byte{}, chunk[:size]...), line...)
+		trimmed := bytes.TrimRight(line, "\n")
+		if index := bytes.LastIndexByte(trimmed, '\n'); index >= 0 {
+			return trimmed[index+1:], nil
+		}
+	}
+	return bytes.TrimRight(line, "\n"), nil
+}
+
+func (l *Launcher) loadAuditEntries() ([]AuditEntry, error) {
+	file, err := os.Open(l.auditLogPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []AuditEntry
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			var entry AuditEntry
+			if jsonErr := json.Unmarshal(line, &entry); jsonErr != nil {
+				return nil, fmt.Errorf("audit entry %d: %w", len(entries)+1, jsonErr)
+			}
+			entries = append(entries, entry)
+		}
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (l *Launcher) auditLogPath() string {
+	return filepath.Join(l.ConfigDirPath, "audit.log")
+}
+
+// auditKey returns the key of the audit log hashes. It is derived from the
+// encryption key and cached, so later entries don't access the keychain again.
+func (l *Launcher) auditKey() ([]byte, error) {
+	if l.auditHashKey == nil {
+		key, err := l.Keychain.RetrieveEncryptionKey()
+		if err != nil {
+			return nil, err
+		}
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte("with-secure-env audit log"))
+		l.auditHashKey = mac.Sum(nil)
+	}
+	return l.auditHashKey, nil
+}
+
+func hashAuditEntry(key []byte, entry AuditEntry) string {
+	entry.Hash = ""
+	data, _ := json.Marshal(entry)
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func callerChain(caller permissiondialog.CallerInfo) []string {
+	if caller.Name == "" && caller.PID == 0 {
+		return nil
+	}
+
+	chain := []string{caller.Name + "[" + strconv.Itoa(caller.PID) + "]"}
+	for _, parent := range caller.Parents {
+		chain = append(chain, parent.Name+"["+strconv.Itoa(parent.PID)+"]")
+	}
+	return chain
+}
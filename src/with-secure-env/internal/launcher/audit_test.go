@@ -0,0 +1,208 @@
+package launcher
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kfischer-okarin/with-secure-env/internal/permissiondialog"
+)
+
+func TestAudit_RecordsLaunchDecisionWithoutValues(t *testing.T) {
+	launcher, _, editDialog, permDialog := newTestLauncher(t)
+	launcher.Init()
+	editDialog.returnValues = map[string]string{"API_KEY": "topsecret"}
+	editDialog.returnOk = true
+	launcher.EditEnvs("/path/to/app")
+
+	caller := permissiondialog.CallerInfo{
+		Name:    "bash",
+		PID:     1234,
+		Parents: []permissiondialog.CallerInfo{{Name: "claude", PID: 1000}},
+	}
+	permDialog.returnGranted = false
+	launcher.Launch("/path/to/app", []string{"--flag"}, caller)
+
+	entries, _ := launcher.AuditEntries(AuditFilter{Action: "launch"})
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 launch entry, got %d", len(entries))
+	}
+	entry := entries[0]
+	if entry.Result != "denied" || entry.App != "/path/to/app" {
+		t.Errorf("expected denied launch of '/path/to/app', got %+v", entry)
+	}
+	if strings.Join(entry.Caller, " ") != "bash[1234] claude[1000]" {
+		t.Errorf("expected caller chain 'bash[1234] claude[1000]', got %v", entry.Caller)
+	}
+	if len(entry.Args) != 1 || entry.Args[0] != "--flag" {
+		t.Errorf("expected args ['--flag'], got %v", entry.Args)
+	}
+	if len(entry.EnvNames) != 1 || entry.EnvNames[0] != "API_KEY" {
+		t.Errorf("expected env names ['API_KEY'], got %v", entry.EnvNames)
+	}
+
+	data, _ := os.ReadFile(filepath.Join(launcher.ConfigDirPath, "audit.log"))
+	if strings.Contains(string(data), "topsecret") {
+		t.Error("expected audit log to not contain secret values")
+	}
+}
+
+func TestAudit_RecordsInitAndEdits(t *testing.T) {
+	launcher, _, editDialog, _ := newTestLauncher(t)
+	launcher.Init()
+	editDialog.returnValues = map[string]string{"API_KEY": "secret"}
+	editDialog.returnOk = true
+	launcher.EditEnvs("/path/to/app")
+	editDialog.returnOk = false
+	launcher.EditEnvs("/path/to/app")
+
+	entries, _ := launcher.AuditEntries(AuditFilter{})
+
+	actions := []string{}
+	for _, entry := range entries {
+		actions = append(actions, entry.Action+":"+entry.Result)
+	}
+	if strings.Join(actions, " ") != "init:ok edit:saved edit:canceled" {
+		t.Errorf("expected 'init:ok edit:saved edit:canceled', got %v", actions)
+	}
+}
+
+func TestAudit_FiltersByAppAndTime(t *testing.T) {
+	launcher, _, editDialog, _ := newTestLauncher(t)
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	launcher.Now = func() time.Time { return now }
+	launcher.Init()
+	editDialog.returnOk = true
+	launcher.EditEnvs("/path/to/app1")
+	now = now.Add(time.Hour)
+	launcher.EditEnvs("/path/to/app2")
+	launcher.EditEnvs("/path/to/app1")
+
+	entries, _ := launcher.AuditEntries(AuditFilter{App: "/path/to/app1", Since: now})
+
+	if len(entries) != 1 || !entries[0].Time.Equal(now) {
+		t.Errorf("expected only the latest edit of app1, got %+v", entries)
+	}
+}
+
+func TestAudit_VerifiesIntactChain(t *testing.T) {
+	launcher, _, editDialog, _ := newTestLauncher(t)
+	launcher.Init()
+	editDialog.returnOk = true
+	launcher.EditEnvs("/path/to/app")
+
+	if err := launcher.VerifyAuditLog(); err != nil {
+		t.Errorf("expected intact chain, got %v", err)
+	}
+}
+
+func TestAudit_DetectsModifiedEntry(t *testing.T) {
+	launcher, _, editDialog, _ := newTestLauncher(t)
+	launcher.Init()
+	editDialog.returnOk = true
+	launcher.EditEnvs("/path/to/app")
+	launcher.EditEnvs("/path/to/app")
+
+	logPath := filepath.Join(launcher.ConfigDirPath, "audit.log")
+	data, _ := os.ReadFile(logPath)
+	os.WriteFile(logPath, []byte(strings.Replace(string(data), "/path/to/app", "/path/to/other", 1)), 0600)
+
+	if err := launcher.VerifyAuditLog(); err == nil {
+		t.Error("expected modified entry to be detected")
+	}
+}
+
+func TestAudit_DetectsDeletedEntry(t *testing.T) {
+	launcher, _, editDialog, _ := newTestLauncher(t)
+	launcher.Init()
+	editDialog.returnOk = true
+	launcher.EditEnvs("/path/to/app")
+	launcher.EditEnvs("/path/to/app")
+
+	logPath := filepath.Join(launcher.ConfigDirPath, "audit.log")
+	data, _ := os.ReadFile(logPath)
+	lines := strings.SplitAfter(string(data), "\n")
+	os.WriteFile(logPath, []byte(lines[0]+lines[2]), 0600)
+
+	if err := launcher.VerifyAuditLog(); err == nil {
+		t.Error("expected deleted entry to be detected")
+	}
+}
+
+func TestAudit_DetectsLogRechainedWithoutKey(t *testing.T) {
+	launcher, kc, editDialog, _ := newTestLauncher(t)
+	launcher.Init()
+	editDialog.returnOk = true
+	launcher.EditEnvs("/path/to/app")
+
+	entries, _ := launcher.AuditEntries(AuditFilter{})
+	var rewritten []byte
+	prevHash := ""
+	for _, entry := range entries {
+		entry.App = strings.Replace(entry.App, "/path/to/app", "/path/to/other", 1)
+		entry.PrevHash = prevHash
+		entry.Hash = hashAuditEntry([]byte("guessed key"), entry)
+		prevHash = entry.Hash
+		line, _ := json.Marshal(entry)
+		rewritten = append(append(rewritten, line...), '\n')
+	}
+	os.WriteFile(filepath.Join(launcher.ConfigDirPath, "audit.log"), rewritten, 0600)
+	verifier := &Launcher{Keychain: kc, ConfigDirPath: launcher.ConfigDirPath}
+
+	if err := verifier.VerifyAuditLog(); err == nil || !strings.Contains(err.Error(), "entry 1 was modified") {
+		t.Errorf("expected re-chained log to be detected, got %v", err)
+	}
+}
+
+func TestAudit_ConcurrentAppendsKeepChainIntact(t *testing.T) {
+	launcher, _, _, _ := newTestLauncher(t)
+	other := *launcher
+
+	var wg sync.WaitGroup
+	for _, l := range []*Launcher{launcher, &other} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 50 {
+				l.audit(AuditEntry{Action: "configure"})
+			}
+		}()
+	}
+	wg.Wait()
+
+	entries, _ := launcher.AuditEntries(AuditFilter{})
+	if len(entries) != 100 {
+		t.Errorf("expected 100 entries, got %d", len(entries))
+	}
+	if err := launcher.VerifyAuditLog(); err != nil {
+		t.Errorf("expected intact chain, got %v", err)
+	}
+}
+
+func TestAudit_ReadsLongEntries(t *testing.T) {
+	launcher, _, _, _ := newTestLauncher(t)
+	launcher.audit(AuditEntry{Action: "launch", Args: []string{strings.Repeat("x", 2*1024*1024)}})
+	launcher.audit(AuditEntry{Action: "exit"})
+
+	entries, err := launcher.AuditEntries(AuditFilter{})
+
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d, %v", len(entries), err)
+	}
+	if err := launcher.VerifyAuditLog(); err != nil {
+		t.Errorf("expected intact chain, got %v", err)
+	}
+}
+
+func TestAudit_ReportsUnreadableLog(t *testing.T) {
+	launcher, _, _, _ := newTestLauncher(t)
+	os.WriteFile(filepath.Join(launcher.ConfigDirPath, "audit.log"), []byte("{not json\n"), 0600)
+
+	if _, err := launcher.AuditEntries(AuditFilter{}); err == nil {
+		t.Error("expected error for unreadable entry")
+	}
+}
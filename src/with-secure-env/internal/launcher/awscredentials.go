@@ -0,0 +1,87 @@
+package launcher
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/kfischer-okarin/with-secure-env/internal/permissiondialog"
+)
+
+// Env names read from AWS entries. The session token and expiration are
+// optional.
+const (
+	AWSAccessKeyIDEnv     = "AWS_ACCESS_KEY_ID"
+	AWSSecretAccessKeyEnv = "AWS_SECRET_ACCESS_KEY"
+	AWSSessionTokenEnv    = "AWS_SESSION_TOKEN"
+	AWSExpirationEnv      = "AWS_CREDENTIAL_EXPIRATION"
+)
+
+// AWSCredentials is the output of a credential_process.
+type AWSCredentials struct {
+	Version         int    `json:"Version"`
+	AccessKeyID     string `json:"AccessKeyId"`
+	SecretAccessKey string `json:"SecretAccessKey"`
+	SessionToken    string `json:"SessionToken,omitempty"`
+	Expiration      string `json:"Expiration,omitempty"`
+}
+
+const awsEntryPrefix = "aws:"
+
+// AWSCredentialEntry returns the entry key of an AWS profile: aws:<profile>.
+func AWSCredentialEntry(awsProfile string) string {
+	return awsEntryPrefix + awsProfile
+}
+
+// AWSCredentialProcess asks for permission and returns the credentials of
+// the AWS profile. The entry works like an application entry, so its keys may
+// come from its profiles or shared groups.
+func (l *Launcher) AWSCredentialProcess(awsProfile string, caller permissiondialog.CallerInfo) (AWSCredentials, error) {
+	if err := validateName("AWS profile", awsProfile); err != nil {
+		return AWSCredentials{}, err
+	}
+	entryKey := AWSCredentialEntry(awsProfile)
+	resolvedPath := l.resolveEntry(entryKey)
+	settings := l.loadSettings()[resolvedPath]
+	profile := profileFor(settings, "")
+	encryptedEnvs := l.mergeGroupEnvs(settings, l.loadProfileEnvs(resolvedPath, profile))
+
+	requested := map[string]string{}
+	for _, name := range []string{AWSAccessKeyIDEnv, AWSSecretAccessKeyEnv, AWSSessionTokenEnv, AWSExpirationEnv} {
+		if encrypted, ok := encryptedEnvs[name]; ok {
+			requested[name] = encrypted
+		}
+	}
+	for _, name := range []string{AWSAccessKeyIDEnv, AWSSecretAccessKeyEnv} {
+		if _, ok := requested[name]; !ok {
+			return AWSCredentials{}, fmt.Errorf("%s is not configured for %s", name, entryKey)
+		}
+	}
+
+	err := l.askCredentialPermission("aws", awsProfile, resolvedPath, permissiondialog.Request{
+		ApplicationPath: entryKey,
+		EnvNames:        sortedNames(requested),
+		Caller:          caller,
+		Profile:         profile,
+		Groups:          settings.Groups,
+	})
+	if err != nil {
+		return AWSCredentials{}, err
+	}
+
+	key, _ := l.Keychain.RetrieveEncryptionKey()
+	values := l.decryptEnvs(key, requested)
+	credentials := AWSCredentials{
+		Version:         1,
+		AccessKeyID:     values[AWSAccessKeyIDEnv],
+		SecretAccessKey: values[AWSSecretAccessKeyEnv],
+		SessionToken:    values[AWSSessionTokenEnv],
+	}
+	if expiration := values[AWSExpirationEnv]; expiration != "" {
+		parsed, err := time.Parse(time.RFC3339, expiration)
+		if err != nil {
+			return AWSCredentials{}, fmt.Errorf("%s is not an ISO 8601 time: %w", AWSExpirationEnv, err)
+		}
+		credentials.Expiration = parsed.UTC().Format(time.RFC3339)
+	}
+	return credentials, nil
+}
@@ -0,0 +1,85 @@
+package launcher
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/kfischer-okarin/with-secure-env/internal/permissiondialog"
+)
+
+func TestAWSCredentialProcess_ReturnsKeysAfterPermission(t *testing.T) {
+	launcher, _, editDialog, permDialog := newTestLauncher(t)
+	launcher.Init()
+	editDialog.returnOk = true
+	editDialog.returnValues = map[string]string{
+		"AWS_ACCESS_KEY_ID":         "AKIA",
+		"AWS_SECRET_ACCESS_KEY":     "secret",
+		"AWS_SESSION_TOKEN":         "session",
+		"AWS_CREDENTIAL_EXPIRATION": "2026-10-18T12:00:00+02:00",
+		"OTHER":                     "unused",
+	}
+	launcher.EditEnvs("aws:prod")
+
+	permDialog.returnGranted = true
+	credentials, err := launcher.AWSCredentialProcess("prod", permissiondialog.CallerInfo{Name: "aws", PID: 42})
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	expected := AWSCredentials{Version: 1, AccessKeyID: "AKIA", SecretAccessKey: "secret", SessionToken: "session", Expiration: "2026-10-18T10:00:00Z"}
+	if credentials != expected {
+		t.Errorf("expected %+v, got %+v", expected, credentials)
+	}
+	if permDialog.receivedCaller.Name != "aws" || permDialog.receivedRequest.Credential != "aws" || len(permDialog.receivedEnvNames) != 4 {
+		t.Errorf("expected aws credential request without OTHER, got %+v", permDialog.receivedRequest)
+	}
+	entries, _ := launcher.AuditEntries(AuditFilter{Action: "aws-credential"})
+	if len(entries) != 1 || entries[0].Result != "granted" || entries[0].App != "aws:prod" {
+		t.Errorf("expected granted audit entry, got %+v", entries)
+	}
+}
+
+func TestAWSCredentialProcess_UsesSharedGroup(t *testing.T) {
+	launcher, _, editDialog, permDialog := newTestLauncher(t)
+	launcher.Init()
+	editDialog.returnOk = true
+	editDialog.returnValues = map[string]string{"AWS_ACCESS_KEY_ID": "AKIA", "AWS_SECRET_ACCESS_KEY": "secret"}
+	launcher.EditGroup("aws")
+	launcher.Configure("aws:dev", "groups", "aws")
+
+	permDialog.returnGranted = true
+	credentials, err := launcher.AWSCredentialProcess("dev", permissiondialog.CallerInfo{})
+
+	if err != nil || credentials.AccessKeyID != "AKIA" || credentials.SessionToken != "" {
+		t.Errorf("expected keys from group, got %+v, %v", credentials, err)
+	}
+}
+
+func TestAWSCredentialProcess_FailsWithoutKeys(t *testing.T) {
+	launcher, _, _, permDialog := newTestLauncher(t)
+	launcher.Init()
+
+	_, err := launcher.AWSCredentialProcess("prod", permissiondialog.CallerInfo{})
+
+	if err == nil {
+		t.Error("expected error for unconfigured profile")
+	}
+	if permDialog.receivedAppPath != "" {
+		t.Error("expected no permission dialog")
+	}
+}
+
+func TestAWSCredentialProcess_ReturnsErrorWhenPermissionDenied(t *testing.T) {
+	launcher, _, editDialog, permDialog := newTestLauncher(t)
+	launcher.Init()
+	editDialog.returnOk = true
+	editDialog.returnValues = map[string]string{"AWS_ACCESS_KEY_ID": "AKIA", "AWS_SECRET_ACCESS_KEY": "secret"}
+	launcher.EditEnvs("aws:prod")
+
+	permDialog.returnGranted = false
+	_, err := launcher.AWSCredentialProcess("prod", permissiondialog.CallerInfo{})
+
+	if !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("expected permission denied, got %v", err)
+	}
+}
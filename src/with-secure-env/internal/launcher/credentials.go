@@ -0,0 +1,47 @@
+package launcher
+
+import (
+	"strings"
+
+	"github.com/kfischer-okarin/with-secure-env/internal/permissiondialog"
+)
+
+// credentialEntryPrefixes start the keys of credential helper entries, which
+// are not file paths.
+var credentialEntryPrefixes = []string{gitEntryPrefix, dockerEntryPrefix, awsEntryPrefix, k8sEntryPrefix}
+
+// IsCredentialEntry reports whether the key is a credential helper entry like
+// git:https://github.com/ rather than an application path.
+func IsCredentialEntry(entry string) bool {
+	for _, prefix := range credentialEntryPrefixes {
+		if strings.HasPrefix(entry, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// askCredentialPermission shows the permission dialog for a credential helper
+// get of target (URL, host or profile) and records the decision in the audit
+// log with action "<helper>-credential". request provides the entry key as
+// ApplicationPath, the env names, the caller and the profile.
+func (l *Launcher) askCredentialPermission(helper string, target string, resolvedPath string, request permissiondialog.Request) error {
+	request.Args = []string{"get", target}
+	request.Credential = helper
+	request.MatchedEntry = matchedPattern(resolvedPath)
+	if request.Profile == "" {
+		request.Profile = DefaultProfile
+	}
+	request.Production = isProductionProfile(request.Profile)
+
+	granted := l.PermissionDialog.AskPermission(request)
+	entry := AuditEntry{Action: helper + "-credential", Caller: callerChain(request.Caller), App: request.ApplicationPath, Entry: request.MatchedEntry, Profile: request.Profile, Args: []string{"get"}, EnvNames: request.EnvNames}
+	if !granted {
+		entry.Result = "denied"
+		l.audit(entry)
+		return ErrPermissionDenied
+	}
+	entry.Result = "granted"
+	l.audit(entry)
+	return nil
+}
@@ -0,0 +1,18 @@
+package launcher
+
+import "testing"
+
+func TestIsCredentialEntry_DistinguishesCredentialEntriesFromPaths(t *testing.T) {
+	for entry, expected := range map[string]bool{
+		"aws:prod":                true,
+		"git:https://github.com/": true,
+		"docker:ghcr.io":          true,
+		"k8s:prod":                true,
+		"/path/to/app":            false,
+		"aws":                     false,
+	} {
+		if IsCredentialEntry(entry) != expected {
+			t.Errorf("expected IsCredentialEntry(%q) to be %v", entry, expected)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package launcher
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// ErrNoMemoryDir is returned for file delivery on systems without a memory
+// backed tmpfs, e.g. macOS.
+var ErrNoMemoryDir = errors.New("file delivery needs a memory backed /dev/shm, which this system lacks (use fifo delivery instead)")
+
+// memoryDir is the memory backed tmpfs for delivered secrets.
+var memoryDir = "/dev/shm"
+
+// deliverSecrets turns the decrypted values into env entries according to the
+// delivery settings. Values delivered as files are written into a private
+// directory whose path is returned; it must be removed once the application
+// exited. The returned directory is empty if all values are plain envs.
+func (l *Launcher) deliverSecrets(values map[string]string, settings AppSettings) (env []string, secretsDir string, err error) {
+	needsMemory := false
+	for name := range values {
+		needsMemory = needsMemory || settings.deliveryFor(name) == DeliveryFile
+	}
+	for _, name := range sortedNames(values) {
+		delivery := settings.deliveryFor(name)
+		if delivery == DeliveryEnv {
+			env = append(env, name+"="+values[name])
+			continue
+		}
+
+		if secretsDir == "" {
+			base, err := l.secretsDirBase(needsMemory)
+			if err != nil {
+				return nil, "", err
+			}
+			secretsDir, err = os.MkdirTemp(base, "with-secure-env-*")
+			if err != nil {
+				return nil, "", err
+			}
+		}
+
+		path := filepath.Join(secretsDir, name)
+		if delivery == DeliveryFifo {
+			err = writeOneShotFifo(path, values[name])
+		} else {
+			err = os.WriteFile(path, []byte(values[name]), 0400)
+		}
+		if err != nil {
+			os.RemoveAll(secretsDir)
+			return nil, "", err
+		}
+		env = append(env, name+"_FILE="+path)
+	}
+	return env, secretsDir, nil
+}
+
+// secretsDirBase returns where the directory for delivered secrets is
+// created. Files need a memory backed tmpfs so the values never touch the
+// disk, fifos only pass them through the kernel and may live anywhere.
+func (l *Launcher) secretsDirBase(needsMemory bool) (string, error) {
+	if l.SecretsDirBase != "" {
+		return l.SecretsDirBase, nil
+	}
+	if info, err := os.Stat(memoryDir); err == nil && info.IsDir() {
+		return memoryDir, nil
+	}
+	if needsMemory {
+		return "", ErrNoMemoryDir
+	}
+	return os.TempDir(), nil
+}
+
+// writeOneShotFifo creates a named pipe that serves value to the first reader
+// and is removed afterwards.
+func writeOneShotFifo(path string, value string) error {
+	if err := syscall.Mkfifo(path, 0600); err != nil {
+		return err
+	}
+
+	go func() {
+		// Blocks until the application opens the pipe for reading
+		file, err := os.OpenFile(path, os.O_WRONLY, 0)
+		if err != nil {
+			return
+		}
+		os.Remove(path)
+		file.Write([]byte(value))
+		file.Close()
+	}()
+	return nil
+}
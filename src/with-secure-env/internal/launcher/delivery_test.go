@@ -0,0 +1,153 @@
+package launcher
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kfischer-okarin/with-secure-env/internal/permissiondialog"
+)
+
+func TestLaunch_DeliversSecretsAsFiles(t *testing.T) {
+	launcher, _, editDialog, permDialog := newTestLauncher(t)
+	launcher.Init()
+	editDialog.returnValues = map[string]string{"API_KEY": "secretkey", "DB_PASS": "secretpass"}
+	editDialog.returnOk = true
+	launcher.EditEnvs("/path/to/app")
+	launcher.Configure("/path/to/app", "delivery", "file")
+
+	var executedEnv []string
+	var fileContent string
+	var fileMode os.FileMode
+	var dirMode os.FileMode
+	launcher.Exec = func(process Process) (ExitStatus, error) {
+		executedEnv = process.Env
+		filePath := envValue(process.Env, "API_KEY_FILE")
+		data, _ := os.ReadFile(filePath)
+		fileContent = string(data)
+		fileInfo, _ := os.Stat(filePath)
+		fileMode = fileInfo.Mode().Perm()
+		dirInfo, _ := os.Stat(filepath.Dir(filePath))
+		dirMode = dirInfo.Mode().Perm()
+		return ExitStatus{Code: 3}, nil
+	}
+	permDialog.returnGranted = true
+	status, _ := launcher.Launch("/path/to/app", nil, permissiondialog.CallerInfo{})
+
+	if fileContent != "secretkey" {
+		t.Errorf("expected file to contain 'secretkey', got '%s'", fileContent)
+	}
+	if envValue(executedEnv, "API_KEY") != "" || envValue(executedEnv, "DB_PASS_FILE") == "" {
+		t.Errorf("expected only *_FILE envs, got %v", executedEnv)
+	}
+	if fileMode&0077 != 0 || dirMode != 0700 {
+		t.Errorf("expected private file and 0700 dir, got %v and %v", fileMode, dirMode)
+	}
+	if status.Code != 3 {
+		t.Errorf("expected exit code 3 of the application, got %d", status.Code)
+	}
+	if _, err := os.Stat(filepath.Dir(envValue(executedEnv, "API_KEY_FILE"))); !os.IsNotExist(err) {
+		t.Error("expected secrets directory to be removed after exit")
+	}
+}
+
+func TestLaunch_DeliversSingleVariableAsFile(t *testing.T) {
+	launcher, _, editDialog, permDialog := newTestLauncher(t)
+	launcher.Init()
+	editDialog.returnValues = map[string]string{"API_KEY": "secretkey", "DB_PASS": "secretpass"}
+	editDialog.returnOk = true
+	launcher.EditEnvs("/path/to/app")
+	launcher.Configure("/path/to/app", "delivery.DB_PASS", "file")
+
+	var executedEnv []string
+	launcher.Exec = func(process Process) (ExitStatus, error) {
+		executedEnv = process.Env
+		return ExitStatus{}, nil
+	}
+	permDialog.returnGranted = true
+	launcher.Launch("/path/to/app", nil, permissiondialog.CallerInfo{})
+
+	if !containsEnv(executedEnv, "API_KEY=secretkey") || envValue(executedEnv, "DB_PASS_FILE") == "" {
+		t.Errorf("expected API_KEY env and DB_PASS_FILE, got %v", executedEnv)
+	}
+}
+
+func TestLaunch_FifoCanBeReadOnlyOnce(t *testing.T) {
+	launcher, _, editDialog, permDialog := newTestLauncher(t)
+	launcher.Init()
+	editDialog.returnValues = map[string]string{"API_KEY": "secretkey"}
+	editDialog.returnOk = true
+	launcher.EditEnvs("/path/to/app")
+	launcher.Configure("/path/to/app", "delivery", "fifo")
+
+	var firstRead string
+	var secondReadErr error
+	launcher.Exec = func(process Process) (ExitStatus, error) {
+		filePath := envValue(process.Env, "API_KEY_FILE")
+		data, _ := os.ReadFile(filePath)
+		firstRead = string(data)
+		_, secondReadErr = os.Stat(filePath)
+		return ExitStatus{}, nil
+	}
+	permDialog.returnGranted = true
+	launcher.Launch("/path/to/app", nil, permissiondialog.CallerInfo{})
+
+	if firstRead != "secretkey" {
+		t.Errorf("expected first read to return 'secretkey', got '%s'", firstRead)
+	}
+	if !os.IsNotExist(secondReadErr) {
+		t.Errorf("expected fifo to be gone after first read, got %v", secondReadErr)
+	}
+}
+
+func TestLaunch_RefusesFileDeliveryWithoutMemoryDir(t *testing.T) {
+	launcher, _, editDialog, permDialog := newTestLauncher(t)
+	launcher.Init()
+	editDialog.returnValues = map[string]string{"API_KEY": "secretkey"}
+	editDialog.returnOk = true
+	launcher.EditEnvs("/path/to/app")
+	launcher.SecretsDirBase = ""
+	memoryDir = filepath.Join(t.TempDir(), "missing")
+	t.Cleanup(func() { memoryDir = "/dev/shm" })
+	executed := false
+	launcher.Exec = func(process Process) (ExitStatus, error) {
+		executed = true
+		return ExitStatus{}, nil
+	}
+	permDialog.returnGranted = true
+
+	launcher.Configure("/path/to/app", "delivery", "file")
+	_, err := launcher.Launch("/path/to/app", nil, permissiondialog.CallerInfo{})
+
+	if !errors.Is(err, ErrNoMemoryDir) || executed {
+		t.Errorf("expected ErrNoMemoryDir without launching, got %v", err)
+	}
+
+	launcher.Configure("/path/to/app", "delivery", "fifo")
+	_, err = launcher.Launch("/path/to/app", nil, permissiondialog.CallerInfo{})
+
+	if err != nil || !executed {
+		t.Errorf("expected fifo delivery to fall back to the temp dir, got %v", err)
+	}
+}
+
+func TestConfigure_RejectsUnknownDeliveryMode(t *testing.T) {
+	launcher, _, _, _ := newTestLauncher(t)
+
+	err := launcher.Configure("/path/to/app", "delivery", "carrier-pigeon")
+
+	if err == nil {
+		t.Error("expected error for unknown delivery mode")
+	}
+}
+
+func envValue(env []string, name string) string {
+	for _, e := range env {
+		if strings.HasPrefix(e, name+"=") {
+			return strings.TrimPrefix(e, name+"=")
+		}
+	}
+	return ""
+}
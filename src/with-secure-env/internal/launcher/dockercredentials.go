@@ -0,0 +1,100 @@
+package launcher
+
+import (
+	"strings"
+
+	"github.com/kfischer-okarin/with-secure-env/internal/dockercredential"
+	"github.com/kfischer-okarin/with-secure-env/internal/permissiondialog"
+)
+
+// Env names holding registry credentials in docker entries.
+const (
+	DockerUsernameEnv = "DOCKER_USERNAME"
+	DockerSecretEnv   = "DOCKER_SECRET"
+)
+
+const dockerEntryPrefix = "docker:"
+
+// DockerCredentialEntry returns the entry key of the credentials of a
+// registry: docker:<server URL>.
+func DockerCredentialEntry(serverURL string) string {
+	return dockerEntryPrefix + serverURL
+}
+
+// DockerCredentialGet asks for permission and returns the credentials of the
+// registry. It returns dockercredential.ErrNotFound if none are stored.
+func (l *Launcher) DockerCredentialGet(serverURL string, caller permissiondialog.CallerInfo) (dockercredential.Credentials, error) {
+	entryKey := DockerCredentialEntry(serverURL)
+	resolvedPath := l.resolveEntry(entryKey)
+	encryptedEnvs := l.loadProfileEnvs(resolvedPath, DefaultProfile)
+	if _, ok := encryptedEnvs[DockerSecretEnv]; !ok {
+		return dockercredential.Credentials{}, dockercredential.ErrNotFound
+	}
+
+	envNames := []string{DockerSecretEnv, DockerUsernameEnv}
+	if err := l.askCredentialPermission("docker", dockercredential.Host(serverURL), resolvedPath, permissiondialog.Request{ApplicationPath: entryKey, EnvNames: envNames, Caller: caller}); err != nil {
+		return dockercredential.Credentials{}, err
+	}
+
+	key, _ := l.Keychain.RetrieveEncryptionKey()
+	values := l.decryptEnvs(key, encryptedEnvs)
+	return dockercredential.Credentials{ServerURL: serverURL, Username: values[DockerUsernameEnv], Secret: values[DockerSecretEnv]}, nil
+}
+
+// DockerCredentialStore encrypts the credentials of a registry, which docker
+// sends after a successful login.
+func (l *Launcher) DockerCredentialStore(credentials dockercredential.Credentials) error {
+	entryKey := DockerCredentialEntry(credentials.ServerURL)
+	key, _ := l.Keychain.RetrieveEncryptionKey()
+	encryptedEnvs := map[string]string{}
+	for name, encrypted := range l.loadProfileEnvs(entryKey, DefaultProfile) {
+		encryptedEnvs[name] = encrypted
+	}
+	encryptedEnvs[DockerUsernameEnv] = l.encrypt(key, credentials.Username)
+	encryptedEnvs[DockerSecretEnv] = l.encrypt(key, credentials.Secret)
+	l.recordChanges(secretScope{Entry: entryKey, Profile: DefaultProfile}, []string{DockerSecretEnv, DockerUsernameEnv}, encryptedEnvs, nil)
+	l.saveProfileEnvs(entryKey, DefaultProfile, encryptedEnvs)
+
+	l.audit(AuditEntry{Action: "docker-credential", Result: "saved", Caller: callerChain(l.Caller), App: entryKey, Args: []string{"store"}, EnvNames: []string{DockerSecretEnv, DockerUsernameEnv}})
+	return nil
+}
+
+// DockerCredentialErase removes the credentials of a registry, which docker
+// requests on logout.
+func (l *Launcher) DockerCredentialErase(serverURL string) error {
+	entryKey := DockerCredentialEntry(serverURL)
+	encryptedEnvs := map[string]string{}
+	for name, encrypted := range l.loadProfileEnvs(entryKey, DefaultProfile) {
+		encryptedEnvs[name] = encrypted
+	}
+	if _, ok := encryptedEnvs[DockerSecretEnv]; !ok {
+		return dockercredential.ErrNotFound
+	}
+	delete(encryptedEnvs, DockerUsernameEnv)
+	delete(encryptedEnvs, DockerSecretEnv)
+	l.recordChanges(secretScope{Entry: entryKey, Profile: DefaultProfile}, nil, encryptedEnvs, nil)
+	l.saveProfileEnvs(entryKey, DefaultProfile, encryptedEnvs)
+
+	l.audit(AuditEntry{Action: "docker-credential", Result: "erased", Caller: callerChain(l.Caller), App: entryKey, Args: []string{"erase"}, EnvNames: []string{DockerSecretEnv, DockerUsernameEnv}})
+	return nil
+}
+
+// DockerCredentials returns the usernames by server URL of all registries
+// with stored credentials. Glob entries are left out since they have no single
+// server URL. Server URLs ending in "/" like Docker Hub's are directory
+// entries and listed.
+func (l *Launcher) DockerCredentials() map[string]string {
+	key, _ := l.Keychain.RetrieveEncryptionKey()
+	result := map[string]string{}
+	for entryKey, encryptedEnvs := range l.loadFileContent() {
+		serverURL, ok := strings.CutPrefix(entryKey, dockerEntryPrefix)
+		if !ok || entryKind(entryKey) == EntryGlob {
+			continue
+		}
+		if _, ok := encryptedEnvs[DockerSecretEnv]; !ok {
+			continue
+		}
+		result[serverURL] = l.decrypt(key, encryptedEnvs[DockerUsernameEnv])
+	}
+	return result
+}
@@ -0,0 +1,87 @@
+package launcher
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/kfischer-okarin/with-secure-env/internal/dockercredential"
+	"github.com/kfischer-okarin/with-secure-env/internal/permissiondialog"
+)
+
+func TestDockerCredentialStore_StoresEncryptedCredentials(t *testing.T) {
+	launcher, _, _, permDialog := newTestLauncher(t)
+	launcher.Init()
+
+	err := launcher.DockerCredentialStore(dockercredential.Credentials{ServerURL: "https://index.docker.io/v1/", Username: "octocat", Secret: "token"})
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	stored := launcher.loadFileContent()["docker:https://index.docker.io/v1/"]
+	if stored[DockerSecretEnv] == "" || stored[DockerSecretEnv] == "token" {
+		t.Errorf("expected encrypted secret, got %v", stored)
+	}
+
+	permDialog.returnGranted = true
+	credentials, err := launcher.DockerCredentialGet("https://index.docker.io/v1/", permissiondialog.CallerInfo{Name: "docker", PID: 42})
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if credentials != (dockercredential.Credentials{ServerURL: "https://index.docker.io/v1/", Username: "octocat", Secret: "token"}) {
+		t.Errorf("unexpected credentials %+v", credentials)
+	}
+	if permDialog.receivedRequest.Credential != "docker" || permDialog.receivedArgs[1] != "index.docker.io" {
+		t.Errorf("expected docker credential request for index.docker.io, got %+v", permDialog.receivedRequest)
+	}
+}
+
+func TestDockerCredentialGet_ReturnsNotFoundWithoutAsking(t *testing.T) {
+	launcher, _, _, permDialog := newTestLauncher(t)
+	launcher.Init()
+
+	_, err := launcher.DockerCredentialGet("ghcr.io", permissiondialog.CallerInfo{})
+
+	if !errors.Is(err, dockercredential.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if permDialog.receivedAppPath != "" {
+		t.Error("expected no permission dialog")
+	}
+}
+
+func TestDockerCredentialGet_ReturnsErrorWhenPermissionDenied(t *testing.T) {
+	launcher, kc, _, permDialog := newTestLauncher(t)
+	launcher.Init()
+	launcher.DockerCredentialStore(dockercredential.Credentials{ServerURL: "ghcr.io", Username: "octocat", Secret: "token"})
+
+	kc.retrieveCount = 0
+	permDialog.returnGranted = false
+	_, err := launcher.DockerCredentialGet("ghcr.io", permissiondialog.CallerInfo{})
+
+	if !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("expected permission denied, got %v", err)
+	}
+	if kc.retrieveCount != 0 {
+		t.Errorf("expected no keychain access, got %d", kc.retrieveCount)
+	}
+}
+
+func TestDockerCredentials_ListsUsernamesAndEraseRemoves(t *testing.T) {
+	launcher, _, _, _ := newTestLauncher(t)
+	launcher.Init()
+	launcher.DockerCredentialStore(dockercredential.Credentials{ServerURL: "https://index.docker.io/v1/", Username: "hub-user", Secret: "a"})
+	launcher.DockerCredentialStore(dockercredential.Credentials{ServerURL: "ghcr.io", Username: "octocat", Secret: "b"})
+
+	list := launcher.DockerCredentials()
+	if len(list) != 2 || list["https://index.docker.io/v1/"] != "hub-user" || list["ghcr.io"] != "octocat" {
+		t.Errorf("unexpected list %v", list)
+	}
+
+	if err := launcher.DockerCredentialErase("ghcr.io"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if list := launcher.DockerCredentials(); len(list) != 1 {
+		t.Errorf("expected ghcr.io to be erased, got %v", list)
+	}
+}
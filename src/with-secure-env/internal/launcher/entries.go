@@ -0,0 +1,175 @@
+package launcher
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Kinds of application entries.
+const (
+	// EntryExact is keyed by the application path itself.
+	EntryExact = "exact"
+	// EntryGlob is keyed by a filepath.Match pattern, e.g. /opt/tools/bin/*.
+	EntryGlob = "glob"
+	// EntryDirectory is keyed by a directory ending in "/" and matches every
+	// application below it.
+	EntryDirectory = "directory"
+)
+
+// EntryMatch is an entry whose key matches an application path.
+type EntryMatch struct {
+	Entry string
+	Kind  string
+	// Specificity is the number of literal characters in the key. The most
+	// specific match wins.
+	Specificity int
+}
+
+// Explanation describes how the entry used for an application path was
+// chosen.
+type Explanation struct {
+	Path string
+	// AliasOf is the alias target if Path is an alias.
+	AliasOf string
+	// Matches lists all matching entries, the selected one first.
+	Matches []EntryMatch
+}
+
+// Explain reports which entries match the application path and which one a
+// launch would use.
+func (l *Launcher) Explain(applicationPath string) Explanation {
+	explanation := Explanation{Path: applicationPath}
+	resolvedPath := l.resolveApplicationPath(applicationPath)
+	if resolvedPath != applicationPath {
+		explanation.AliasOf = resolvedPath
+	}
+	explanation.Matches = l.matchingEntries(resolvedPath)
+	return explanation
+}
+
+// resolveEntry returns the entry holding the envs and settings of the
+// application: the alias target, the exact path, or the most specific glob or
+// directory entry, in this order. Without any match it returns the path
+// itself.
+func (l *Launcher) resolveEntry(applicationPath string) string {
+	resolvedPath := l.resolveApplicationPath(applicationPath)
+	if matches := l.matchingEntries(resolvedPath); len(matches) > 0 {
+		return matches[0].Entry
+	}
+	return resolvedPath
+}
+
+func (l *Launcher) matchingEntries(path string) []EntryMatch {
+	var matches []EntryMatch
+	for _, entry := range l.entryKeys() {
+		kind := entryKind(entry)
+		if !entryMatches(entry, kind, path) {
+			continue
+		}
+		matches = append(matches, EntryMatch{Entry: entry, Kind: kind, Specificity: specificity(entry, kind)})
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if (a.Kind == EntryExact) != (b.Kind == EntryExact) {
+			return a.Kind == EntryExact
+		}
+		if a.Specificity != b.Specificity {
+			return a.Specificity > b.Specificity
+		}
+		if a.Kind != b.Kind {
+			return rankOf(a.Kind) < rankOf(b.Kind)
+		}
+		return a.Entry < b.Entry
+	})
+	return matches
+}
+
+// entryKeys returns all keys that have envs, profiles or groups. Keys with
+// other settings only do not count, so they cannot shadow the entry holding
+// the envs.
+func (l *Launcher) entryKeys() []string {
+	keys := map[string]bool{}
+	for key := range l.loadFileContent() {
+		keys[key] = true
+	}
+	for key := range l.loadProfiles() {
+		keys[key] = true
+	}
+	for key, settings := range l.loadSettings() {
+		if len(settings.Groups) > 0 {
+			keys[key] = true
+		}
+	}
+	return sortedNames(keys)
+}
+
+func entryKind(entry string) string {
+	switch {
+	case strings.HasSuffix(entry, "/"):
+		return EntryDirectory
+	case strings.ContainsAny(entry, "*?["):
+		return EntryGlob
+	}
+	return EntryExact
+}
+
+func entryMatches(entry string, kind string, path string) bool {
+	switch kind {
+	case EntryDirectory:
+		return strings.HasPrefix(path, entry)
+	case EntryGlob:
+		matched, _ := filepath.Match(entry, path)
+		return matched
+	}
+	return entry == path
+}
+
+func specificity(entry string, kind string) int {
+	if kind != EntryGlob {
+		return len(entry)
+	}
+	literal := 0
+	inClass := false
+	for _, c := range entry {
+		switch {
+		case c == '[':
+			inClass = true
+		case c == ']':
+			inClass = false
+		case !inClass && c != '*' && c != '?':
+			literal++
+		}
+	}
+	return literal
+}
+
+// rankOf orders kinds for equally specific matches.
+func rankOf(kind string) int {
+	switch kind {
+	case EntryExact:
+		return 0
+	case EntryGlob:
+		return 1
+	}
+	return 2
+}
+
+// matchedPattern returns the entry if it is a glob or directory entry, which
+// the permission dialog shows.
+func matchedPattern(entry string) string {
+	if entryKind(entry) == EntryExact {
+		return ""
+	}
+	return entry
+}
+
+func validateEntry(entry string) error {
+	if entryKind(entry) == EntryGlob {
+		if _, err := filepath.Match(entry, ""); err != nil {
+			return fmt.Errorf("invalid glob pattern %s: %w", entry, err)
+		}
+	}
+	return nil
+}
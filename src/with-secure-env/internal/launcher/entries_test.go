@@ -0,0 +1,131 @@
+package launcher
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLaunch_UsesGlobEntry(t *testing.T) {
+	launcher, _, editDialog, permDialog := newTestLauncher(t)
+	launcher.Init()
+	editDialog.returnOk = true
+	editDialog.returnValues = map[string]string{"API_KEY": "tools-secret"}
+	launcher.EditEnvs("/path/to/*")
+
+	env := launchAndCaptureEnv(launcher, permDialog)
+
+	if !containsEnv(env, "API_KEY=tools-secret") {
+		t.Errorf("expected API_KEY from glob entry, got %v", env)
+	}
+	if permDialog.receivedRequest.MatchedEntry != "/path/to/*" {
+		t.Errorf("expected matched entry '/path/to/*', got '%s'", permDialog.receivedRequest.MatchedEntry)
+	}
+	entries, _ := launcher.AuditEntries(AuditFilter{Action: "launch"})
+	if len(entries) != 1 || entries[0].App != "/path/to/app" || entries[0].Entry != "/path/to/*" {
+		t.Errorf("expected launch audit entry with matched entry, got %+v", entries)
+	}
+}
+
+func TestLaunch_UsesDirectoryEntryForNestedApplications(t *testing.T) {
+	launcher, _, editDialog, permDialog := newTestLauncher(t)
+	launcher.Init()
+	editDialog.returnOk = true
+	editDialog.returnValues = map[string]string{"API_KEY": "dir-secret"}
+	launcher.EditEnvs("/path/")
+
+	env := launchAndCaptureEnv(launcher, permDialog)
+
+	if !containsEnv(env, "API_KEY=dir-secret") {
+		t.Errorf("expected API_KEY from directory entry, got %v", env)
+	}
+}
+
+func TestLaunch_MostSpecificEntryWins(t *testing.T) {
+	launcher, _, editDialog, permDialog := newTestLauncher(t)
+	launcher.Init()
+	editDialog.returnOk = true
+	editDialog.returnValues = map[string]string{"API_KEY": "dir"}
+	launcher.EditEnvs("/path/")
+	editDialog.returnValues = map[string]string{"API_KEY": "glob"}
+	launcher.EditEnvs("/path/to/a*")
+	editDialog.returnValues = map[string]string{"API_KEY": "other"}
+	launcher.EditEnvs("/path/to/b*")
+
+	env := launchAndCaptureEnv(launcher, permDialog)
+	if !containsEnv(env, "API_KEY=glob") {
+		t.Errorf("expected glob entry to win over directory entry, got %v", env)
+	}
+
+	editDialog.returnValues = map[string]string{"API_KEY": "exact"}
+	launcher.EditEnvs("/path/to/app")
+	env = launchAndCaptureEnv(launcher, permDialog)
+	if !containsEnv(env, "API_KEY=exact") {
+		t.Errorf("expected exact entry to win, got %v", env)
+	}
+	if permDialog.receivedRequest.MatchedEntry != "" {
+		t.Errorf("expected no matched pattern for exact entry, got '%s'", permDialog.receivedRequest.MatchedEntry)
+	}
+}
+
+func TestLaunch_SettingsOnlyKeyDoesNotShadowEntryWithEnvs(t *testing.T) {
+	launcher, _, editDialog, permDialog := newTestLauncher(t)
+	launcher.Init()
+	editDialog.returnOk = true
+	editDialog.returnValues = map[string]string{"API_KEY": "tools-secret"}
+	launcher.EditEnvs("/path/to/*")
+	launcher.saveSettings(map[string]AppSettings{"/path/to/app": {LaunchMode: LaunchModeSupervised}})
+
+	env := launchAndCaptureEnv(launcher, permDialog)
+
+	if !containsEnv(env, "API_KEY=tools-secret") {
+		t.Errorf("expected API_KEY from glob entry, got %v", env)
+	}
+	if err := launcher.Configure("/path/to/app", "launchMode", LaunchModeExec); err == nil || !strings.Contains(err.Error(), "/path/to/*") {
+		t.Errorf("expected error pointing to the glob entry, got %v", err)
+	}
+}
+
+func TestExplain_ListsMatchingEntriesInPrecedenceOrder(t *testing.T) {
+	launcher, _, editDialog, _ := newTestLauncher(t)
+	launcher.Init()
+	editDialog.returnOk = true
+	editDialog.returnValues = map[string]string{"API_KEY": "secret"}
+	launcher.EditEnvs("/path/")
+	launcher.EditEnvs("/path/to/*")
+	launcher.EditEnvs("/other/")
+	launcher.EditEnvs("/path/to/app")
+	launcher.Alias("/usr/local/bin/app", "/path/to/app")
+
+	explanation := launcher.Explain("/usr/local/bin/app")
+
+	if explanation.AliasOf != "/path/to/app" {
+		t.Errorf("expected alias of '/path/to/app', got '%s'", explanation.AliasOf)
+	}
+	matches := explanation.Matches
+	if len(matches) != 3 {
+		t.Fatalf("expected 3 matches, got %+v", matches)
+	}
+	if matches[0].Entry != "/path/to/app" || matches[0].Kind != EntryExact {
+		t.Errorf("expected exact entry first, got %+v", matches[0])
+	}
+	if matches[1].Entry != "/path/to/*" || matches[1].Kind != EntryGlob {
+		t.Errorf("expected glob entry second, got %+v", matches[1])
+	}
+	if matches[2].Entry != "/path/" || matches[2].Kind != EntryDirectory {
+		t.Errorf("expected directory entry last, got %+v", matches[2])
+	}
+}
+
+func TestEditEnvs_RejectsInvalidGlob(t *testing.T) {
+	launcher, _, editDialog, _ := newTestLauncher(t)
+	launcher.Init()
+
+	err := launcher.EditProfile("/path/to/[app", "")
+
+	if err == nil {
+		t.Error("expected error for invalid glob")
+	}
+	if editDialog.receivedAppPath != "" {
+		t.Error("expected no edit dialog for invalid glob")
+	}
+}
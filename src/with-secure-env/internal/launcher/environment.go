@@ -0,0 +1,118 @@
+package launcher
+
+import (
+	"os"
+	"strings"
+)
+
+// DefaultInheritEnv is the allowlist of inherited variables for applications
+// launched with a clean environment. A trailing * matches any suffix.
+var DefaultInheritEnv = []string{"PATH", "HOME", "USER", "LOGNAME", "SHELL", "TERM", "TMPDIR", "LANG", "LC_*"}
+
+// riskyEnvNames are inherited variables that change how programs behave
+// beyond their configuration, e.g. by loading additional code.
+var riskyEnvNames = []string{
+	"LD_PRELOAD", "LD_LIBRARY_PATH", "DYLD_INSERT_LIBRARIES", "DYLD_LIBRARY_PATH",
+	"NODE_OPTIONS", "PYTHONPATH", "PYTHONSTARTUP", "RUBYOPT", "PERL5OPT",
+	"BASH_ENV", "ENV", "GIT_SSH_COMMAND",
+}
+
+// inheritedEnv returns the variables of with-secure-env's own environment that
+// are passed on to the application.
+func (l *Launcher) inheritedEnv(settings AppSettings) map[string]string {
+	allowlist := settings.InheritEnv
+	if len(allowlist) == 0 {
+		allowlist = DefaultInheritEnv
+	}
+
+	inherited := map[string]string{}
+	for _, entry := range l.environ() {
+		name, value, ok := strings.Cut(entry, "=")
+		if !ok || name == "" {
+			continue
+		}
+		if settings.CleanEnv && !matchesAny(name, allowlist) {
+			continue
+		}
+		if _, exists := inherited[name]; !exists {
+			inherited[name] = value
+		}
+	}
+	return inherited
+}
+
+func (l *Launcher) environ() []string {
+	if l.Environ != nil {
+		return l.Environ()
+	}
+	return os.Environ()
+}
+
+// getenv returns the value of a variable in with-secure-env's own
+// environment.
+func (l *Launcher) getenv(name string) string {
+	for _, entry := range l.environ() {
+		if entryName, value, ok := strings.Cut(entry, "="); ok && entryName == name {
+			return value
+		}
+	}
+	return ""
+}
+
+// buildEnv merges the inherited variables with the injected ones. Injected
+// variables always win; inherited duplicates are dropped so the application
+// can't pick the wrong one.
+func buildEnv(inherited map[string]string, injected []string) []string {
+	injectedNames := map[string]bool{}
+	for _, entry := range injected {
+		name, _, _ := strings.Cut(entry, "=")
+		injectedNames[name] = true
+	}
+
+	env := make([]string, 0, len(inherited)+len(injected))
+	for _, name := range sortedNames(inherited) {
+		if !injectedNames[name] {
+			env = append(env, name+"="+inherited[name])
+		}
+	}
+	return append(env, injected...)
+}
+
+// environmentWarnings describes how the inherited variables interfere with the
+// injected secrets.
+func environmentWarnings(inherited map[string]string, envNames []string, settings AppSettings) []string {
+	var warnings []string
+	for _, name := range envNames {
+		delivery := settings.deliveryFor(name)
+		if _, ok := inherited[name]; ok {
+			if delivery == DeliveryEnv {
+				warnings = append(warnings, name+" is already set by the caller and will be overridden by the stored secret")
+			} else {
+				warnings = append(warnings, name+" is set by the caller and might take precedence over the stored secret in "+name+"_FILE")
+			}
+		}
+		if _, ok := inherited[name+"_FILE"]; ok && delivery != DeliveryEnv {
+			warnings = append(warnings, name+"_FILE is already set by the caller and will be overridden by the stored secret")
+		}
+	}
+
+	for _, name := range riskyEnvNames {
+		if _, ok := inherited[name]; ok {
+			warnings = append(warnings, name+" is inherited from the caller and can change the behavior of the application")
+		}
+	}
+	return warnings
+}
+
+func matchesAny(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		} else if name == pattern {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,124 @@
+package launcher
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kfischer-okarin/with-secure-env/internal/permissiondialog"
+)
+
+func TestLaunch_InheritsCallerEnvironment(t *testing.T) {
+	launcher, _, editDialog, permDialog := newTestLauncher(t)
+	launcher.Init()
+	editDialog.returnValues = map[string]string{"API_KEY": "secretkey"}
+	editDialog.returnOk = true
+	launcher.EditEnvs("/path/to/app")
+	launcher.Environ = func() []string { return []string{"PATH=/bin", "CUSTOM=value"} }
+
+	executedEnv := launchAndCaptureEnv(launcher, permDialog)
+
+	if !containsEnv(executedEnv, "PATH=/bin") || !containsEnv(executedEnv, "CUSTOM=value") || !containsEnv(executedEnv, "API_KEY=secretkey") {
+		t.Errorf("expected inherited and injected envs, got %v", executedEnv)
+	}
+}
+
+func TestLaunch_InjectedSecretReplacesInheritedVariable(t *testing.T) {
+	launcher, _, editDialog, permDialog := newTestLauncher(t)
+	launcher.Init()
+	editDialog.returnValues = map[string]string{"API_KEY": "secretkey"}
+	editDialog.returnOk = true
+	launcher.EditEnvs("/path/to/app")
+	launcher.Environ = func() []string { return []string{"API_KEY=fromcaller"} }
+
+	executedEnv := launchAndCaptureEnv(launcher, permDialog)
+
+	if containsEnv(executedEnv, "API_KEY=fromcaller") || !containsEnv(executedEnv, "API_KEY=secretkey") {
+		t.Errorf("expected only the stored API_KEY, got %v", executedEnv)
+	}
+	if !containsWarning(permDialog.receivedRequest.Warnings, "API_KEY is already set") {
+		t.Errorf("expected shadowing warning, got %v", permDialog.receivedRequest.Warnings)
+	}
+}
+
+func TestLaunch_WarnsWhenInheritedVariableMightShadowSecretFile(t *testing.T) {
+	launcher, _, editDialog, permDialog := newTestLauncher(t)
+	launcher.Init()
+	editDialog.returnValues = map[string]string{"API_KEY": "secretkey"}
+	editDialog.returnOk = true
+	launcher.EditEnvs("/path/to/app")
+	launcher.Configure("/path/to/app", "delivery", "file")
+	launcher.Environ = func() []string { return []string{"API_KEY=fromcaller"} }
+
+	permDialog.returnGranted = false
+	launcher.Launch("/path/to/app", nil, permissiondialog.CallerInfo{})
+
+	if !containsWarning(permDialog.receivedRequest.Warnings, "might take precedence") {
+		t.Errorf("expected warning about API_KEY shadowing API_KEY_FILE, got %v", permDialog.receivedRequest.Warnings)
+	}
+}
+
+func TestLaunch_CleanEnvOnlyInheritsAllowlist(t *testing.T) {
+	launcher, _, editDialog, permDialog := newTestLauncher(t)
+	launcher.Init()
+	editDialog.returnValues = map[string]string{"API_KEY": "secretkey"}
+	editDialog.returnOk = true
+	launcher.EditEnvs("/path/to/app")
+	launcher.Configure("/path/to/app", "cleanEnv", "true")
+	launcher.Environ = func() []string {
+		return []string{"PATH=/bin", "LC_ALL=C", "LD_PRELOAD=/tmp/evil.so", "NODE_OPTIONS=--require evil"}
+	}
+
+	executedEnv := launchAndCaptureEnv(launcher, permDialog)
+
+	expected := []string{"LC_ALL=C", "PATH=/bin", "API_KEY=secretkey"}
+	if strings.Join(executedEnv, " ") != strings.Join(expected, " ") {
+		t.Errorf("expected %v, got %v", expected, executedEnv)
+	}
+}
+
+func TestLaunch_CleanEnvUsesConfiguredAllowlist(t *testing.T) {
+	launcher, _, _, permDialog := newTestLauncher(t)
+	launcher.Init()
+	launcher.Configure("/path/to/app", "cleanEnv", "true")
+	launcher.Configure("/path/to/app", "inheritEnv", "PATH, EDITOR")
+	launcher.Environ = func() []string { return []string{"PATH=/bin", "HOME=/home/user", "EDITOR=vim"} }
+
+	executedEnv := launchAndCaptureEnv(launcher, permDialog)
+
+	if strings.Join(executedEnv, " ") != "EDITOR=vim PATH=/bin" {
+		t.Errorf("expected only PATH and EDITOR, got %v", executedEnv)
+	}
+}
+
+func TestLaunch_WarnsAboutRiskyInheritedVariables(t *testing.T) {
+	launcher, _, _, permDialog := newTestLauncher(t)
+	launcher.Init()
+	launcher.Environ = func() []string { return []string{"LD_PRELOAD=/tmp/evil.so"} }
+
+	permDialog.returnGranted = false
+	launcher.Launch("/path/to/app", nil, permissiondialog.CallerInfo{})
+
+	if !containsWarning(permDialog.receivedRequest.Warnings, "LD_PRELOAD") {
+		t.Errorf("expected warning about LD_PRELOAD, got %v", permDialog.receivedRequest.Warnings)
+	}
+}
+
+func launchAndCaptureEnv(launcher *Launcher, permDialog *stubPermissionDialog) []string {
+	var executedEnv []string
+	launcher.Exec = func(process Process) (ExitStatus, error) {
+		executedEnv = process.Env
+		return ExitStatus{}, nil
+	}
+	permDialog.returnGranted = true
+	launcher.Launch("/path/to/app", nil, permissiondialog.CallerInfo{})
+	return executedEnv
+}
+
+func containsWarning(warnings []string, needle string) bool {
+	for _, warning := range warnings {
+		if strings.Contains(warning, needle) {
+			return true
+		}
+	}
+	return false
+}
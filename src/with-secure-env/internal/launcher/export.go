@@ -0,0 +1,76 @@
+package launcher
+
+import (
+	"path/filepath"
+
+	"github.com/kfischer-okarin/with-secure-env/internal/envfile"
+	"github.com/kfischer-okarin/with-secure-env/internal/permissiondialog"
+)
+
+// ExportOptions describe a plaintext export.
+type ExportOptions struct {
+	// Format is one of envfile.ExportFormats.
+	Format string
+	// Profile selects the profile instead of the application's default
+	// profile.
+	Profile string
+	// Name is the name of a k8s-secret. Defaults to the application's file
+	// name.
+	Name string
+	// Destination is where the output goes (file path or "stdout"), recorded
+	// in the audit log.
+	Destination string
+}
+
+// Export asks for permission like a launch and returns the envs of the
+// application rendered in the requested format.
+func (l *Launcher) Export(applicationPath string, caller permissiondialog.CallerInfo, options ExportOptions) ([]byte, error) {
+	if err := envfile.ValidateExportFormat(options.Format); err != nil {
+		return nil, err
+	}
+	resolvedPath := l.resolveEntry(applicationPath)
+	settings := l.loadSettings()[resolvedPath]
+	profile := profileFor(settings, options.Profile)
+	if err := validateName("profile", profile); err != nil {
+		return nil, err
+	}
+	appEnvs := l.loadProfileEnvs(resolvedPath, profile)
+	encryptedEnvs := l.mergeGroupEnvs(settings, appEnvs)
+	envNames := sortedNames(encryptedEnvs)
+	entry := AuditEntry{Action: "export", Caller: callerChain(caller), App: applicationPath, Entry: matchedPattern(resolvedPath), Profile: profile, Args: []string{options.Format, options.Destination}, EnvNames: envNames}
+	expired, err := l.checkEnvs(resolvedPath, profile, settings, appEnvs, envNames, entry)
+	if err != nil {
+		return nil, err
+	}
+
+	granted := l.PermissionDialog.AskPermission(permissiondialog.Request{
+		ApplicationPath: applicationPath,
+		EnvNames:        envNames,
+		Caller:          caller,
+		Warnings:        expired,
+		Profile:         profile,
+		Production:      isProductionProfile(profile),
+		Groups:          settings.Groups,
+		MatchedEntry:    matchedPattern(resolvedPath),
+		Export:          options.Format,
+		Derived:         derivedNames(encryptedEnvs),
+	})
+	if !granted {
+		entry.Result = "denied"
+		l.audit(entry)
+		return nil, ErrPermissionDenied
+	}
+	entry.Result = "granted"
+	l.audit(entry)
+
+	key, _ := l.Keychain.RetrieveEncryptionKey()
+	name := options.Name
+	if name == "" {
+		name = filepath.Base(applicationPath)
+	}
+	values, err := l.resolveTemplates(key, l.entryScope(key, settings, encryptedEnvs), envNames)
+	if err != nil {
+		return nil, err
+	}
+	return envfile.Render(options.Format, values, name)
+}
@@ -0,0 +1,86 @@
+package launcher
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/kfischer-okarin/with-secure-env/internal/permissiondialog"
+)
+
+func TestExport_RendersEnvsAfterPermission(t *testing.T) {
+	launcher, _, editDialog, permDialog := newTestLauncher(t)
+	launcher.Init()
+	editDialog.returnOk = true
+	editDialog.returnValues = map[string]string{"API_KEY": "secret"}
+	launcher.EditEnvs("/path/to/app")
+
+	permDialog.returnGranted = true
+	data, err := launcher.Export("/path/to/app", permissiondialog.CallerInfo{Name: "sh", PID: 1}, ExportOptions{Format: "shell", Destination: "stdout"})
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if string(data) != "export API_KEY='secret'\n" {
+		t.Errorf("unexpected output %q", data)
+	}
+	if permDialog.receivedRequest.Export != "shell" {
+		t.Errorf("expected export format in permission request, got '%s'", permDialog.receivedRequest.Export)
+	}
+	entries, _ := launcher.AuditEntries(AuditFilter{Action: "export"})
+	if len(entries) != 1 || entries[0].Result != "granted" || entries[0].Args[1] != "stdout" {
+		t.Errorf("expected granted export audit entry, got %+v", entries)
+	}
+}
+
+func TestExport_DoesNotAccessKeychainIfPermissionDenied(t *testing.T) {
+	launcher, kc, editDialog, permDialog := newTestLauncher(t)
+	launcher.Init()
+	editDialog.returnOk = true
+	editDialog.returnValues = map[string]string{"API_KEY": "secret"}
+	launcher.EditEnvs("/path/to/app")
+
+	kc.retrieveCount = 0
+	permDialog.returnGranted = false
+	data, err := launcher.Export("/path/to/app", permissiondialog.CallerInfo{}, ExportOptions{Format: "dotenv"})
+
+	if !errors.Is(err, ErrPermissionDenied) || data != nil {
+		t.Errorf("expected permission denied, got %v, %q", err, data)
+	}
+	if kc.retrieveCount != 0 {
+		t.Errorf("expected no keychain access, got %d", kc.retrieveCount)
+	}
+	entries, _ := launcher.AuditEntries(AuditFilter{Action: "export"})
+	if len(entries) != 1 || entries[0].Result != "denied" {
+		t.Errorf("expected denied export audit entry, got %+v", entries)
+	}
+}
+
+func TestExport_RejectsUnknownFormatWithoutAsking(t *testing.T) {
+	launcher, _, _, permDialog := newTestLauncher(t)
+	launcher.Init()
+
+	_, err := launcher.Export("/path/to/app", permissiondialog.CallerInfo{}, ExportOptions{Format: "xml"})
+
+	if err == nil {
+		t.Error("expected error for unknown format")
+	}
+	if permDialog.receivedAppPath != "" {
+		t.Error("expected no permission dialog for unknown format")
+	}
+}
+
+func TestExport_NamesK8sSecretAfterApplication(t *testing.T) {
+	launcher, _, editDialog, permDialog := newTestLauncher(t)
+	launcher.Init()
+	editDialog.returnOk = true
+	editDialog.returnValues = map[string]string{"API_KEY": "secret"}
+	launcher.EditEnvs("/path/to/my_app")
+
+	permDialog.returnGranted = true
+	data, _ := launcher.Export("/path/to/my_app", permissiondialog.CallerInfo{}, ExportOptions{Format: "k8s-secret"})
+
+	if !strings.Contains(string(data), "\n  name: my-app\n") {
+		t.Errorf("expected secret named my-app, got %s", data)
+	}
+}
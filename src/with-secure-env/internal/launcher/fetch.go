@@ -0,0 +1,54 @@
+package launcher
+
+import (
+	"fmt"
+
+	"github.com/kfischer-okarin/with-secure-env/internal/permissiondialog"
+)
+
+// Fetch asks for permission like a launch and returns the values of the
+// requested envs of the application, for applications that request their
+// secrets at runtime instead of reading the environment.
+func (l *Launcher) Fetch(applicationPath string, envNames []string, caller permissiondialog.CallerInfo) (map[string]string, error) {
+	resolvedPath := l.resolveEntry(applicationPath)
+	settings := l.loadSettings()[resolvedPath]
+	profile := profileFor(settings, "")
+	appEnvs := l.loadProfileEnvs(resolvedPath, profile)
+	encryptedEnvs := l.mergeGroupEnvs(settings, appEnvs)
+
+	requested := map[string]string{}
+	for _, name := range envNames {
+		encrypted, ok := encryptedEnvs[name]
+		if !ok {
+			return nil, fmt.Errorf("%s is not configured for %s", name, applicationPath)
+		}
+		requested[name] = encrypted
+	}
+	entry := AuditEntry{Action: "fetch", Caller: callerChain(caller), App: applicationPath, Entry: matchedPattern(resolvedPath), Profile: profile, EnvNames: sortedNames(requested)}
+	expired, err := l.checkEnvs(resolvedPath, profile, settings, appEnvs, sortedNames(requested), entry)
+	if err != nil {
+		return nil, err
+	}
+
+	granted := l.PermissionDialog.AskPermission(permissiondialog.Request{
+		ApplicationPath: applicationPath,
+		EnvNames:        sortedNames(requested),
+		Caller:          caller,
+		Warnings:        expired,
+		Profile:         profile,
+		Production:      isProductionProfile(profile),
+		Groups:          settings.Groups,
+		MatchedEntry:    matchedPattern(resolvedPath),
+		Derived:         derivedNames(requested),
+	})
+	if !granted {
+		entry.Result = "denied"
+		l.audit(entry)
+		return nil, ErrPermissionDenied
+	}
+	entry.Result = "granted"
+	l.audit(entry)
+
+	key, _ := l.Keychain.RetrieveEncryptionKey()
+	return l.resolveTemplates(key, l.entryScope(key, settings, encryptedEnvs), sortedNames(requested))
+}
@@ -0,0 +1,69 @@
+package launcher
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/kfischer-okarin/with-secure-env/internal/permissiondialog"
+)
+
+func TestFetch_ReturnsOnlyRequestedValuesAfterPermission(t *testing.T) {
+	launcher, _, editDialog, permDialog := newTestLauncher(t)
+	launcher.Init()
+	editDialog.returnOk = true
+	editDialog.returnValues = map[string]string{"API_KEY": "secret", "DB_PASS": "pass"}
+	launcher.EditEnvs("/path/to/app")
+
+	permDialog.returnGranted = true
+	values, err := launcher.Fetch("/path/to/app", []string{"API_KEY"}, permissiondialog.CallerInfo{Name: "service", PID: 42})
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(values) != 1 || values["API_KEY"] != "secret" {
+		t.Errorf("expected only API_KEY, got %v", values)
+	}
+	if len(permDialog.receivedEnvNames) != 1 || permDialog.receivedEnvNames[0] != "API_KEY" {
+		t.Errorf("expected dialog to show only API_KEY, got %v", permDialog.receivedEnvNames)
+	}
+	entries, _ := launcher.AuditEntries(AuditFilter{Action: "fetch"})
+	if len(entries) != 1 || entries[0].Result != "granted" || entries[0].Caller[0] != "service[42]" {
+		t.Errorf("expected granted fetch audit entry, got %+v", entries)
+	}
+}
+
+func TestFetch_FailsForUnknownNameWithoutAsking(t *testing.T) {
+	launcher, _, editDialog, permDialog := newTestLauncher(t)
+	launcher.Init()
+	editDialog.returnOk = true
+	editDialog.returnValues = map[string]string{"API_KEY": "secret"}
+	launcher.EditEnvs("/path/to/app")
+
+	_, err := launcher.Fetch("/path/to/app", []string{"MISSING"}, permissiondialog.CallerInfo{})
+
+	if err == nil {
+		t.Error("expected error for unknown env name")
+	}
+	if permDialog.receivedAppPath != "" {
+		t.Error("expected no permission dialog")
+	}
+}
+
+func TestFetch_ReturnsErrorWhenPermissionDenied(t *testing.T) {
+	launcher, kc, editDialog, permDialog := newTestLauncher(t)
+	launcher.Init()
+	editDialog.returnOk = true
+	editDialog.returnValues = map[string]string{"API_KEY": "secret"}
+	launcher.EditEnvs("/path/to/app")
+
+	kc.retrieveCount = 0
+	permDialog.returnGranted = false
+	values, err := launcher.Fetch("/path/to/app", []string{"API_KEY"}, permissiondialog.CallerInfo{})
+
+	if !errors.Is(err, ErrPermissionDenied) || values != nil {
+		t.Errorf("expected permission denied, got %v, %v", values, err)
+	}
+	if kc.retrieveCount != 0 {
+		t.Errorf("expected no keychain access, got %d", kc.retrieveCount)
+	}
+}
@@ -0,0 +1,61 @@
+package launcher
+
+import (
+	"fmt"
+
+	"github.com/kfischer-okarin/with-secure-env/internal/envschema"
+	"github.com/kfischer-okarin/with-secure-env/internal/secretgen"
+)
+
+// GenerateTarget selects where generated values are stored: a profile of an
+// application or a shared group.
+type GenerateTarget struct {
+	ApplicationPath string
+	// Profile selects the profile instead of the application's default
+	// profile.
+	Profile string
+	// Group is set instead of ApplicationPath to store into a shared group.
+	Group string
+	// Replace allows overwriting existing envs.
+	Replace bool
+}
+
+// Generate generates a random secret named name and stores it encrypted, so
+// the value never passes through the clipboard or a shell. It returns the
+// generated values, of which only public keys may be shown.
+func (l *Launcher) Generate(target GenerateTarget, name string, options secretgen.Options) (map[string]string, error) {
+	if err := envschema.ValidateName(name); err != nil {
+		return nil, err
+	}
+	values, err := secretgen.Generate(name, options)
+	if err != nil {
+		return nil, err
+	}
+
+	scope, err := l.targetScope(target.ApplicationPath, target.Profile, target.Group)
+	if err != nil {
+		return nil, err
+	}
+	existing := l.scopeEnvs(scope)
+	if !target.Replace {
+		for generatedName := range values {
+			if _, ok := existing[generatedName]; ok {
+				return nil, fmt.Errorf("%s already exists (replace it explicitly)", generatedName)
+			}
+		}
+	}
+
+	key, _ := l.Keychain.RetrieveEncryptionKey()
+	encryptedEnvs := map[string]string{}
+	for envName, encrypted := range existing {
+		encryptedEnvs[envName] = encrypted
+	}
+	for envName, value := range values {
+		encryptedEnvs[envName] = l.encrypt(key, value)
+	}
+	l.recordChanges(scope, sortedNames(values), encryptedEnvs, nil)
+	l.saveScopeEnvs(scope, encryptedEnvs)
+
+	l.audit(AuditEntry{Action: "generate", Result: "saved", Caller: callerChain(l.Caller), App: scope.Entry, Profile: scope.Profile, Group: scope.Group, Args: []string{options.Kind}, EnvNames: sortedNames(values)})
+	return values, nil
+}
@@ -0,0 +1,75 @@
+package launcher
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kfischer-okarin/with-secure-env/internal/secretgen"
+)
+
+func TestGenerate_StoresEncryptedValue(t *testing.T) {
+	launcher, kc, _, _ := newTestLauncher(t)
+	launcher.Init()
+
+	values, err := launcher.Generate(GenerateTarget{ApplicationPath: "/path/to/app"}, "DB_PASSWORD", secretgen.Options{Length: 24})
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	stored := launcher.loadFileContent()["/path/to/app"]["DB_PASSWORD"]
+	if len(values["DB_PASSWORD"]) != 24 || decrypt(t, kc.storedKey, stored) != values["DB_PASSWORD"] {
+		t.Errorf("expected generated value to be stored encrypted, got %q", stored)
+	}
+	entries, _ := launcher.AuditEntries(AuditFilter{Action: "generate"})
+	if len(entries) != 1 || entries[0].EnvNames[0] != "DB_PASSWORD" {
+		t.Errorf("expected generate audit entry, got %+v", entries)
+	}
+}
+
+func TestGenerate_StoresKeyPairInGroup(t *testing.T) {
+	launcher, _, _, _ := newTestLauncher(t)
+	launcher.Init()
+
+	_, err := launcher.Generate(GenerateTarget{Group: "signing"}, "SIGNING_KEY", secretgen.Options{Kind: secretgen.KindEd25519})
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	groups := launcher.Groups()
+	if len(groups) != 1 || strings.Join(groups[0].EnvNames, ",") != "SIGNING_KEY,SIGNING_KEY_PUBLIC" {
+		t.Errorf("expected key pair in group, got %+v", groups)
+	}
+}
+
+func TestGenerate_RefusesToReplaceExistingValue(t *testing.T) {
+	launcher, _, editDialog, _ := newTestLauncher(t)
+	launcher.Init()
+	editDialog.returnOk = true
+	editDialog.returnValues = map[string]string{"DB_PASSWORD": "old"}
+	launcher.EditEnvs("/path/to/app")
+	target := GenerateTarget{ApplicationPath: "/path/to/app"}
+
+	if _, err := launcher.Generate(target, "DB_PASSWORD", secretgen.Options{}); err == nil {
+		t.Error("expected error for existing value")
+	}
+
+	target.Replace = true
+	if _, err := launcher.Generate(target, "DB_PASSWORD", secretgen.Options{}); err != nil {
+		t.Errorf("expected replace to succeed, got %v", err)
+	}
+}
+
+func TestEditEnvs_OffersGenerator(t *testing.T) {
+	launcher, _, editDialog, _ := newTestLauncher(t)
+	launcher.Init()
+
+	launcher.EditEnvs("/path/to/app")
+
+	if editDialog.receivedRequest.Generate == nil {
+		t.Fatal("expected edit dialog to get a generator")
+	}
+	values, err := editDialog.receivedRequest.Generate("ID", secretgen.Options{Kind: secretgen.KindUUID})
+	if err != nil || len(values["ID"]) != 36 {
+		t.Errorf("expected UUID, got %v, %v", values, err)
+	}
+}
@@ -0,0 +1,111 @@
+package launcher
+
+import (
+	"strings"
+
+	"github.com/kfischer-okarin/with-secure-env/internal/gitcredential"
+	"github.com/kfischer-okarin/with-secure-env/internal/permissiondialog"
+)
+
+// Env names holding git credentials in git entries.
+const (
+	GitUsernameEnv = "GIT_USERNAME"
+	GitPasswordEnv = "GIT_PASSWORD"
+)
+
+const gitEntryPrefix = "git:"
+
+// GitCredentialEntry returns the entry key of a git credential:
+// git:protocol://host/path. Since the key of a credential without path ends in
+// "/", it is a directory entry used for all repositories on the host.
+func GitCredentialEntry(credential gitcredential.Credential) string {
+	return gitEntryPrefix + credential.Protocol + "://" + credential.Host + "/" + strings.TrimPrefix(credential.Path, "/")
+}
+
+// GitCredentialGet asks for permission and returns the stored credential
+// matching the request. ok is false if no credential is stored, so git can
+// try other helpers or ask the user.
+func (l *Launcher) GitCredentialGet(request gitcredential.Credential, caller permissiondialog.CallerInfo) (credential gitcredential.Credential, ok bool, err error) {
+	entryKey := GitCredentialEntry(request)
+	resolvedPath := l.resolveEntry(entryKey)
+	encryptedEnvs := l.loadProfileEnvs(resolvedPath, DefaultProfile)
+	if _, ok := encryptedEnvs[GitPasswordEnv]; !ok {
+		return gitcredential.Credential{}, false, nil
+	}
+
+	envNames := []string{GitPasswordEnv}
+	if _, ok := encryptedEnvs[GitUsernameEnv]; ok {
+		envNames = []string{GitPasswordEnv, GitUsernameEnv}
+	}
+	if err := l.askCredentialPermission("git", request.URL(), resolvedPath, permissiondialog.Request{ApplicationPath: entryKey, EnvNames: envNames, Caller: caller}); err != nil {
+		return gitcredential.Credential{}, false, err
+	}
+
+	key, _ := l.Keychain.RetrieveEncryptionKey()
+	values := l.decryptEnvs(key, encryptedEnvs)
+	username := values[GitUsernameEnv]
+	if request.Username != "" && username != "" && request.Username != username {
+		return gitcredential.Credential{}, false, nil
+	}
+	credential = request
+	if username != "" {
+		credential.Username = username
+	}
+	credential.Password = values[GitPasswordEnv]
+	return credential, true, nil
+}
+
+// GitCredentialStore encrypts a credential git reports as working under the
+// credential's own entry. Storing the credential of the matching entry again
+// does nothing, since git stores every credential it used successfully.
+func (l *Launcher) GitCredentialStore(credential gitcredential.Credential) error {
+	entryKey := GitCredentialEntry(credential)
+	key, _ := l.Keychain.RetrieveEncryptionKey()
+	current := l.decryptEnvs(key, l.loadProfileEnvs(l.resolveEntry(entryKey), DefaultProfile))
+	if current[GitPasswordEnv] == credential.Password && current[GitUsernameEnv] == credential.Username {
+		return nil
+	}
+
+	encryptedEnvs := map[string]string{}
+	for name, encrypted := range l.loadProfileEnvs(entryKey, DefaultProfile) {
+		encryptedEnvs[name] = encrypted
+	}
+	encryptedEnvs[GitPasswordEnv] = l.encrypt(key, credential.Password)
+	envNames := []string{GitPasswordEnv}
+	if credential.Username != "" {
+		encryptedEnvs[GitUsernameEnv] = l.encrypt(key, credential.Username)
+		envNames = append(envNames, GitUsernameEnv)
+	} else {
+		delete(encryptedEnvs, GitUsernameEnv)
+	}
+	l.recordChanges(secretScope{Entry: entryKey, Profile: DefaultProfile}, envNames, encryptedEnvs, nil)
+	l.saveProfileEnvs(entryKey, DefaultProfile, encryptedEnvs)
+
+	l.audit(AuditEntry{Action: "git-credential", Result: "saved", Caller: callerChain(l.Caller), App: entryKey, Args: []string{"store"}, EnvNames: envNames})
+	return nil
+}
+
+// GitCredentialErase removes the password of the matching entry if it is the
+// one git reports as rejected.
+func (l *Launcher) GitCredentialErase(credential gitcredential.Credential) error {
+	resolvedPath := l.resolveEntry(GitCredentialEntry(credential))
+	encryptedEnvs := map[string]string{}
+	for name, encrypted := range l.loadProfileEnvs(resolvedPath, DefaultProfile) {
+		encryptedEnvs[name] = encrypted
+	}
+	encryptedPassword, ok := encryptedEnvs[GitPasswordEnv]
+	if !ok {
+		return nil
+	}
+
+	key, _ := l.Keychain.RetrieveEncryptionKey()
+	if credential.Password != "" && l.decrypt(key, encryptedPassword) != credential.Password {
+		return nil
+	}
+	delete(encryptedEnvs, GitPasswordEnv)
+	l.recordChanges(secretScope{Entry: resolvedPath, Profile: DefaultProfile}, nil, encryptedEnvs, nil)
+	l.saveProfileEnvs(resolvedPath, DefaultProfile, encryptedEnvs)
+
+	l.audit(AuditEntry{Action: "git-credential", Result: "erased", Caller: callerChain(l.Caller), App: resolvedPath, Args: []string{"erase"}, EnvNames: []string{GitPasswordEnv}})
+	return nil
+}
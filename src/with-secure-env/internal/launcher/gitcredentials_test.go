@@ -0,0 +1,100 @@
+package launcher
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/kfischer-okarin/with-secure-env/internal/gitcredential"
+	"github.com/kfischer-okarin/with-secure-env/internal/permissiondialog"
+)
+
+func TestGitCredentialStore_StoresEncryptedCredentialForHost(t *testing.T) {
+	launcher, _, _, permDialog := newTestLauncher(t)
+	launcher.Init()
+
+	err := launcher.GitCredentialStore(gitcredential.Credential{Protocol: "https", Host: "github.com", Username: "octocat", Password: "token"})
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	stored := launcher.loadFileContent()["git:https://github.com/"]
+	if stored[GitPasswordEnv] == "" || stored[GitPasswordEnv] == "token" {
+		t.Errorf("expected encrypted password, got %v", stored)
+	}
+
+	permDialog.returnGranted = true
+	caller := permissiondialog.CallerInfo{Name: "git", PID: 42}
+	credential, ok, err := launcher.GitCredentialGet(gitcredential.Credential{Protocol: "https", Host: "github.com", Path: "org/repo.git"}, caller)
+
+	if err != nil || !ok {
+		t.Fatalf("expected stored credential, got %v, %v", ok, err)
+	}
+	if credential.Username != "octocat" || credential.Password != "token" {
+		t.Errorf("expected octocat/token, got %+v", credential)
+	}
+	if permDialog.receivedCaller.Name != "git" || permDialog.receivedRequest.Credential != "git" || permDialog.receivedRequest.MatchedEntry != "git:https://github.com/" {
+		t.Errorf("expected git credential request, got %+v", permDialog.receivedRequest)
+	}
+}
+
+func TestGitCredentialGet_ReturnsNothingWithoutStoredCredential(t *testing.T) {
+	launcher, _, _, permDialog := newTestLauncher(t)
+	launcher.Init()
+
+	_, ok, err := launcher.GitCredentialGet(gitcredential.Credential{Protocol: "https", Host: "github.com"}, permissiondialog.CallerInfo{})
+
+	if ok || err != nil {
+		t.Errorf("expected no credential, got %v, %v", ok, err)
+	}
+	if permDialog.receivedAppPath != "" {
+		t.Error("expected no permission dialog")
+	}
+}
+
+func TestGitCredentialGet_ReturnsErrorWhenPermissionDenied(t *testing.T) {
+	launcher, _, _, permDialog := newTestLauncher(t)
+	launcher.Init()
+	launcher.GitCredentialStore(gitcredential.Credential{Protocol: "https", Host: "github.com", Password: "token"})
+
+	permDialog.returnGranted = false
+	_, ok, err := launcher.GitCredentialGet(gitcredential.Credential{Protocol: "https", Host: "github.com"}, permissiondialog.CallerInfo{})
+
+	if ok || !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("expected permission denied, got %v, %v", ok, err)
+	}
+	entries, _ := launcher.AuditEntries(AuditFilter{Action: "git-credential"})
+	if len(entries) != 2 || entries[1].Result != "denied" {
+		t.Errorf("expected store and denied get audit entries, got %+v", entries)
+	}
+}
+
+func TestGitCredentialGet_PrefersRepositoryEntryOverHostEntry(t *testing.T) {
+	launcher, _, _, permDialog := newTestLauncher(t)
+	launcher.Init()
+	launcher.GitCredentialStore(gitcredential.Credential{Protocol: "https", Host: "github.com", Password: "host-token"})
+	launcher.GitCredentialStore(gitcredential.Credential{Protocol: "https", Host: "github.com", Path: "org/repo.git", Password: "repo-token"})
+
+	permDialog.returnGranted = true
+	repo, _, _ := launcher.GitCredentialGet(gitcredential.Credential{Protocol: "https", Host: "github.com", Path: "org/repo.git"}, permissiondialog.CallerInfo{})
+	other, _, _ := launcher.GitCredentialGet(gitcredential.Credential{Protocol: "https", Host: "github.com", Path: "org/other.git"}, permissiondialog.CallerInfo{})
+
+	if repo.Password != "repo-token" || other.Password != "host-token" {
+		t.Errorf("expected repo-token and host-token, got %s and %s", repo.Password, other.Password)
+	}
+}
+
+func TestGitCredentialErase_RemovesOnlyRejectedPassword(t *testing.T) {
+	launcher, _, _, _ := newTestLauncher(t)
+	launcher.Init()
+	launcher.GitCredentialStore(gitcredential.Credential{Protocol: "https", Host: "github.com", Password: "token"})
+
+	launcher.GitCredentialErase(gitcredential.Credential{Protocol: "https", Host: "github.com", Password: "other"})
+	if _, ok := launcher.loadFileContent()["git:https://github.com/"][GitPasswordEnv]; !ok {
+		t.Error("expected password to be kept when a different one was rejected")
+	}
+
+	launcher.GitCredentialErase(gitcredential.Credential{Protocol: "https", Host: "github.com", Password: "token"})
+	if _, ok := launcher.loadFileContent()["git:https://github.com/"][GitPasswordEnv]; ok {
+		t.Error("expected rejected password to be erased")
+	}
+}
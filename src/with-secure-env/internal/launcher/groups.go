@@ -0,0 +1,138 @@
+package launcher
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/kfischer-okarin/with-secure-env/internal/editdialog"
+	"github.com/kfischer-okarin/with-secure-env/internal/envschema"
+	"github.com/kfischer-okarin/with-secure-env/internal/secretgen"
+	"github.com/kfischer-okarin/with-secure-env/internal/secretmeta"
+)
+
+// GroupInfo describes a shared group of envs.
+type GroupInfo struct {
+	Name     string
+	EnvNames []string
+	// UsedBy lists the applications referencing the group.
+	UsedBy []string
+	// Metadata is the metadata of the group's envs.
+	Metadata map[string]secretmeta.Metadata
+}
+
+// EditGroup edits the envs of a shared group, creating it if necessary.
+// Changes apply to every application using the group.
+func (l *Launcher) EditGroup(group string) error {
+	if err := validateName("group", group); err != nil {
+		return err
+	}
+
+	groups := l.loadGroups()
+	key, _ := l.Keychain.RetrieveEncryptionKey()
+	currentValues := l.decryptEnvs(key, groups[group])
+
+	scope := secretScope{Group: group}
+	// Groups are shared by applications with different schemas, so only the
+	// names are checked.
+	validate := validator(envschema.Schema{}, nil)
+	result, ok := l.EditDialog.EditEnvs(editdialog.Request{
+		Group:    group,
+		UsedBy:   l.groupUsers()[group],
+		Values:   currentValues,
+		Metadata: l.secretMetadata(scope),
+		Generate: secretgen.Generate,
+		Validate: validate,
+	})
+	entry := AuditEntry{Action: "edit", Caller: callerChain(l.Caller), Group: group}
+	if !ok {
+		entry.Result = "canceled"
+		l.audit(entry)
+		return nil
+	}
+	if problems := validate(result.Values); problems != nil {
+		entry.Result = "invalid"
+		l.audit(entry)
+		return envschema.Error(problems)
+	}
+
+	encryptedEnvs := make(map[string]string)
+	for envName, value := range result.Values {
+		encryptedEnvs[envName] = l.encryptEdited(key, groups[group], currentValues, envName, value)
+	}
+	l.recordChanges(scope, changedNames(currentValues, result.Values), result.Values, result.Metadata)
+	groups[group] = encryptedEnvs
+	l.saveGroups(groups)
+
+	entry.Result = "saved"
+	entry.EnvNames = sortedNames(result.Values)
+	l.audit(entry)
+	return nil
+}
+
+// Groups returns all shared groups sorted by name.
+func (l *Launcher) Groups() []GroupInfo {
+	groups := l.loadGroups()
+	users := l.groupUsers()
+	metadata := l.loadMetadata().Groups
+
+	var result []GroupInfo
+	for _, name := range sortedNames(groups) {
+		result = append(result, GroupInfo{Name: name, EnvNames: sortedNames(groups[name]), UsedBy: users[name], Metadata: metadata[name]})
+	}
+	return result
+}
+
+// groupUsers returns the sorted applications using each group.
+func (l *Launcher) groupUsers() map[string][]string {
+	users := map[string][]string{}
+	for applicationPath, settings := range l.loadSettings() {
+		for _, group := range settings.Groups {
+			users[group] = append(users[group], applicationPath)
+		}
+	}
+	for _, paths := range users {
+		sort.Strings(paths)
+	}
+	return users
+}
+
+// mergeGroupEnvs returns the encrypted envs of the groups used by the
+// application, overridden by the application's own envs. Later groups override
+// earlier ones.
+func (l *Launcher) mergeGroupEnvs(settings AppSettings, appEnvs map[string]string) map[string]string {
+	if len(settings.Groups) == 0 {
+		return appEnvs
+	}
+
+	groups := l.loadGroups()
+	merged := map[string]string{}
+	for _, group := range settings.Groups {
+		for name, encrypted := range groups[group] {
+			merged[name] = encrypted
+		}
+	}
+	for name, encrypted := range appEnvs {
+		merged[name] = encrypted
+	}
+	return merged
+}
+
+func (l *Launcher) validateGroupsExist(groups []string) error {
+	existing := l.loadGroups()
+	for _, group := range groups {
+		if _, ok := existing[group]; !ok {
+			return fmt.Errorf("unknown group %s (create it with edit --group)", group)
+		}
+	}
+	return nil
+}
+
+func (l *Launcher) loadGroups() map[string]map[string]string {
+	groups := map[string]map[string]string{}
+	l.loadJSON("groups.json", &groups)
+	return groups
+}
+
+func (l *Launcher) saveGroups(groups map[string]map[string]string) {
+	l.saveJSON("groups.json", groups)
+}
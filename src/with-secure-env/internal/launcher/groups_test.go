@@ -0,0 +1,109 @@
+package launcher
+
+import (
+	"testing"
+)
+
+func TestLaunch_InjectsEnvsOfConfiguredGroups(t *testing.T) {
+	launcher, _, editDialog, permDialog := newTestLauncher(t)
+	launcher.Init()
+	editDialog.returnOk = true
+	editDialog.returnValues = map[string]string{"AWS_ACCESS_KEY_ID": "AKIA123"}
+	launcher.EditGroup("aws")
+	editDialog.returnValues = map[string]string{"API_KEY": "app-secret"}
+	launcher.EditEnvs("/path/to/app")
+
+	launcher.Configure("/path/to/app", "groups", "aws")
+	env := launchAndCaptureEnv(launcher, permDialog)
+
+	if !containsEnv(env, "AWS_ACCESS_KEY_ID=AKIA123") || !containsEnv(env, "API_KEY=app-secret") {
+		t.Errorf("expected group and app envs, got %v", env)
+	}
+	if len(permDialog.receivedRequest.Groups) != 1 || permDialog.receivedRequest.Groups[0] != "aws" {
+		t.Errorf("expected groups [aws] in permission request, got %v", permDialog.receivedRequest.Groups)
+	}
+}
+
+func TestLaunch_AppEnvsOverrideLaterGroupsOverrideEarlierGroups(t *testing.T) {
+	launcher, _, editDialog, permDialog := newTestLauncher(t)
+	launcher.Init()
+	editDialog.returnOk = true
+	editDialog.returnValues = map[string]string{"REGION": "eu", "TOKEN": "first", "USER": "first"}
+	launcher.EditGroup("first")
+	editDialog.returnValues = map[string]string{"TOKEN": "second", "USER": "second"}
+	launcher.EditGroup("second")
+	editDialog.returnValues = map[string]string{"USER": "app"}
+	launcher.EditEnvs("/path/to/app")
+
+	launcher.Configure("/path/to/app", "groups", "first,second")
+	env := launchAndCaptureEnv(launcher, permDialog)
+
+	for _, expected := range []string{"REGION=eu", "TOKEN=second", "USER=app"} {
+		if !containsEnv(env, expected) {
+			t.Errorf("expected %s, got %v", expected, env)
+		}
+	}
+}
+
+func TestEditGroup_UpdatesEveryApplicationUsingIt(t *testing.T) {
+	launcher, _, editDialog, permDialog := newTestLauncher(t)
+	launcher.Init()
+	editDialog.returnOk = true
+	editDialog.returnValues = map[string]string{"GITHUB_TOKEN": "old"}
+	launcher.EditGroup("github")
+	launcher.Configure("/path/to/app", "groups", "github")
+	launcher.Configure("/path/to/other", "groups", "github")
+
+	editDialog.returnValues = map[string]string{"GITHUB_TOKEN": "rotated"}
+	launcher.EditGroup("github")
+	env := launchAndCaptureEnv(launcher, permDialog)
+
+	if !containsEnv(env, "GITHUB_TOKEN=rotated") {
+		t.Errorf("expected rotated token, got %v", env)
+	}
+	usedBy := editDialog.receivedRequest.UsedBy
+	if len(usedBy) != 2 || usedBy[0] != "/path/to/app" || usedBy[1] != "/path/to/other" {
+		t.Errorf("expected edit dialog to show both users, got %v", usedBy)
+	}
+}
+
+func TestConfigure_RejectsUnknownGroup(t *testing.T) {
+	launcher, _, _, _ := newTestLauncher(t)
+	launcher.Init()
+
+	err := launcher.Configure("/path/to/app", "groups", "missing")
+
+	if err == nil {
+		t.Error("expected error for unknown group")
+	}
+}
+
+func TestGroups_ListsWhereGroupsAreUsed(t *testing.T) {
+	launcher, _, editDialog, _ := newTestLauncher(t)
+	launcher.Init()
+	editDialog.returnOk = true
+	editDialog.returnValues = map[string]string{"AWS_SECRET_ACCESS_KEY": "s", "AWS_ACCESS_KEY_ID": "k"}
+	launcher.EditGroup("aws")
+	editDialog.returnValues = map[string]string{"GITHUB_TOKEN": "t"}
+	launcher.EditGroup("github")
+	launcher.Configure("/path/to/app", "groups", "aws")
+
+	groups := launcher.Groups()
+
+	if len(groups) != 2 || groups[0].Name != "aws" || groups[1].Name != "github" {
+		t.Fatalf("expected groups aws and github, got %+v", groups)
+	}
+	if len(groups[0].EnvNames) != 2 || groups[0].EnvNames[0] != "AWS_ACCESS_KEY_ID" {
+		t.Errorf("expected sorted env names, got %v", groups[0].EnvNames)
+	}
+	if len(groups[0].UsedBy) != 1 || groups[0].UsedBy[0] != "/path/to/app" {
+		t.Errorf("expected aws used by /path/to/app, got %v", groups[0].UsedBy)
+	}
+	if len(groups[1].UsedBy) != 0 {
+		t.Errorf("expected github unused, got %v", groups[1].UsedBy)
+	}
+	apps := launcher.Applications()
+	if len(apps) != 1 || apps[0].Path != "/path/to/app" || apps[0].Groups[0] != "aws" {
+		t.Errorf("expected /path/to/app using aws, got %+v", apps)
+	}
+}
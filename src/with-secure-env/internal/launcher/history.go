@@ -0,0 +1,208 @@
+package launcher
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultHistoryVersions is the number of previous values kept per variable
+// unless configured otherwise with historyVersions.
+const DefaultHistoryVersions = 10
+
+// HistoryTarget selects the variable whose history is accessed: a profile of
+// an application or a shared group.
+type HistoryTarget struct {
+	ApplicationPath string
+	// Profile selects the profile instead of the application's default
+	// profile.
+	Profile string
+	// Group is set instead of ApplicationPath to select a shared group.
+	Group string
+}
+
+// ValueVersion describes a previous value of a variable without revealing it.
+type ValueVersion struct {
+	Version int
+	// Replaced is when the value was overwritten or removed.
+	Replaced time.Time
+}
+
+// historyVersion is a previous value as stored in history.json.
+type historyVersion struct {
+	Version  int       `json:"version"`
+	Replaced time.Time `json:"replaced"`
+	// Value is the ciphertext as it was stored.
+	Value string `json:"value"`
+}
+
+// History returns the previous values of a variable, oldest first.
+func (l *Launcher) History(target HistoryTarget, name string) ([]ValueVersion, error) {
+	scope, err := l.targetScope(target.ApplicationPath, target.Profile, target.Group)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []ValueVersion
+	for _, version := range l.pruneVersions(scope, l.loadHistory().get(scope)[name]) {
+		result = append(result, ValueVersion{Version: version.Version, Replaced: version.Replaced})
+	}
+	return result, nil
+}
+
+// Rollback restores a previous value of a variable, the most recent one if
+// version is 0, and returns the restored version. The replaced value is
+// archived in turn, so rolling back again without version undoes a rollback.
+// No value is decrypted, so the key stays locked.
+func (l *Launcher) Rollback(target HistoryTarget, name string, version int) (int, error) {
+	scope, err := l.targetScope(target.ApplicationPath, target.Profile, target.Group)
+	if err != nil {
+		return 0, err
+	}
+	versions := l.pruneVersions(scope, l.loadHistory().get(scope)[name])
+	if len(versions) == 0 {
+		return 0, fmt.Errorf("no previous values of %s", name)
+	}
+	restored := versions[len(versions)-1]
+	if version != 0 {
+		found := false
+		for _, candidate := range versions {
+			if candidate.Version == version {
+				restored, found = candidate, true
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("version %d of %s not found", version, name)
+		}
+	}
+
+	encryptedEnvs := map[string]string{}
+	for envName, encrypted := range l.scopeEnvs(scope) {
+		encryptedEnvs[envName] = encrypted
+	}
+	encryptedEnvs[name] = restored.Value
+	l.recordChanges(scope, []string{name}, encryptedEnvs, nil)
+	l.saveScopeEnvs(scope, encryptedEnvs)
+
+	l.audit(AuditEntry{Action: "rollback", Result: "saved", Caller: callerChain(l.Caller), App: scope.Entry, Profile: scope.Profile, Group: scope.Group, Args: []string{strconv.Itoa(restored.Version)}, EnvNames: []string{name}})
+	return restored.Version, nil
+}
+
+// archiveValues appends the stored values of names in scope to their history.
+func (l *Launcher) archiveValues(scope secretScope, names []string) {
+	encryptedEnvs := l.scopeEnvs(scope)
+	file := l.loadHistory()
+	history := file.get(scope)
+	archived := false
+	for _, name := range names {
+		encrypted, ok := encryptedEnvs[name]
+		if !ok {
+			continue
+		}
+		versions := history[name]
+		next := 1
+		if len(versions) > 0 {
+			next = versions[len(versions)-1].Version + 1
+		}
+		history[name] = l.pruneVersions(scope, append(versions, historyVersion{Version: next, Replaced: l.now().UTC(), Value: encrypted}))
+		archived = true
+	}
+	if !archived {
+		return
+	}
+	file.set(scope, history)
+	l.saveHistory(file)
+}
+
+// pruneVersions drops versions older than historyMaxAge and all but the last
+// historyVersions ones.
+func (l *Launcher) pruneVersions(scope secretScope, versions []historyVersion) []historyVersion {
+	limit, maxAge := DefaultHistoryVersions, time.Duration(0)
+	if scope.Group == "" {
+		settings := l.loadSettings()[scope.Entry]
+		if settings.HistoryVersions > 0 {
+			limit = settings.HistoryVersions
+		}
+		maxAge, _ = parseAge(settings.HistoryMaxAge)
+	}
+
+	var kept []historyVersion
+	for _, version := range versions {
+		if maxAge == 0 || l.now().Sub(version.Replaced) <= maxAge {
+			kept = append(kept, version)
+		}
+	}
+	if len(kept) > limit {
+		kept = kept[len(kept)-limit:]
+	}
+	return kept
+}
+
+// parseAge parses a duration like time.ParseDuration, additionally accepting
+// whole days like "90d".
+func parseAge(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid age %s (expected e.g. 90d or 12h)", value)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	age, err := time.ParseDuration(value)
+	if err != nil || age <= 0 {
+		return 0, fmt.Errorf("invalid age %s (expected e.g. 90d or 12h)", value)
+	}
+	return age, nil
+}
+
+// targetScope validates and resolves the scope selected by a command: a
+// profile of an application or a shared group.
+func (l *Launcher) targetScope(applicationPath string, profile string, group string) (secretScope, error) {
+	if group != "" {
+		if err := validateName("group", group); err != nil {
+			return secretScope{}, err
+		}
+		return secretScope{Group: group}, nil
+	}
+	applicationPath = l.resolveApplicationPath(applicationPath)
+	if err := validateEntry(applicationPath); err != nil {
+		return secretScope{}, err
+	}
+	profile = profileFor(l.loadSettings()[applicationPath], profile)
+	if err := validateName("profile", profile); err != nil {
+		return secretScope{}, err
+	}
+	return secretScope{Entry: applicationPath, Profile: profile}, nil
+}
+
+// scopeEnvs returns the stored encrypted envs of scope.
+func (l *Launcher) scopeEnvs(scope secretScope) map[string]string {
+	if scope.Group != "" {
+		return l.loadGroups()[scope.Group]
+	}
+	return l.loadProfileEnvs(scope.Entry, scope.Profile)
+}
+
+func (l *Launcher) saveScopeEnvs(scope secretScope, encryptedEnvs map[string]string) {
+	if scope.Group != "" {
+		groups := l.loadGroups()
+		groups[scope.Group] = encryptedEnvs
+		l.saveGroups(groups)
+		return
+	}
+	l.saveProfileEnvs(scope.Entry, scope.Profile, encryptedEnvs)
+}
+
+func (l *Launcher) loadHistory() scopedFile[[]historyVersion] {
+	var file scopedFile[[]historyVersion]
+	l.loadJSON("history.json", &file)
+	return file
+}
+
+func (l *Launcher) saveHistory(file scopedFile[[]historyVersion]) {
+	l.saveJSON("history.json", file)
+}
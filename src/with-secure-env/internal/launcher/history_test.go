@@ -0,0 +1,153 @@
+package launcher
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kfischer-okarin/with-secure-env/internal/permissiondialog"
+)
+
+func TestEditEnvs_KeepsPreviousValues(t *testing.T) {
+	launcher, _, editDialog, _ := newTestLauncher(t)
+	launcher.Init()
+	editDialog.returnOk = true
+	for _, value := range []string{"one", "two", "three"} {
+		editDialog.returnValues = map[string]string{"API_KEY": value, "UNCHANGED": "same"}
+		launcher.EditEnvs("/path/to/app")
+	}
+
+	history, err := launcher.History(HistoryTarget{ApplicationPath: "/path/to/app"}, "API_KEY")
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(history) != 2 || history[0].Version != 1 || history[1].Version != 2 {
+		t.Errorf("expected versions 1 and 2, got %v", history)
+	}
+	if unchanged, _ := launcher.History(HistoryTarget{ApplicationPath: "/path/to/app"}, "UNCHANGED"); len(unchanged) != 0 {
+		t.Errorf("expected no history for unchanged value, got %v", unchanged)
+	}
+}
+
+func TestRollback_RestoresPreviousValue(t *testing.T) {
+	launcher, _, editDialog, permDialog := newTestLauncher(t)
+	launcher.Init()
+	editDialog.returnOk = true
+	for _, value := range []string{"one", "two", "three"} {
+		editDialog.returnValues = map[string]string{"API_KEY": value}
+		launcher.EditEnvs("/path/to/app")
+	}
+	var executedEnv []string
+	launcher.Exec = func(process Process) (ExitStatus, error) {
+		executedEnv = process.Env
+		return ExitStatus{}, nil
+	}
+	permDialog.returnGranted = true
+
+	version, err := launcher.Rollback(HistoryTarget{ApplicationPath: "/path/to/app"}, "API_KEY", 1)
+	launcher.Launch("/path/to/app", nil, permissiondialog.CallerInfo{})
+
+	if err != nil || version != 1 {
+		t.Fatalf("expected version 1 to be restored, got %d, %v", version, err)
+	}
+	if !containsEnv(executedEnv, "API_KEY=one") {
+		t.Errorf("expected restored value, got %v", executedEnv)
+	}
+
+	version, _ = launcher.Rollback(HistoryTarget{ApplicationPath: "/path/to/app"}, "API_KEY", 0)
+	launcher.Launch("/path/to/app", nil, permissiondialog.CallerInfo{})
+
+	if version != 3 || !containsEnv(executedEnv, "API_KEY=three") {
+		t.Errorf("expected rollback without version to undo the rollback, got version %d and %v", version, executedEnv)
+	}
+}
+
+func TestRollback_RestoresRemovedGroupValue(t *testing.T) {
+	launcher, _, editDialog, _ := newTestLauncher(t)
+	launcher.Init()
+	editDialog.returnOk = true
+	editDialog.returnValues = map[string]string{"PASSWORD": "secret", "USER": "app"}
+	launcher.EditGroup("db")
+	editDialog.returnValues = map[string]string{"USER": "app"}
+	launcher.EditGroup("db")
+
+	_, err := launcher.Rollback(HistoryTarget{Group: "db"}, "PASSWORD", 0)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	key, _ := launcher.Keychain.RetrieveEncryptionKey()
+	if values := launcher.decryptEnvs(key, launcher.loadGroups()["db"]); values["PASSWORD"] != "secret" || values["USER"] != "app" {
+		t.Errorf("expected removed value to be restored, got %v", values)
+	}
+}
+
+func TestRollback_FailsWithoutHistory(t *testing.T) {
+	launcher, _, editDialog, _ := newTestLauncher(t)
+	launcher.Init()
+	editDialog.returnOk = true
+	editDialog.returnValues = map[string]string{"API_KEY": "one"}
+	launcher.EditEnvs("/path/to/app")
+
+	if _, err := launcher.Rollback(HistoryTarget{ApplicationPath: "/path/to/app"}, "API_KEY", 0); err == nil {
+		t.Error("expected error without previous values")
+	}
+	editDialog.returnValues = map[string]string{"API_KEY": "two"}
+	launcher.EditEnvs("/path/to/app")
+	if _, err := launcher.Rollback(HistoryTarget{ApplicationPath: "/path/to/app"}, "API_KEY", 5); err == nil {
+		t.Error("expected error for unknown version")
+	}
+}
+
+func TestHistory_PrunesByCountAndAge(t *testing.T) {
+	launcher, _, editDialog, _ := newTestLauncher(t)
+	launcher.Init()
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	launcher.Now = func() time.Time { return now }
+	launcher.Configure("/path/to/app", "historyVersions", "3")
+	launcher.Configure("/path/to/app", "historyMaxAge", "30d")
+	editDialog.returnOk = true
+	for i, value := range []string{"1", "2", "3", "4", "5", "6"} {
+		now = time.Date(2026, 1, 1+i*10, 0, 0, 0, 0, time.UTC)
+		editDialog.returnValues = map[string]string{"API_KEY": value}
+		launcher.EditEnvs("/path/to/app")
+	}
+
+	history, _ := launcher.History(HistoryTarget{ApplicationPath: "/path/to/app"}, "API_KEY")
+
+	if len(history) != 3 || history[0].Version != 3 {
+		t.Errorf("expected the last 3 versions, got %v", history)
+	}
+	now = now.AddDate(0, 0, 15)
+	if history, _ = launcher.History(HistoryTarget{ApplicationPath: "/path/to/app"}, "API_KEY"); len(history) != 2 {
+		t.Errorf("expected versions older than 30 days to be dropped, got %v", history)
+	}
+}
+
+func TestConfigure_RejectsInvalidHistorySettings(t *testing.T) {
+	launcher, _, _, _ := newTestLauncher(t)
+
+	for key, value := range map[string]string{"historyVersions": "0", "historyMaxAge": "soon"} {
+		if err := launcher.Configure("/path/to/app", key, value); err == nil {
+			t.Errorf("expected error for %s %s", key, value)
+		}
+	}
+}
+
+func TestMove_KeepsHistory(t *testing.T) {
+	launcher, _, editDialog, _ := newTestLauncher(t)
+	launcher.Init()
+	editDialog.returnOk = true
+	for _, value := range []string{"one", "two"} {
+		editDialog.returnValues = map[string]string{"API_KEY": value}
+		launcher.EditEnvs("/old/app")
+	}
+
+	launcher.Move("/old/app", "/new/app")
+	launcher.Rollback(HistoryTarget{ApplicationPath: "/new/app"}, "API_KEY", 0)
+
+	key, _ := launcher.Keychain.RetrieveEncryptionKey()
+	if values := launcher.decryptEnvs(key, launcher.loadFileContent()["/new/app"]); values["API_KEY"] != "one" {
+		t.Errorf("expected history to move along, got %v", values)
+	}
+}
@@ -0,0 +1,65 @@
+package launcher
+
+import "github.com/kfischer-okarin/with-secure-env/internal/envschema"
+
+// ImportPlan previews an import by env names only.
+type ImportPlan struct {
+	ApplicationPath string
+	Profile         string
+	// Added are the names not configured yet.
+	Added []string
+	// Replaced are the names whose values get overwritten.
+	Replaced []string
+}
+
+// PlanImport returns which envs an import of values into the profile of the
+// application would add or replace. An empty profile selects the
+// application's default profile.
+func (l *Launcher) PlanImport(applicationPath string, profile string, values map[string]string) (ImportPlan, error) {
+	applicationPath = l.resolveApplicationPath(applicationPath)
+	if err := validateEntry(applicationPath); err != nil {
+		return ImportPlan{}, err
+	}
+	profile = profileFor(l.loadSettings()[applicationPath], profile)
+	if err := validateName("profile", profile); err != nil {
+		return ImportPlan{}, err
+	}
+
+	if err := envschema.ValidateNames(sortedNames(values)); err != nil {
+		return ImportPlan{}, err
+	}
+
+	plan := ImportPlan{ApplicationPath: applicationPath, Profile: profile}
+	existing := l.loadProfileEnvs(applicationPath, profile)
+	for _, name := range sortedNames(values) {
+		if _, ok := existing[name]; ok {
+			plan.Replaced = append(plan.Replaced, name)
+		} else {
+			plan.Added = append(plan.Added, name)
+		}
+	}
+	return plan, nil
+}
+
+// Import merges values into the profile of the application, replacing envs
+// with the same name.
+func (l *Launcher) Import(applicationPath string, profile string, values map[string]string) error {
+	plan, err := l.PlanImport(applicationPath, profile, values)
+	if err != nil {
+		return err
+	}
+
+	key, _ := l.Keychain.RetrieveEncryptionKey()
+	encryptedEnvs := map[string]string{}
+	for name, encrypted := range l.loadProfileEnvs(plan.ApplicationPath, plan.Profile) {
+		encryptedEnvs[name] = encrypted
+	}
+	for name, value := range values {
+		encryptedEnvs[name] = l.encrypt(key, value)
+	}
+	l.recordChanges(secretScope{Entry: plan.ApplicationPath, Profile: plan.Profile}, sortedNames(values), encryptedEnvs, nil)
+	l.saveProfileEnvs(plan.ApplicationPath, plan.Profile, encryptedEnvs)
+
+	l.audit(AuditEntry{Action: "import", Result: "saved", Caller: callerChain(l.Caller), App: plan.ApplicationPath, Profile: plan.Profile, EnvNames: sortedNames(values)})
+	return nil
+}
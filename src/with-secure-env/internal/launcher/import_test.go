@@ -0,0 +1,68 @@
+package launcher
+
+import (
+	"testing"
+)
+
+func TestImport_PreviewsAddedAndReplacedNames(t *testing.T) {
+	launcher, _, editDialog, _ := newTestLauncher(t)
+	launcher.Init()
+	editDialog.returnOk = true
+	editDialog.returnValues = map[string]string{"API_KEY": "old"}
+	launcher.EditEnvs("/path/to/app")
+
+	plan, err := launcher.PlanImport("/path/to/app", "", map[string]string{"API_KEY": "new", "DB_PASS": "pass"})
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if plan.Profile != "default" {
+		t.Errorf("expected default profile, got '%s'", plan.Profile)
+	}
+	if len(plan.Added) != 1 || plan.Added[0] != "DB_PASS" {
+		t.Errorf("expected DB_PASS to be added, got %v", plan.Added)
+	}
+	if len(plan.Replaced) != 1 || plan.Replaced[0] != "API_KEY" {
+		t.Errorf("expected API_KEY to be replaced, got %v", plan.Replaced)
+	}
+}
+
+func TestImport_MergesIntoExistingEnvs(t *testing.T) {
+	launcher, _, editDialog, _ := newTestLauncher(t)
+	launcher.Init()
+	editDialog.returnOk = true
+	editDialog.returnValues = map[string]string{"API_KEY": "old", "KEEP": "kept"}
+	launcher.EditEnvs("/path/to/app")
+
+	err := launcher.Import("/path/to/app", "", map[string]string{"API_KEY": "new", "DB_PASS": "pass"})
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	editDialog.returnOk = false
+	launcher.EditEnvs("/path/to/app")
+	expected := map[string]string{"API_KEY": "new", "DB_PASS": "pass", "KEEP": "kept"}
+	if !equalEnvs(editDialog.receivedCurrentValues, expected) {
+		t.Errorf("expected %v, got %v", expected, editDialog.receivedCurrentValues)
+	}
+	entries, _ := launcher.AuditEntries(AuditFilter{Action: "import"})
+	if len(entries) != 1 || len(entries[0].EnvNames) != 2 {
+		t.Errorf("expected import audit entry with 2 env names, got %+v", entries)
+	}
+}
+
+func TestImport_IntoProfile(t *testing.T) {
+	launcher, _, editDialog, _ := newTestLauncher(t)
+	launcher.Init()
+
+	launcher.Import("/path/to/app", "staging", map[string]string{"API_KEY": "staging"})
+
+	launcher.EditProfile("/path/to/app", "staging")
+	if editDialog.receivedCurrentValues["API_KEY"] != "staging" {
+		t.Errorf("expected API_KEY in staging profile, got %v", editDialog.receivedCurrentValues)
+	}
+	launcher.EditEnvs("/path/to/app")
+	if len(editDialog.receivedCurrentValues) != 0 {
+		t.Errorf("expected empty default profile, got %v", editDialog.receivedCurrentValues)
+	}
+}
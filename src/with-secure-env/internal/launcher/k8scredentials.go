@@ -0,0 +1,133 @@
+package launcher
+
+import (
+	"fmt"
+	"maps"
+	"time"
+
+	"github.com/kfischer-okarin/with-secure-env/internal/execcredential"
+	"github.com/kfischer-okarin/with-secure-env/internal/permissiondialog"
+)
+
+// Env names read from k8s entries. An entry holds a token or a client
+// certificate and key. The expiration is optional.
+const (
+	K8sTokenEnv             = "K8S_TOKEN"
+	K8sClientCertificateEnv = "K8S_CLIENT_CERTIFICATE"
+	K8sClientKeyEnv         = "K8S_CLIENT_KEY"
+	K8sExpirationEnv        = "K8S_EXPIRATION"
+)
+
+const k8sEntryPrefix = "k8s:"
+
+// k8sCacheEntry remembers an approved credential until it expires, so kubectl,
+// which runs the plugin for every command, doesn't ask each time.
+type k8sCacheEntry struct {
+	// Caller is the executable of the process the approval was given to.
+	// The process name is no identity, any process can choose it.
+	Caller  string    `json:"caller"`
+	Expires time.Time `json:"expires"`
+	// Values are the approved encrypted values. The approval is void once
+	// they change.
+	Values map[string]string `json:"values"`
+}
+
+// K8sCredentialEntry returns the entry key of a kubectl credential:
+// k8s:<name>.
+func K8sCredentialEntry(name string) string {
+	return k8sEntryPrefix + name
+}
+
+// K8sCredential returns the credential of the entry k8s:<name>. The first
+// request asks for permission. The approval is cached for later requests of
+// the same caller executable until the credential's expiration or ttl, whichever comes
+// first, and the returned status expires at the same time. Without either
+// nothing is cached.
+func (l *Launcher) K8sCredential(name string, caller permissiondialog.CallerInfo, ttl time.Duration) (execcredential.Status, error) {
+	if err := validateName("k8s credential", name); err != nil {
+		return execcredential.Status{}, err
+	}
+	entryKey := K8sCredentialEntry(name)
+	resolvedPath := l.resolveEntry(entryKey)
+	settings := l.loadSettings()[resolvedPath]
+	profile := profileFor(settings, "")
+	encryptedEnvs := l.mergeGroupEnvs(settings, l.loadProfileEnvs(resolvedPath, profile))
+
+	requested := map[string]string{}
+	for _, envName := range []string{K8sTokenEnv, K8sClientCertificateEnv, K8sClientKeyEnv, K8sExpirationEnv} {
+		if encrypted, ok := encryptedEnvs[envName]; ok {
+			requested[envName] = encrypted
+		}
+	}
+	if requested[K8sTokenEnv] == "" && requested[K8sClientCertificateEnv] == "" {
+		return execcredential.Status{}, fmt.Errorf("neither %s nor %s is configured for %s", K8sTokenEnv, K8sClientCertificateEnv, entryKey)
+	}
+	if (requested[K8sClientCertificateEnv] == "") != (requested[K8sClientKeyEnv] == "") {
+		return execcredential.Status{}, fmt.Errorf("%s and %s must be configured together for %s", K8sClientCertificateEnv, K8sClientKeyEnv, entryKey)
+	}
+
+	cache := l.loadK8sCache()
+	cached, ok := cache[entryKey]
+	if ok && caller.Executable != "" && cached.Caller == caller.Executable && l.now().Before(cached.Expires) && maps.Equal(cached.Values, requested) {
+		l.audit(AuditEntry{Action: "k8s-credential", Result: "cached", Caller: callerChain(caller), App: entryKey, Entry: matchedPattern(resolvedPath), Profile: profile, Args: []string{"get"}, EnvNames: sortedNames(requested)})
+		return l.k8sStatus(requested, cached.Expires)
+	}
+
+	err := l.askCredentialPermission("k8s", name, resolvedPath, permissiondialog.Request{
+		ApplicationPath: entryKey,
+		EnvNames:        sortedNames(requested),
+		Caller:          caller,
+		Profile:         profile,
+		Groups:          settings.Groups,
+	})
+	if err != nil {
+		return execcredential.Status{}, err
+	}
+
+	key, _ := l.Keychain.RetrieveEncryptionKey()
+	var expires time.Time
+	if ttl > 0 {
+		expires = l.now().Add(ttl)
+	}
+	if expiration := l.decrypt(key, requested[K8sExpirationEnv]); expiration != "" {
+		parsed, err := time.Parse(time.RFC3339, expiration)
+		if err != nil {
+			return execcredential.Status{}, fmt.Errorf("%s is not an RFC 3339 time: %w", K8sExpirationEnv, err)
+		}
+		if expires.IsZero() || parsed.Before(expires) {
+			expires = parsed
+		}
+	}
+	if expires.IsZero() {
+		delete(cache, entryKey)
+	} else {
+		cache[entryKey] = k8sCacheEntry{Caller: caller.Executable, Expires: expires, Values: requested}
+	}
+	l.saveK8sCache(cache)
+	return l.k8sStatus(requested, expires)
+}
+
+func (l *Launcher) k8sStatus(encryptedEnvs map[string]string, expires time.Time) (execcredential.Status, error) {
+	key, _ := l.Keychain.RetrieveEncryptionKey()
+	values := l.decryptEnvs(key, encryptedEnvs)
+	status := execcredential.Status{
+		Token:                 values[K8sTokenEnv],
+		ClientCertificateData: values[K8sClientCertificateEnv],
+		ClientKeyData:         values[K8sClientKeyEnv],
+	}
+	if !expires.IsZero() {
+		expires = expires.UTC().Truncate(time.Second)
+		status.ExpirationTimestamp = &expires
+	}
+	return status, status.Validate()
+}
+
+func (l *Launcher) loadK8sCache() map[string]k8sCacheEntry {
+	cache := map[string]k8sCacheEntry{}
+	l.loadJSON("k8s-cache.json", &cache)
+	return cache
+}
+
+func (l *Launcher) saveK8sCache(cache map[string]k8sCacheEntry) {
+	l.saveJSON("k8s-cache.json", cache)
+}
@@ -0,0 +1,131 @@
+package launcher
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kfischer-okarin/with-secure-env/internal/permissiondialog"
+)
+
+func TestK8sCredential_ReturnsTokenAfterPermission(t *testing.T) {
+	launcher, _, permDialog := newK8sTestLauncher(t, map[string]string{"K8S_TOKEN": "token"})
+
+	permDialog.returnGranted = true
+	status, err := launcher.K8sCredential("prod", permissiondialog.CallerInfo{Name: "kubectl", PID: 42}, 0)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if status.Token != "token" || status.ExpirationTimestamp != nil {
+		t.Errorf("expected token without expiration, got %+v", status)
+	}
+	if permDialog.receivedRequest.Credential != "k8s" || permDialog.receivedCaller.Name != "kubectl" {
+		t.Errorf("expected k8s credential request from kubectl, got %+v", permDialog.receivedRequest)
+	}
+}
+
+func TestK8sCredential_CachesApprovalUntilExpiry(t *testing.T) {
+	launcher, _, permDialog := newK8sTestLauncher(t, map[string]string{"K8S_TOKEN": "token"})
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	launcher.Now = func() time.Time { return now }
+	kubectl := permissiondialog.CallerInfo{Name: "kubectl", PID: 42, Executable: "/usr/local/bin/kubectl"}
+
+	permDialog.returnGranted = true
+	first, _ := launcher.K8sCredential("prod", kubectl, 10*time.Minute)
+	permDialog.returnGranted = false
+	now = now.Add(5 * time.Minute)
+	second, err := launcher.K8sCredential("prod", permissiondialog.CallerInfo{Name: "kubectl", PID: 43, Executable: "/usr/local/bin/kubectl"}, 10*time.Minute)
+
+	if err != nil || second.Token != "token" {
+		t.Fatalf("expected cached credential, got %+v, %v", second, err)
+	}
+	if !first.ExpirationTimestamp.Equal(time.Date(2026, 10, 18, 12, 10, 0, 0, time.UTC)) || !second.ExpirationTimestamp.Equal(*first.ExpirationTimestamp) {
+		t.Errorf("expected both to expire at 12:10, got %v and %v", first.ExpirationTimestamp, second.ExpirationTimestamp)
+	}
+	if _, err := launcher.K8sCredential("prod", permissiondialog.CallerInfo{Name: "other"}, 10*time.Minute); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("expected other callers to be asked, got %v", err)
+	}
+
+	now = now.Add(6 * time.Minute)
+	if _, err := launcher.K8sCredential("prod", kubectl, 10*time.Minute); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("expected expired approval to ask again, got %v", err)
+	}
+	entries, _ := launcher.AuditEntries(AuditFilter{Action: "k8s-credential"})
+	if len(entries) != 4 || entries[1].Result != "cached" {
+		t.Errorf("expected granted, cached and two denied audit entries, got %+v", entries)
+	}
+}
+
+func TestK8sCredential_CacheIsKeyedOnCallerExecutable(t *testing.T) {
+	launcher, _, permDialog := newK8sTestLauncher(t, map[string]string{"K8S_TOKEN": "token"})
+	launcher.Now = func() time.Time { return time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC) }
+
+	permDialog.returnGranted = true
+	launcher.K8sCredential("prod", permissiondialog.CallerInfo{Name: "kubectl", Executable: "/usr/local/bin/kubectl"}, time.Hour)
+	permDialog.returnGranted = false
+
+	for _, caller := range []permissiondialog.CallerInfo{
+		{Name: "kubectl", Executable: "/tmp/kubectl"},
+		{Name: "kubectl"},
+	} {
+		if _, err := launcher.K8sCredential("prod", caller, time.Hour); !errors.Is(err, ErrPermissionDenied) {
+			t.Errorf("expected %+v to miss the cache, got %v", caller, err)
+		}
+	}
+}
+
+func TestK8sCredential_ExpiresWithStoredExpiration(t *testing.T) {
+	launcher, _, permDialog := newK8sTestLauncher(t, map[string]string{
+		"K8S_CLIENT_CERTIFICATE": "cert",
+		"K8S_CLIENT_KEY":         "key",
+		"K8S_EXPIRATION":         "2026-10-18T12:01:00Z",
+	})
+	launcher.Now = func() time.Time { return time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC) }
+
+	permDialog.returnGranted = true
+	status, err := launcher.K8sCredential("prod", permissiondialog.CallerInfo{}, 10*time.Minute)
+
+	if err != nil || status.ClientCertificateData != "cert" || status.ClientKeyData != "key" {
+		t.Fatalf("expected client certificate, got %+v, %v", status, err)
+	}
+	if !status.ExpirationTimestamp.Equal(time.Date(2026, 10, 18, 12, 1, 0, 0, time.UTC)) {
+		t.Errorf("expected stored expiration, got %v", status.ExpirationTimestamp)
+	}
+}
+
+func TestK8sCredential_ChangedValuesVoidCachedApproval(t *testing.T) {
+	launcher, editDialog, permDialog := newK8sTestLauncher(t, map[string]string{"K8S_TOKEN": "token"})
+
+	permDialog.returnGranted = true
+	launcher.K8sCredential("prod", permissiondialog.CallerInfo{Name: "kubectl", Executable: "/usr/local/bin/kubectl"}, time.Hour)
+	editDialog.returnValues = map[string]string{"K8S_TOKEN": "rotated"}
+	launcher.EditEnvs("k8s:prod")
+	permDialog.returnGranted = false
+
+	if _, err := launcher.K8sCredential("prod", permissiondialog.CallerInfo{Name: "kubectl", Executable: "/usr/local/bin/kubectl"}, time.Hour); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("expected rotated credential to ask again, got %v", err)
+	}
+}
+
+func TestK8sCredential_FailsWithoutCredential(t *testing.T) {
+	launcher, _, permDialog := newK8sTestLauncher(t, map[string]string{"K8S_CLIENT_CERTIFICATE": "cert"})
+
+	_, err := launcher.K8sCredential("prod", permissiondialog.CallerInfo{}, 0)
+
+	if err == nil {
+		t.Error("expected error for certificate without key")
+	}
+	if permDialog.receivedAppPath != "" {
+		t.Error("expected no permission dialog")
+	}
+}
+
+func newK8sTestLauncher(t *testing.T, values map[string]string) (*Launcher, *stubEditDialog, *stubPermissionDialog) {
+	launcher, _, editDialog, permDialog := newTestLauncher(t)
+	launcher.Init()
+	editDialog.returnOk = true
+	editDialog.returnValues = values
+	launcher.EditEnvs("k8s:prod")
+	return launcher, editDialog, permDialog
+}
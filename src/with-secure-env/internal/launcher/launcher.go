@@ -1,17 +1,24 @@
 package launcher
 
 import (
-	"crypto/aes"
-	"crypto/cipher"
 	"crypto/rand"
-	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/kfischer-okarin/with-secure-env/internal/editdialog"
+	"github.com/kfischer-okarin/with-secure-env/internal/envcrypt"
+	"github.com/kfischer-okarin/with-secure-env/internal/envschema"
 	"github.com/kfischer-okarin/with-secure-env/internal/keychain"
 	"github.com/kfischer-okarin/with-secure-env/internal/permissiondialog"
+	"github.com/kfischer-okarin/with-secure-env/internal/secretgen"
 )
 
 type Launcher struct {
@@ -19,67 +26,287 @@ type Launcher struct {
 	EditDialog       editdialog.EditDialog
 	PermissionDialog permissiondialog.PermissionDialog
 	ConfigDirPath    string
-	Exec             func(path string, args []string, env []string) error
+	// Exec starts the application. If process.Supervised is false it replaces
+	// the current process and only returns on failure, otherwise it runs the
+	// application as child process and returns once it exited.
+	Exec func(process Process) (ExitStatus, error)
+	// SecretsDirBase is where directories for file delivered secrets are
+	// created. Defaults to the memory backed /dev/shm. Without it (e.g. on
+	// macOS) file delivery fails with ErrNoMemoryDir and fifos are created
+	// in os.TempDir().
+	SecretsDirBase string
+	// Caller is the process invoking with-secure-env, recorded in the audit
+	// log for commands that don't receive caller information themselves.
+	Caller permissiondialog.CallerInfo
+	// Now returns the current time. Defaults to time.Now.
+	Now func() time.Time
+	// Environ returns the environment of with-secure-env, which the launched
+	// application inherits. Defaults to os.Environ.
+	Environ func() []string
+	// WorkingDir returns the directory project files are searched from.
+	// Defaults to os.Getwd.
+	WorkingDir func() (string, error)
+	// LaunchAuthorizer replaces AuthorizeLaunch in launches, e.g. to let a
+	// running agent ask for permission and decrypt the envs.
+	LaunchAuthorizer func(applicationPath string, args []string, caller permissiondialog.CallerInfo, options LaunchOptions) (map[string]string, error)
+
+	// auditHashKey caches the key of the audit log hashes, see auditKey.
+	auditHashKey []byte
 }
 
 func (l *Launcher) Init() {
 	key := make([]byte, 32)
 	rand.Read(key)
-	l.Keychain.StoreEncryptionKey(key)
+	err := l.Keychain.StoreEncryptionKey(key)
+	l.auditHashKey = nil
+	l.audit(AuditEntry{Action: "init", Result: resultOf(err), Caller: callerChain(l.Caller)})
+}
+
+// Process describes how to start an application.
+type Process struct {
+	Path string
+	Args []string
+	// Env is the complete environment of the application, i.e. the inherited
+	// variables and the injected secrets.
+	Env []string
+	// Supervised runs the application as child process instead of replacing
+	// the with-secure-env process, so it can clean up after the exit.
+	Supervised bool
+	// Redact contains the secret values (by env name) to redact from the
+	// output of a supervised application.
+	Redact map[string]string
+}
+
+// ExitStatus describes how a supervised application exited.
+type ExitStatus struct {
+	Code int
+	// Signal is set if the application was terminated by a signal.
+	Signal syscall.Signal
+}
+
+func (s ExitStatus) String() string {
+	if s.Signal != 0 {
+		return "signal " + s.Signal.String()
+	}
+	return "exit code " + strconv.Itoa(s.Code)
+}
+
+// LaunchOptions adjust a single launch.
+type LaunchOptions struct {
+	// Supervised forces the supervised mode even if the application is not
+	// configured for it.
+	Supervised bool
+	// Profile selects the profile to inject instead of the application's
+	// default profile.
+	Profile string
+}
+
+// ErrPermissionDenied is returned by Launch when the user denied the launch.
+var ErrPermissionDenied = errors.New("permission denied")
+
+// Launch asks for permission and then starts the application with its envs.
+func (l *Launcher) Launch(applicationPath string, args []string, caller permissiondialog.CallerInfo) (ExitStatus, error) {
+	return l.LaunchWithOptions(applicationPath, args, caller, LaunchOptions{})
 }
 
-func (l *Launcher) Launch(applicationPath string, args []string, caller permissiondialog.CallerInfo) {
-	fileContent := l.loadFileContent()
-	encryptedEnvs := fileContent[applicationPath]
+// LaunchWithOptions is Launch with adjustments for this launch. It returns the
+// exit status with-secure-env should exit with; in exec mode it doesn't
+// return at all on success.
+func (l *Launcher) LaunchWithOptions(applicationPath string, args []string, caller permissiondialog.CallerInfo, options LaunchOptions) (ExitStatus, error) {
+	authorize := l.LaunchAuthorizer
+	if authorize == nil {
+		authorize = l.AuthorizeLaunch
+	}
+	values, err := authorize(applicationPath, args, caller, options)
+	if err != nil {
+		return ExitStatus{Code: 1}, err
+	}
+
+	settings := l.loadSettings()[l.resolveEntry(applicationPath)]
+	injected, secretsDir, err := l.deliverSecrets(values, settings)
+	if err != nil {
+		return ExitStatus{Code: 1}, err
+	}
+
+	process := Process{
+		Path:       applicationPath,
+		Args:       args,
+		Env:        buildEnv(l.inheritedEnv(settings), injected),
+		Supervised: options.Supervised || settings.LaunchMode == LaunchModeSupervised || secretsDir != "",
+	}
+	if process.Supervised && !settings.DisableRedaction {
+		process.Redact = values
+	}
+	if !process.Supervised {
+		if _, err := l.Exec(process); err != nil {
+			return ExitStatus{Code: 1}, err
+		}
+		return ExitStatus{}, nil
+	}
 
-	envNames := make([]string, 0, len(encryptedEnvs))
-	for name := range encryptedEnvs {
-		envNames = append(envNames, name)
+	status, err := l.Exec(process)
+	if secretsDir != "" {
+		os.RemoveAll(secretsDir)
 	}
+	exitEntry := AuditEntry{Action: "exit", Caller: callerChain(caller), App: applicationPath, Result: status.String()}
+	if err != nil {
+		exitEntry.Result = resultOf(err)
+		status = ExitStatus{Code: 1}
+	}
+	l.audit(exitEntry)
+	return status, err
+}
 
-	granted := l.PermissionDialog.AskPermission(applicationPath, args, envNames, caller)
+// AuthorizeLaunch checks the envs of a launch, asks for permission and
+// returns the resolved values by env name.
+func (l *Launcher) AuthorizeLaunch(applicationPath string, args []string, caller permissiondialog.CallerInfo, options LaunchOptions) (map[string]string, error) {
+	resolvedPath := l.resolveEntry(applicationPath)
+	settings := l.loadSettings()[resolvedPath]
+	profile := profileFor(settings, options.Profile)
+	if err := validateName("profile", profile); err != nil {
+		return nil, err
+	}
+	appEnvs := l.loadProfileEnvs(resolvedPath, profile)
+	encryptedEnvs := l.mergeGroupEnvs(settings, appEnvs)
+	project, projectWarnings := l.launchProject()
+	injectedEnvs := withProjectEnvs(encryptedEnvs, project)
+
+	envNames := sortedNames(injectedEnvs)
+	schema, err := l.launchSchema(applicationPath, settings, project)
+	if err != nil {
+		return nil, err
+	}
+	if missing := schema.Missing(envNames); len(missing) > 0 {
+		l.audit(AuditEntry{Action: "launch", Result: "missing", Caller: callerChain(caller), App: applicationPath, Entry: matchedPattern(resolvedPath), Profile: profile, Project: projectPath(project), Args: args, EnvNames: missing})
+		return nil, missingError(missing)
+	}
+	entry := AuditEntry{Action: "launch", Caller: callerChain(caller), App: applicationPath, Entry: matchedPattern(resolvedPath), Profile: profile, Project: projectPath(project), Args: args, EnvNames: envNames}
+	expired, err := l.checkEnvs(resolvedPath, profile, settings, appEnvs, envNames, entry)
+	if err != nil {
+		return nil, err
+	}
+	inherited := l.inheritedEnv(settings)
+
+	granted := l.PermissionDialog.AskPermission(permissiondialog.Request{
+		ApplicationPath: applicationPath,
+		Args:            args,
+		EnvNames:        envNames,
+		Caller:          caller,
+		Warnings:        slices.Concat(expired, projectWarnings, environmentWarnings(inherited, envNames, settings)),
+		Profile:         profile,
+		Production:      isProductionProfile(profile),
+		Groups:          settings.Groups,
+		MatchedEntry:    matchedPattern(resolvedPath),
+		Derived:         derivedNames(injectedEnvs),
+		Project:         projectPath(project),
+	})
 	if !granted {
-		return
+		entry.Result = "denied"
+		l.audit(entry)
+		return nil, ErrPermissionDenied
 	}
+	entry.Result = "granted"
+	l.audit(entry)
 
 	key, _ := l.Keychain.RetrieveEncryptionKey()
 
-	env := make([]string, 0, len(encryptedEnvs))
-	for name, encrypted := range encryptedEnvs {
-		env = append(env, name+"="+l.decrypt(key, encrypted))
+	scope := l.entryScope(key, settings, encryptedEnvs)
+	if project != nil {
+		projectScope, err := l.projectScope(project)
+		if err != nil {
+			return nil, err
+		}
+		scope = overrideScope(scope, projectScope)
 	}
-
-	l.Exec(applicationPath, args, env)
+	return l.resolveTemplates(key, scope, envNames)
 }
 
+// EditEnvs edits the envs of the default profile of the application.
 func (l *Launcher) EditEnvs(applicationPath string) {
+	l.EditProfile(applicationPath, "")
+}
+
+// EditProfile edits the envs of a profile of the application. An empty
+// profile selects the application's default profile.
+func (l *Launcher) EditProfile(applicationPath string, profile string) error {
+	applicationPath = l.resolveApplicationPath(applicationPath)
+	if err := validateEntry(applicationPath); err != nil {
+		return err
+	}
+	settings := l.loadSettings()[applicationPath]
+	profile = profileFor(settings, profile)
+	if err := validateName("profile", profile); err != nil {
+		return err
+	}
+	schema, err := l.appSchema(applicationPath, settings)
+	if err != nil {
+		return err
+	}
+	// Envs of groups satisfy required variables as well.
+	validate := validator(schema, sortedNames(l.mergeGroupEnvs(settings, nil)))
+
 	key, _ := l.Keychain.RetrieveEncryptionKey()
-	currentValues := l.loadEnvs(applicationPath, key)
+	currentEnvs := l.loadProfileEnvs(applicationPath, profile)
+	currentValues := l.decryptEnvs(key, currentEnvs)
+	scope := secretScope{Entry: applicationPath, Profile: profile}
 
-	newValues, ok := l.EditDialog.EditEnvs(applicationPath, currentValues)
+	result, ok := l.EditDialog.EditEnvs(editdialog.Request{
+		ApplicationPath: applicationPath,
+		Profile:         profile,
+		Values:          currentValues,
+		Metadata:        l.secretMetadata(scope),
+		Generate:        secretgen.Generate,
+		Validate:        validate,
+	})
+	entry := AuditEntry{Action: "edit", Caller: callerChain(l.Caller), App: applicationPath, Profile: profile}
 	if !ok {
-		return
+		entry.Result = "canceled"
+		l.audit(entry)
+		return nil
+	}
+	if problems := validate(result.Values); problems != nil {
+		entry.Result = "invalid"
+		l.audit(entry)
+		return envschema.Error(problems)
 	}
 
 	encryptedEnvs := make(map[string]string)
-	for envName, value := range newValues {
-		encryptedEnvs[envName] = l.encrypt(key, value)
+	for envName, value := range result.Values {
+		encryptedEnvs[envName] = l.encryptEdited(key, currentEnvs, currentValues, envName, value)
 	}
+	l.recordChanges(scope, changedNames(currentValues, result.Values), result.Values, result.Metadata)
+	l.saveProfileEnvs(applicationPath, profile, encryptedEnvs)
 
-	fileContent := l.loadFileContent()
-	fileContent[applicationPath] = encryptedEnvs
+	entry.Result = "saved"
+	entry.EnvNames = sortedNames(result.Values)
+	l.audit(entry)
+	return nil
+}
 
-	data, _ := json.Marshal(fileContent)
-	os.WriteFile(l.encryptedEnvsPath(), data, 0600)
+func (l *Launcher) now() time.Time {
+	if l.Now == nil {
+		return time.Now()
+	}
+	return l.Now()
 }
 
-func (l *Launcher) loadEnvs(applicationPath string, key []byte) map[string]string {
-	fileContent := l.loadFileContent()
-	encryptedEnvs := fileContent[applicationPath]
-	if encryptedEnvs == nil {
-		return map[string]string{}
+func resultOf(err error) string {
+	if err != nil {
+		return "failed: " + err.Error()
 	}
+	return "ok"
+}
 
+func sortedNames[V any](envs map[string]V) []string {
+	names := make([]string, 0, len(envs))
+	for name := range envs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (l *Launcher) decryptEnvs(key []byte, encryptedEnvs map[string]string) map[string]string {
 	decryptedEnvs := make(map[string]string)
 	for envName, encryptedValue := range encryptedEnvs {
 		decryptedEnvs[envName] = l.decrypt(key, encryptedValue)
@@ -89,32 +316,29 @@ func (l *Launcher) loadEnvs(applicationPath string, key []byte) map[string]strin
 
 func (l *Launcher) loadFileContent() map[string]map[string]string {
 	fileContent := map[string]map[string]string{}
-	data, _ := os.ReadFile(l.encryptedEnvsPath())
-	json.Unmarshal(data, &fileContent)
+	l.loadJSON("envs.json", &fileContent)
 	return fileContent
 }
 
-func (l *Launcher) encryptedEnvsPath() string {
-	return filepath.Join(l.ConfigDirPath, "envs.json")
+func (l *Launcher) saveFileContent(fileContent map[string]map[string]string) {
+	l.saveJSON("envs.json", fileContent)
 }
 
-func (l *Launcher) encrypt(key []byte, plaintext string) string {
-	block, _ := aes.NewCipher(key)
-	gcm, _ := cipher.NewGCM(block)
+func (l *Launcher) loadJSON(fileName string, v any) {
+	data, _ := os.ReadFile(filepath.Join(l.ConfigDirPath, fileName))
+	json.Unmarshal(data, v)
+}
 
-	nonce := make([]byte, gcm.NonceSize())
-	rand.Read(nonce)
+func (l *Launcher) saveJSON(fileName string, v any) {
+	data, _ := json.Marshal(v)
+	os.WriteFile(filepath.Join(l.ConfigDirPath, fileName), data, 0600)
+}
 
-	ciphertext := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
-	return base64.StdEncoding.EncodeToString(ciphertext)
+func (l *Launcher) encrypt(key []byte, plaintext string) string {
+	return envcrypt.Encrypt(key, plaintext)
 }
 
 func (l *Launcher) decrypt(key []byte, encryptedBase64 string) string {
-	data, _ := base64.StdEncoding.DecodeString(encryptedBase64)
-	block, _ := aes.NewCipher(key)
-	gcm, _ := cipher.NewGCM(block)
-	nonceSize := gcm.NonceSize()
-	nonce, ciphertext := data[:nonceSize], data[nonceSize:]
-	plaintext, _ := gcm.Open(nil, nonce, ciphertext, nil)
-	return string(plaintext)
+	plaintext, _ := envcrypt.Decrypt(key, strings.TrimPrefix(encryptedBase64, templatePrefix))
+	return plaintext
 }
@@ -7,9 +7,12 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"syscall"
 	"testing"
 
+	"github.com/kfischer-okarin/with-secure-env/internal/editdialog"
 	"github.com/kfischer-okarin/with-secure-env/internal/permissiondialog"
+	"github.com/kfischer-okarin/with-secure-env/internal/secretmeta"
 )
 
 func TestLauncherInit_StoresValidAESKey(t *testing.T) {
@@ -200,11 +203,11 @@ func TestLaunch_ExecutesAppWithEnvsAndArgs(t *testing.T) {
 	var executedPath string
 	var executedArgs []string
 	var executedEnv []string
-	launcher.Exec = func(path string, args []string, env []string) error {
-		executedPath = path
-		executedArgs = args
-		executedEnv = env
-		return nil
+	launcher.Exec = func(process Process) (ExitStatus, error) {
+		executedPath = process.Path
+		executedArgs = process.Args
+		executedEnv = process.Env
+		return ExitStatus{}, nil
 	}
 
 	permDialog.returnGranted = true
@@ -221,6 +224,33 @@ func TestLaunch_ExecutesAppWithEnvsAndArgs(t *testing.T) {
 	}
 }
 
+func TestLaunch_UsesLaunchAuthorizerInsteadOfKeychainAndDialog(t *testing.T) {
+	launcher, kc, _, permDialog := newTestLauncher(t)
+	var receivedApp string
+	var receivedOptions LaunchOptions
+	launcher.LaunchAuthorizer = func(applicationPath string, args []string, caller permissiondialog.CallerInfo, options LaunchOptions) (map[string]string, error) {
+		receivedApp, receivedOptions = applicationPath, options
+		return map[string]string{"API_KEY": "from-agent"}, nil
+	}
+	var executedEnv []string
+	launcher.Exec = func(process Process) (ExitStatus, error) {
+		executedEnv = process.Env
+		return ExitStatus{}, nil
+	}
+
+	launcher.LaunchWithOptions("/path/to/app", nil, permissiondialog.CallerInfo{}, LaunchOptions{Profile: "staging"})
+
+	if receivedApp != "/path/to/app" || receivedOptions.Profile != "staging" {
+		t.Errorf("expected launch to be authorized for /path/to/app with profile staging, got %s %+v", receivedApp, receivedOptions)
+	}
+	if !containsEnv(executedEnv, "API_KEY=from-agent") {
+		t.Errorf("expected authorized values to be injected, got %v", executedEnv)
+	}
+	if kc.retrieveCount != 0 || permDialog.receivedAppPath != "" {
+		t.Error("expected neither keychain access nor permission dialog")
+	}
+}
+
 func containsEnv(env []string, needle string) bool {
 	for _, e := range env {
 		if e == needle {
@@ -242,6 +272,8 @@ func newTestLauncher(t *testing.T) (*Launcher, *stubKeychain, *stubEditDialog, *
 		EditDialog:       editDialog,
 		PermissionDialog: permDialog,
 		ConfigDirPath:    tmpDir,
+		SecretsDirBase:   t.TempDir(),
+		WorkingDir:       func() (string, error) { return tmpDir, nil },
 	}
 
 	return launcher, kc, editDialog, permDialog
@@ -292,14 +324,17 @@ func (s *stubKeychain) RetrieveEncryptionKey() ([]byte, error) {
 type stubEditDialog struct {
 	receivedAppPath       string
 	receivedCurrentValues map[string]string
+	receivedRequest       editdialog.Request
 	returnValues          map[string]string
+	returnMetadata        map[string]secretmeta.Metadata
 	returnOk              bool
 }
 
-func (s *stubEditDialog) EditEnvs(applicationPath string, currentValues map[string]string) (map[string]string, bool) {
-	s.receivedAppPath = applicationPath
-	s.receivedCurrentValues = currentValues
-	return s.returnValues, s.returnOk
+func (s *stubEditDialog) EditEnvs(request editdialog.Request) (editdialog.Result, bool) {
+	s.receivedAppPath = request.ApplicationPath
+	s.receivedCurrentValues = request.Values
+	s.receivedRequest = request
+	return editdialog.Result{Values: s.returnValues, Metadata: s.returnMetadata}, s.returnOk
 }
 
 type stubPermissionDialog struct {
@@ -307,13 +342,139 @@ type stubPermissionDialog struct {
 	receivedArgs     []string
 	receivedEnvNames []string
 	receivedCaller   permissiondialog.CallerInfo
+	receivedRequest  permissiondialog.Request
 	returnGranted    bool
 }
 
-func (s *stubPermissionDialog) AskPermission(applicationPath string, args []string, envNames []string, caller permissiondialog.CallerInfo) bool {
-	s.receivedAppPath = applicationPath
-	s.receivedArgs = args
-	s.receivedEnvNames = envNames
-	s.receivedCaller = caller
+func (s *stubPermissionDialog) AskPermission(request permissiondialog.Request) bool {
+	s.receivedAppPath = request.ApplicationPath
+	s.receivedArgs = request.Args
+	s.receivedEnvNames = request.EnvNames
+	s.receivedCaller = request.Caller
+	s.receivedRequest = request
 	return s.returnGranted
 }
+
+func TestLaunch_ExecsByDefault(t *testing.T) {
+	launcher, _, _, permDialog := newTestLauncher(t)
+	launcher.Init()
+
+	var executed Process
+	launcher.Exec = func(process Process) (ExitStatus, error) {
+		executed = process
+		return ExitStatus{}, nil
+	}
+	permDialog.returnGranted = true
+	launcher.Launch("/path/to/app", nil, permissiondialog.CallerInfo{})
+
+	if executed.Supervised {
+		t.Error("expected exec mode by default")
+	}
+}
+
+func TestLaunch_SupervisesWhenConfigured(t *testing.T) {
+	launcher, _, _, permDialog := newTestLauncher(t)
+	launcher.Init()
+	launcher.Configure("/path/to/app", "launchMode", "supervised")
+
+	var executed Process
+	launcher.Exec = func(process Process) (ExitStatus, error) {
+		executed = process
+		return ExitStatus{Code: 130, Signal: syscall.SIGINT}, nil
+	}
+	permDialog.returnGranted = true
+	status, _ := launcher.Launch("/path/to/app", nil, permissiondialog.CallerInfo{})
+
+	if !executed.Supervised {
+		t.Error("expected supervised mode")
+	}
+	if status.Signal != syscall.SIGINT {
+		t.Errorf("expected exit status of the application, got %v", status)
+	}
+}
+
+func TestLaunch_SupervisesWhenRequestedForLaunch(t *testing.T) {
+	launcher, _, _, permDialog := newTestLauncher(t)
+	launcher.Init()
+
+	var executed Process
+	launcher.Exec = func(process Process) (ExitStatus, error) {
+		executed = process
+		return ExitStatus{}, nil
+	}
+	permDialog.returnGranted = true
+	launcher.LaunchWithOptions("/path/to/app", nil, permissiondialog.CallerInfo{}, LaunchOptions{Supervised: true})
+
+	if !executed.Supervised {
+		t.Error("expected supervised mode")
+	}
+}
+
+func TestLaunch_RecordsExitOfSupervisedApplication(t *testing.T) {
+	launcher, _, _, permDialog := newTestLauncher(t)
+	launcher.Init()
+
+	launcher.Exec = func(process Process) (ExitStatus, error) {
+		return ExitStatus{Code: 2}, nil
+	}
+	permDialog.returnGranted = true
+	launcher.LaunchWithOptions("/path/to/app", nil, permissiondialog.CallerInfo{}, LaunchOptions{Supervised: true})
+
+	entries, _ := launcher.AuditEntries(AuditFilter{Action: "exit"})
+	if len(entries) != 1 || entries[0].Result != "exit code 2" {
+		t.Errorf("expected exit entry with 'exit code 2', got %+v", entries)
+	}
+}
+
+func TestLaunch_ReturnsErrorWhenPermissionDenied(t *testing.T) {
+	launcher, _, _, permDialog := newTestLauncher(t)
+	launcher.Init()
+
+	permDialog.returnGranted = false
+	status, err := launcher.Launch("/path/to/app", nil, permissiondialog.CallerInfo{})
+
+	if err != ErrPermissionDenied || status.Code == 0 {
+		t.Errorf("expected permission denied error and non-zero exit code, got %v, %v", status, err)
+	}
+}
+
+func TestLaunch_RedactsSecretsFromSupervisedOutput(t *testing.T) {
+	launcher, _, editDialog, permDialog := newTestLauncher(t)
+	launcher.Init()
+	editDialog.returnValues = map[string]string{"API_KEY": "secretkey"}
+	editDialog.returnOk = true
+	launcher.EditEnvs("/path/to/app")
+
+	var executed Process
+	launcher.Exec = func(process Process) (ExitStatus, error) {
+		executed = process
+		return ExitStatus{}, nil
+	}
+	permDialog.returnGranted = true
+	launcher.LaunchWithOptions("/path/to/app", nil, permissiondialog.CallerInfo{}, LaunchOptions{Supervised: true})
+
+	if executed.Redact["API_KEY"] != "secretkey" {
+		t.Errorf("expected API_KEY to be redacted, got %v", executed.Redact)
+	}
+}
+
+func TestLaunch_DoesNotRedactWhenDisabled(t *testing.T) {
+	launcher, _, editDialog, permDialog := newTestLauncher(t)
+	launcher.Init()
+	editDialog.returnValues = map[string]string{"API_KEY": "secretkey"}
+	editDialog.returnOk = true
+	launcher.EditEnvs("/path/to/app")
+	launcher.Configure("/path/to/app", "redactOutput", "false")
+
+	var executed Process
+	launcher.Exec = func(process Process) (ExitStatus, error) {
+		executed = process
+		return ExitStatus{}, nil
+	}
+	permDialog.returnGranted = true
+	launcher.LaunchWithOptions("/path/to/app", nil, permissiondialog.CallerInfo{}, LaunchOptions{Supervised: true})
+
+	if executed.Redact != nil {
+		t.Errorf("expected no redaction, got %v", executed.Redact)
+	}
+}
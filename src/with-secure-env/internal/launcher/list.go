@@ -0,0 +1,58 @@
+package launcher
+
+import (
+	"sort"
+
+	"github.com/kfischer-okarin/with-secure-env/internal/secretmeta"
+)
+
+// ApplicationInfo describes a configured application without revealing
+// values.
+type ApplicationInfo struct {
+	Path string
+	// Profiles maps profile names to their env names.
+	Profiles map[string][]string
+	Groups   []string
+	// Metadata maps profile names to the metadata of their envs.
+	Metadata map[string]map[string]secretmeta.Metadata
+}
+
+// Applications returns all applications with envs or groups, sorted by path.
+func (l *Launcher) Applications() []ApplicationInfo {
+	apps := map[string]*ApplicationInfo{}
+	get := func(path string) *ApplicationInfo {
+		if apps[path] == nil {
+			apps[path] = &ApplicationInfo{Path: path, Profiles: map[string][]string{}, Metadata: map[string]map[string]secretmeta.Metadata{}}
+		}
+		return apps[path]
+	}
+
+	for path, encryptedEnvs := range l.loadFileContent() {
+		get(path).Profiles[DefaultProfile] = sortedNames(encryptedEnvs)
+	}
+	for path, profiles := range l.loadProfiles() {
+		for profile, encryptedEnvs := range profiles {
+			get(path).Profiles[profile] = sortedNames(encryptedEnvs)
+		}
+	}
+	for path, settings := range l.loadSettings() {
+		if len(settings.Groups) > 0 {
+			get(path).Groups = settings.Groups
+		}
+	}
+
+	for path, profiles := range l.loadMetadata().Entries {
+		if app, ok := apps[path]; ok {
+			for profile, metadata := range profiles {
+				app.Metadata[profile] = metadata
+			}
+		}
+	}
+
+	var result []ApplicationInfo
+	for _, app := range apps {
+		result = append(result, *app)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Path < result[j].Path })
+	return result
+}
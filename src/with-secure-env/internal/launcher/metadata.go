@@ -0,0 +1,180 @@
+package launcher
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/kfischer-okarin/with-secure-env/internal/secretmeta"
+)
+
+// ErrSecretExpired is returned by launches of applications configured with
+// blockExpired when an injected secret has expired.
+var ErrSecretExpired = errors.New("secret expired")
+
+// secretScope identifies the envs metadata and history belong to: a profile
+// of an entry or a shared group.
+type secretScope struct {
+	Entry   string
+	Profile string
+	Group   string
+}
+
+// scopedFile holds values by env name for every scope, the layout of
+// metadata.json and history.json.
+type scopedFile[V any] struct {
+	// Entries maps entry keys to profiles to env names.
+	Entries map[string]map[string]map[string]V `json:"entries,omitempty"`
+	Groups  map[string]map[string]V            `json:"groups,omitempty"`
+}
+
+// get returns the values of scope, never nil.
+func (f scopedFile[V]) get(scope secretScope) map[string]V {
+	var values map[string]V
+	if scope.Group != "" {
+		values = f.Groups[scope.Group]
+	} else {
+		values = f.Entries[scope.Entry][scope.Profile]
+	}
+	if values == nil {
+		values = map[string]V{}
+	}
+	return values
+}
+
+// set replaces the values of scope, dropping empty scopes.
+func (f *scopedFile[V]) set(scope secretScope, values map[string]V) {
+	if scope.Group != "" {
+		if f.Groups == nil {
+			f.Groups = map[string]map[string]V{}
+		}
+		f.Groups[scope.Group] = values
+		if len(values) == 0 {
+			delete(f.Groups, scope.Group)
+		}
+		return
+	}
+	if f.Entries == nil {
+		f.Entries = map[string]map[string]map[string]V{}
+	}
+	if f.Entries[scope.Entry] == nil {
+		f.Entries[scope.Entry] = map[string]map[string]V{}
+	}
+	f.Entries[scope.Entry][scope.Profile] = values
+	if len(values) == 0 {
+		delete(f.Entries[scope.Entry], scope.Profile)
+	}
+	if len(f.Entries[scope.Entry]) == 0 {
+		delete(f.Entries, scope.Entry)
+	}
+}
+
+// secretMetadata returns the metadata of the envs in scope by env name.
+func (l *Launcher) secretMetadata(scope secretScope) map[string]secretmeta.Metadata {
+	return l.loadMetadata().get(scope)
+}
+
+// recordChanges updates the timestamps of the changed envs in scope and drops
+// the metadata of envs that no longer exist. edited replaces the
+// user-editable fields if not nil. The stored values of changed and removed
+// envs are archived, so it has to be called before saving the new values.
+func (l *Launcher) recordChanges(scope secretScope, changed []string, remaining map[string]string, edited map[string]secretmeta.Metadata) {
+	archived := append([]string(nil), changed...)
+	for name := range l.scopeEnvs(scope) {
+		if _, ok := remaining[name]; !ok {
+			archived = append(archived, name)
+		}
+	}
+	l.archiveValues(scope, archived)
+
+	metadata := l.secretMetadata(scope)
+	for name := range metadata {
+		if _, ok := remaining[name]; !ok {
+			delete(metadata, name)
+		}
+	}
+	for name, fields := range edited {
+		if _, ok := remaining[name]; !ok {
+			continue
+		}
+		current := metadata[name]
+		current.Description, current.Source, current.Expires = fields.Description, fields.Source, fields.Expires
+		metadata[name] = current
+	}
+	now := l.now().UTC()
+	for _, name := range changed {
+		current := metadata[name]
+		if current.Created.IsZero() {
+			current.Created = now
+		}
+		current.Updated = now
+		metadata[name] = current
+	}
+	for name, current := range metadata {
+		if current.IsZero() {
+			delete(metadata, name)
+		}
+	}
+	l.saveSecretMetadata(scope, metadata)
+}
+
+func (l *Launcher) saveSecretMetadata(scope secretScope, metadata map[string]secretmeta.Metadata) {
+	file := l.loadMetadata()
+	file.set(scope, metadata)
+	l.saveMetadata(file)
+}
+
+// changedNames returns the names whose values differ between old and new.
+func changedNames(oldValues map[string]string, newValues map[string]string) []string {
+	var changed []string
+	for _, name := range sortedNames(newValues) {
+		if oldValue, ok := oldValues[name]; !ok || oldValue != newValues[name] {
+			changed = append(changed, name)
+		}
+	}
+	return changed
+}
+
+// injectedMetadata returns the metadata of the envs injected into a launch,
+// taking each env's metadata from where its value comes from: the
+// application's profile or the last group providing it.
+func (l *Launcher) injectedMetadata(resolvedPath string, profile string, settings AppSettings, appEnvs map[string]string) map[string]secretmeta.Metadata {
+	result := map[string]secretmeta.Metadata{}
+	groups := l.loadGroups()
+	for _, group := range settings.Groups {
+		groupMetadata := l.secretMetadata(secretScope{Group: group})
+		for name := range groups[group] {
+			result[name] = groupMetadata[name]
+		}
+	}
+	appMetadata := l.secretMetadata(secretScope{Entry: resolvedPath, Profile: profile})
+	for name := range appEnvs {
+		result[name] = appMetadata[name]
+	}
+	return result
+}
+
+// expiredSecrets describes the expired secrets among metadata, sorted by name.
+func (l *Launcher) expiredSecrets(metadata map[string]secretmeta.Metadata) []string {
+	var expired []string
+	for _, name := range sortedNames(metadata) {
+		if metadata[name].Expired(l.now()) {
+			expired = append(expired, fmt.Sprintf("%s expired on %s", name, metadata[name].Expires.Format("2006-01-02")))
+		}
+	}
+	return expired
+}
+
+func expiredError(expired []string) error {
+	return fmt.Errorf("%w: %s (configure blockExpired false to allow)", ErrSecretExpired, strings.Join(expired, ", "))
+}
+
+func (l *Launcher) loadMetadata() scopedFile[secretmeta.Metadata] {
+	var file scopedFile[secretmeta.Metadata]
+	l.loadJSON("metadata.json", &file)
+	return file
+}
+
+func (l *Launcher) saveMetadata(file scopedFile[secretmeta.Metadata]) {
+	l.saveJSON("metadata.json", file)
+}
@@ -0,0 +1,180 @@
+package launcher
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kfischer-okarin/with-secure-env/internal/permissiondialog"
+	"github.com/kfischer-okarin/with-secure-env/internal/secretmeta"
+)
+
+func TestEditEnvs_UpdatesTimestampsOfChangedValues(t *testing.T) {
+	launcher, _, editDialog, _ := newTestLauncher(t)
+	launcher.Init()
+	created := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	launcher.Now = func() time.Time { return created }
+	editDialog.returnOk = true
+	editDialog.returnValues = map[string]string{"API_KEY": "one", "DB_PASS": "secret"}
+	launcher.EditEnvs("/path/to/app")
+
+	updated := created.AddDate(0, 1, 0)
+	launcher.Now = func() time.Time { return updated }
+	editDialog.returnValues = map[string]string{"API_KEY": "two", "DB_PASS": "secret"}
+	launcher.EditEnvs("/path/to/app")
+
+	metadata := editDialog.receivedRequest.Metadata
+	if !metadata["API_KEY"].Created.Equal(created) {
+		t.Errorf("expected API_KEY to be shown with its creation time, got %v", metadata["API_KEY"])
+	}
+	stored := launcher.secretMetadata(secretScope{Entry: "/path/to/app", Profile: "default"})
+	if !stored["API_KEY"].Created.Equal(created) || !stored["API_KEY"].Updated.Equal(updated) {
+		t.Errorf("expected API_KEY created %v and updated %v, got %v", created, updated, stored["API_KEY"])
+	}
+	if !stored["DB_PASS"].Updated.Equal(created) {
+		t.Errorf("expected unchanged DB_PASS to keep its update time, got %v", stored["DB_PASS"])
+	}
+}
+
+func TestEditEnvs_SavesEditedMetadata(t *testing.T) {
+	launcher, _, editDialog, _ := newTestLauncher(t)
+	launcher.Init()
+	expires := time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC)
+	editDialog.returnOk = true
+	editDialog.returnValues = map[string]string{"API_KEY": "one"}
+	editDialog.returnMetadata = map[string]secretmeta.Metadata{
+		"API_KEY": {Description: "Deploy token", Source: "https://example.com/tokens", Expires: expires},
+		"REMOVED": {Description: "not saved"},
+	}
+
+	launcher.EditEnvs("/path/to/app")
+
+	stored := launcher.secretMetadata(secretScope{Entry: "/path/to/app", Profile: "default"})
+	if m := stored["API_KEY"]; m.Description != "Deploy token" || m.Source != "https://example.com/tokens" || !m.Expires.Equal(expires) {
+		t.Errorf("expected edited metadata to be saved, got %v", m)
+	}
+	if _, ok := stored["REMOVED"]; ok {
+		t.Error("expected metadata of nonexistent env not to be saved")
+	}
+}
+
+func TestLaunch_WarnsAboutExpiredSecrets(t *testing.T) {
+	launcher, _, editDialog, permDialog := newTestLauncher(t)
+	launcher.Init()
+	launcher.Now = func() time.Time { return time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC) }
+	editDialog.returnOk = true
+	editDialog.returnValues = map[string]string{"API_KEY": "one"}
+	editDialog.returnMetadata = map[string]secretmeta.Metadata{"API_KEY": {Expires: time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)}}
+	launcher.EditEnvs("/path/to/app")
+	launcher.Exec = func(process Process) (ExitStatus, error) { return ExitStatus{}, nil }
+
+	permDialog.returnGranted = true
+	_, err := launcher.Launch("/path/to/app", nil, permissiondialog.CallerInfo{})
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	warnings := permDialog.receivedRequest.Warnings
+	if len(warnings) == 0 || !strings.Contains(warnings[0], "API_KEY expired on 2026-10-01") {
+		t.Errorf("expected expiry warning, got %v", warnings)
+	}
+}
+
+func TestLaunch_BlocksExpiredSecretsIfConfigured(t *testing.T) {
+	launcher, _, editDialog, permDialog := newTestLauncher(t)
+	launcher.Init()
+	launcher.Now = func() time.Time { return time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC) }
+	editDialog.returnOk = true
+	editDialog.returnValues = map[string]string{"API_KEY": "one"}
+	editDialog.returnMetadata = map[string]secretmeta.Metadata{"API_KEY": {Expires: time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)}}
+	launcher.EditEnvs("/path/to/app")
+	launcher.Configure("/path/to/app", "blockExpired", "true")
+	executed := false
+	launcher.Exec = func(process Process) (ExitStatus, error) {
+		executed = true
+		return ExitStatus{}, nil
+	}
+
+	permDialog.returnGranted = true
+	_, err := launcher.Launch("/path/to/app", nil, permissiondialog.CallerInfo{})
+
+	if !errors.Is(err, ErrSecretExpired) {
+		t.Errorf("expected ErrSecretExpired, got %v", err)
+	}
+	if executed || permDialog.receivedAppPath != "" {
+		t.Error("expected launch to be blocked before asking for permission")
+	}
+}
+
+func TestExportFetchShare_BlockExpiredSecretsIfConfigured(t *testing.T) {
+	launcher, _, editDialog, permDialog := newTestLauncher(t)
+	launcher.Init()
+	launcher.Now = func() time.Time { return time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC) }
+	editDialog.returnOk = true
+	editDialog.returnValues = map[string]string{"API_KEY": "one", "PORT": "80"}
+	editDialog.returnMetadata = map[string]secretmeta.Metadata{"API_KEY": {Expires: time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)}}
+	launcher.EditEnvs("/path/to/app")
+	launcher.Configure("/path/to/app", "blockExpired", "true")
+	identity, _ := launcher.Identity()
+	permDialog.returnGranted = true
+
+	if _, err := launcher.Export("/path/to/app", permissiondialog.CallerInfo{}, ExportOptions{Format: "dotenv"}); !errors.Is(err, ErrSecretExpired) {
+		t.Errorf("expected export to be blocked, got %v", err)
+	}
+	if _, err := launcher.Fetch("/path/to/app", []string{"API_KEY"}, permissiondialog.CallerInfo{}); !errors.Is(err, ErrSecretExpired) {
+		t.Errorf("expected fetch to be blocked, got %v", err)
+	}
+	if _, err := launcher.Share("/path/to/app", permissiondialog.CallerInfo{}, ShareOptions{Recipients: []string{identity.Recipient}}); !errors.Is(err, ErrSecretExpired) {
+		t.Errorf("expected share to be blocked, got %v", err)
+	}
+	if permDialog.receivedAppPath != "" {
+		t.Error("expected requests to be blocked before asking for permission")
+	}
+	if values, err := launcher.Fetch("/path/to/app", []string{"PORT"}, permissiondialog.CallerInfo{}); err != nil || values["PORT"] != "80" {
+		t.Errorf("expected fetch of unexpired secret to work, got %v, %v", values, err)
+	}
+	entries, _ := launcher.AuditEntries(AuditFilter{})
+	expired := 0
+	for _, entry := range entries {
+		if entry.Result == "expired" {
+			expired++
+		}
+	}
+	if expired != 3 {
+		t.Errorf("expected 3 expired audit entries, got %d", expired)
+	}
+}
+
+func TestMove_KeepsMetadata(t *testing.T) {
+	launcher, _, editDialog, _ := newTestLauncher(t)
+	launcher.Init()
+	editDialog.returnOk = true
+	editDialog.returnValues = map[string]string{"API_KEY": "one"}
+	editDialog.returnMetadata = map[string]secretmeta.Metadata{"API_KEY": {Description: "Deploy token"}}
+	launcher.EditEnvs("/old/app")
+
+	launcher.Move("/old/app", "/new/app")
+
+	if m := launcher.secretMetadata(secretScope{Entry: "/new/app", Profile: "default"}); m["API_KEY"].Description != "Deploy token" {
+		t.Errorf("expected metadata to move, got %v", m)
+	}
+	if m := launcher.secretMetadata(secretScope{Entry: "/old/app", Profile: "default"}); len(m) != 0 {
+		t.Errorf("expected no metadata left for old path, got %v", m)
+	}
+}
+
+func TestGroups_IncludesMetadata(t *testing.T) {
+	launcher, _, editDialog, _ := newTestLauncher(t)
+	launcher.Init()
+	editDialog.returnOk = true
+	editDialog.returnValues = map[string]string{"PASSWORD": "secret"}
+	editDialog.returnMetadata = map[string]secretmeta.Metadata{"PASSWORD": {Source: "vault"}}
+	launcher.EditGroup("db")
+
+	groups := launcher.Groups()
+
+	if len(groups) != 1 || groups[0].Metadata["PASSWORD"].Source != "vault" {
+		t.Errorf("expected group metadata, got %v", groups)
+	}
+}
@@ -0,0 +1,125 @@
+package launcher
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// DefaultProfile is the profile used when an application has no configured
+// default profile. Its envs are stored in envs.json, all other profiles in
+// profiles.json.
+const DefaultProfile = "default"
+
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// Profiles returns the names of all profiles of the application that have
+// envs.
+func (l *Launcher) Profiles(applicationPath string) []string {
+	applicationPath = l.resolveApplicationPath(applicationPath)
+
+	var profiles []string
+	if _, ok := l.loadFileContent()[applicationPath]; ok {
+		profiles = append(profiles, DefaultProfile)
+	}
+	for profile := range l.loadProfiles()[applicationPath] {
+		profiles = append(profiles, profile)
+	}
+	sort.Strings(profiles)
+	return profiles
+}
+
+// hasEnvs reports whether envs of any profile are stored under the path,
+// without resolving aliases.
+func (l *Launcher) hasEnvs(path string) bool {
+	if _, ok := l.loadFileContent()[path]; ok {
+		return true
+	}
+	_, ok := l.loadProfiles()[path]
+	return ok
+}
+
+// profileFor returns the profile to use when requested is not specified.
+func profileFor(settings AppSettings, requested string) string {
+	if requested != "" {
+		return requested
+	}
+	if settings.DefaultProfile != "" {
+		return settings.DefaultProfile
+	}
+	return DefaultProfile
+}
+
+// isProductionProfile reports whether the profile holds production
+// credentials, which the permission dialog flags.
+func isProductionProfile(profile string) bool {
+	name := strings.ToLower(profile)
+	return name == "prod" || name == "production" || strings.HasPrefix(name, "prod-") || strings.HasPrefix(name, "production-")
+}
+
+// validateName checks profile and group names, which must be safe to show
+// and to use as keys.
+func validateName(kind string, name string) error {
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("invalid %s name %q (letters, digits, '_', '.' and '-' only)", kind, name)
+	}
+	return nil
+}
+
+// profileEnvs returns the encrypted envs of all profiles stored under path,
+// without resolving aliases.
+func (l *Launcher) profileEnvs(path string) map[string]map[string]string {
+	envs := map[string]map[string]string{}
+	if defaultEnvs, ok := l.loadFileContent()[path]; ok {
+		envs[DefaultProfile] = defaultEnvs
+	}
+	for profile, encryptedEnvs := range l.loadProfiles()[path] {
+		envs[profile] = encryptedEnvs
+	}
+	return envs
+}
+
+// profileEnvNames returns the sorted env names of all profiles.
+func profileEnvNames(profiles map[string]map[string]string) []string {
+	names := map[string]bool{}
+	for _, encryptedEnvs := range profiles {
+		for name := range encryptedEnvs {
+			names[name] = true
+		}
+	}
+	return sortedNames(names)
+}
+
+func (l *Launcher) loadProfileEnvs(applicationPath string, profile string) map[string]string {
+	if profile == DefaultProfile {
+		return l.loadFileContent()[applicationPath]
+	}
+	return l.loadProfiles()[applicationPath][profile]
+}
+
+func (l *Launcher) saveProfileEnvs(applicationPath string, profile string, encryptedEnvs map[string]string) {
+	if profile == DefaultProfile {
+		fileContent := l.loadFileContent()
+		fileContent[applicationPath] = encryptedEnvs
+		l.saveFileContent(fileContent)
+		return
+	}
+
+	profiles := l.loadProfiles()
+	if profiles[applicationPath] == nil {
+		profiles[applicationPath] = map[string]map[string]string{}
+	}
+	profiles[applicationPath][profile] = encryptedEnvs
+	l.saveProfiles(profiles)
+}
+
+func (l *Launcher) loadProfiles() map[string]map[string]map[string]string {
+	profiles := map[string]map[string]map[string]string{}
+	l.loadJSON("profiles.json", &profiles)
+	return profiles
+}
+
+func (l *Launcher) saveProfiles(profiles map[string]map[string]map[string]string) {
+	l.saveJSON("profiles.json", profiles)
+}
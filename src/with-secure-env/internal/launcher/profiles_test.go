@@ -0,0 +1,132 @@
+package launcher
+
+import (
+	"testing"
+
+	"github.com/kfischer-okarin/with-secure-env/internal/permissiondialog"
+)
+
+func TestEditProfile_KeepsProfilesSeparate(t *testing.T) {
+	launcher, _, editDialog, _ := newTestLauncher(t)
+	launcher.Init()
+	editDialog.returnOk = true
+	editDialog.returnValues = map[string]string{"API_KEY": "dev-secret"}
+	launcher.EditEnvs("/path/to/app")
+	editDialog.returnValues = map[string]string{"API_KEY": "prod-secret"}
+	launcher.EditProfile("/path/to/app", "prod")
+
+	editDialog.returnOk = false
+	launcher.EditProfile("/path/to/app", "prod")
+
+	if editDialog.receivedRequest.Profile != "prod" {
+		t.Errorf("expected profile 'prod' in edit request, got '%s'", editDialog.receivedRequest.Profile)
+	}
+	if editDialog.receivedCurrentValues["API_KEY"] != "prod-secret" {
+		t.Errorf("expected 'prod-secret', got %v", editDialog.receivedCurrentValues)
+	}
+	launcher.EditEnvs("/path/to/app")
+	if editDialog.receivedCurrentValues["API_KEY"] != "dev-secret" {
+		t.Errorf("expected 'dev-secret' in default profile, got %v", editDialog.receivedCurrentValues)
+	}
+	profiles := launcher.Profiles("/path/to/app")
+	if len(profiles) != 2 || profiles[0] != "default" || profiles[1] != "prod" {
+		t.Errorf("expected profiles [default prod], got %v", profiles)
+	}
+}
+
+func TestLaunch_InjectsRequestedProfile(t *testing.T) {
+	launcher, _, editDialog, permDialog := newTestLauncher(t)
+	launcher.Init()
+	editDialog.returnOk = true
+	editDialog.returnValues = map[string]string{"API_KEY": "dev-secret"}
+	launcher.EditEnvs("/path/to/app")
+	editDialog.returnValues = map[string]string{"API_KEY": "staging-secret"}
+	launcher.EditProfile("/path/to/app", "staging")
+
+	var executedEnv []string
+	launcher.Exec = func(process Process) (ExitStatus, error) {
+		executedEnv = process.Env
+		return ExitStatus{}, nil
+	}
+	permDialog.returnGranted = true
+	launcher.LaunchWithOptions("/path/to/app", nil, permissiondialog.CallerInfo{}, LaunchOptions{Profile: "staging"})
+
+	if !containsEnv(executedEnv, "API_KEY=staging-secret") {
+		t.Errorf("expected API_KEY=staging-secret, got %v", executedEnv)
+	}
+	if permDialog.receivedRequest.Profile != "staging" || permDialog.receivedRequest.Production {
+		t.Errorf("expected non-production profile 'staging', got %+v", permDialog.receivedRequest)
+	}
+}
+
+func TestLaunch_UsesConfiguredDefaultProfile(t *testing.T) {
+	launcher, _, editDialog, permDialog := newTestLauncher(t)
+	launcher.Init()
+	editDialog.returnOk = true
+	editDialog.returnValues = map[string]string{"API_KEY": "dev-secret"}
+	launcher.EditEnvs("/path/to/app")
+	editDialog.returnValues = map[string]string{"API_KEY": "staging-secret"}
+	launcher.EditProfile("/path/to/app", "staging")
+
+	launcher.Configure("/path/to/app", "defaultProfile", "staging")
+	env := launchAndCaptureEnv(launcher, permDialog)
+
+	if !containsEnv(env, "API_KEY=staging-secret") {
+		t.Errorf("expected API_KEY=staging-secret, got %v", env)
+	}
+}
+
+func TestLaunch_FlagsProductionProfile(t *testing.T) {
+	launcher, _, editDialog, permDialog := newTestLauncher(t)
+	launcher.Init()
+	editDialog.returnOk = true
+	editDialog.returnValues = map[string]string{"API_KEY": "prod-secret"}
+	launcher.EditProfile("/path/to/app", "prod")
+
+	permDialog.returnGranted = false
+	launcher.LaunchWithOptions("/path/to/app", nil, permissiondialog.CallerInfo{}, LaunchOptions{Profile: "prod"})
+
+	if !permDialog.receivedRequest.Production {
+		t.Error("expected production profile to be flagged")
+	}
+	entries, _ := launcher.AuditEntries(AuditFilter{Action: "launch"})
+	if len(entries) != 1 || entries[0].Profile != "prod" {
+		t.Errorf("expected launch audit entry for profile 'prod', got %+v", entries)
+	}
+}
+
+func TestLaunch_RejectsInvalidProfileName(t *testing.T) {
+	launcher, _, _, permDialog := newTestLauncher(t)
+	launcher.Init()
+
+	_, err := launcher.LaunchWithOptions("/path/to/app", nil, permissiondialog.CallerInfo{}, LaunchOptions{Profile: "../prod"})
+
+	if err == nil {
+		t.Error("expected error for invalid profile name")
+	}
+	if permDialog.receivedAppPath != "" {
+		t.Error("expected no permission dialog for invalid profile name")
+	}
+}
+
+func TestMove_MovesAllProfiles(t *testing.T) {
+	launcher, _, editDialog, _ := newTestLauncher(t)
+	launcher.Init()
+	editDialog.returnOk = true
+	editDialog.returnValues = map[string]string{"API_KEY": "prod-secret"}
+	launcher.EditProfile("/old/app", "prod")
+
+	err := launcher.Move("/old/app", "/new/app")
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	editDialog.returnOk = false
+	launcher.EditProfile("/new/app", "prod")
+	if editDialog.receivedCurrentValues["API_KEY"] != "prod-secret" {
+		t.Errorf("expected API_KEY 'prod-secret' at new path, got %v", editDialog.receivedCurrentValues)
+	}
+	if profiles := launcher.Profiles("/old/app"); len(profiles) != 0 {
+		t.Errorf("expected no profiles at old path, got %v", profiles)
+	}
+}
@@ -0,0 +1,347 @@
+package launcher
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/kfischer-okarin/with-secure-env/internal/bundle"
+	"github.com/kfischer-okarin/with-secure-env/internal/editdialog"
+	"github.com/kfischer-okarin/with-secure-env/internal/envcrypt"
+	"github.com/kfischer-okarin/with-secure-env/internal/envschema"
+	"github.com/kfischer-okarin/with-secure-env/internal/envtemplate"
+	"github.com/kfischer-okarin/with-secure-env/internal/projectfile"
+	"github.com/kfischer-okarin/with-secure-env/internal/secretgen"
+)
+
+// ErrNoProject is returned when no project file is found from the working
+// directory.
+var ErrNoProject = errors.New("no " + projectfile.FileName + " found in this directory or its parents")
+
+// ProjectInfo describes the project file found from the working directory.
+type ProjectInfo struct {
+	Path       string
+	Recipients []string
+	EnvNames   []string
+	// Trusted is set if launches inject the project's envs.
+	Trusted bool
+}
+
+// project is a parsed project file.
+type project struct {
+	Path string
+	File projectfile.File
+}
+
+// ProjectInit creates a project file in the working directory, encrypted for
+// the local identity and recipients, and trusts it.
+func (l *Launcher) ProjectInit(recipients []string) (string, error) {
+	dir, err := l.workingDir()
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, projectfile.FileName)
+	if _, err := os.Stat(path); err == nil {
+		return "", fmt.Errorf("%s already exists", path)
+	}
+	identity, err := l.Identity()
+	if err != nil {
+		return "", err
+	}
+
+	dataKey := make([]byte, 32)
+	rand.Read(dataKey)
+	file := projectfile.File{KeyID: keyID(dataKey), Recipients: map[string]string{}, Values: map[string]string{}}
+	for _, recipient := range append([]string{identity.Recipient}, recipients...) {
+		if err := addRecipient(&file, dataKey, recipient); err != nil {
+			return "", err
+		}
+	}
+	if err := os.WriteFile(path, file.Format(), 0644); err != nil {
+		return "", err
+	}
+	l.trustProject(&project{Path: path, File: file})
+
+	l.audit(AuditEntry{Action: "project-init", Result: "ok", Caller: callerChain(l.Caller), Project: path, Args: sortedNames(file.Recipients)})
+	return path, nil
+}
+
+// Project describes the project file found from the working directory.
+func (l *Launcher) Project() (ProjectInfo, error) {
+	p, err := l.findProject()
+	if err != nil {
+		return ProjectInfo{}, err
+	}
+	return ProjectInfo{
+		Path:       p.Path,
+		Recipients: sortedNames(p.File.Recipients),
+		EnvNames:   sortedNames(p.File.Values),
+		Trusted:    l.isTrusted(p),
+	}, nil
+}
+
+// TrustProject lets launches from within the project inject its current envs.
+// Anyone able to commit could otherwise inject variables like NODE_OPTIONS by
+// encrypting them for a recipient's public key, so envs added later need
+// another trust.
+func (l *Launcher) TrustProject() error {
+	p, err := l.findProject()
+	if err != nil {
+		return err
+	}
+	l.trustProject(p)
+	l.audit(AuditEntry{Action: "project-trust", Result: "ok", Caller: callerChain(l.Caller), Project: p.Path, EnvNames: sortedNames(p.File.Values)})
+	return nil
+}
+
+// ProjectAddRecipient wraps the project's data key for another recipient, who
+// can then decrypt the project's envs.
+func (l *Launcher) ProjectAddRecipient(recipient string) error {
+	p, err := l.findProject()
+	if err != nil {
+		return err
+	}
+	dataKey, err := l.projectKey(p)
+	if err != nil {
+		return err
+	}
+	if err := addRecipient(&p.File, dataKey, recipient); err != nil {
+		return err
+	}
+	if err := os.WriteFile(p.Path, p.File.Format(), 0644); err != nil {
+		return err
+	}
+	l.audit(AuditEntry{Action: "project-recipient", Result: "added", Caller: callerChain(l.Caller), Project: p.Path, Args: []string{recipient}})
+	return nil
+}
+
+// EditProject edits the envs of the project file. Unchanged values keep their
+// ciphertext so diffs only show actual changes.
+func (l *Launcher) EditProject() error {
+	p, err := l.findProject()
+	if err != nil {
+		return err
+	}
+	dataKey, err := l.projectKey(p)
+	if err != nil {
+		return err
+	}
+	schema, err := projectSchema(p)
+	if err != nil {
+		return err
+	}
+	validate := validator(schema, nil)
+	currentValues := l.decryptEnvs(dataKey, p.File.Values)
+	trusted := l.isTrusted(p)
+
+	result, ok := l.EditDialog.EditEnvs(editdialog.Request{
+		ApplicationPath: p.Path,
+		Profile:         "project",
+		Values:          currentValues,
+		Generate:        secretgen.Generate,
+		Validate:        validate,
+	})
+	entry := AuditEntry{Action: "edit", Caller: callerChain(l.Caller), Project: p.Path}
+	if !ok {
+		entry.Result = "canceled"
+		l.audit(entry)
+		return nil
+	}
+	if problems := validate(result.Values); problems != nil {
+		entry.Result = "invalid"
+		l.audit(entry)
+		return envschema.Error(problems)
+	}
+
+	encryptedEnvs := map[string]string{}
+	for envName, value := range result.Values {
+		encryptedEnvs[envName] = l.encryptEdited(dataKey, p.File.Values, currentValues, envName, value)
+	}
+	p.File.Values = encryptedEnvs
+	if err := os.WriteFile(p.Path, p.File.Format(), 0644); err != nil {
+		return err
+	}
+	if trusted {
+		l.trustProject(p)
+	}
+
+	entry.Result = "saved"
+	entry.EnvNames = sortedNames(result.Values)
+	l.audit(entry)
+	return nil
+}
+
+// launchProject returns the trusted project whose envs a launch injects, or
+// nil and a warning if the project file is unreadable, not trusted or has
+// envs added since it was trusted.
+func (l *Launcher) launchProject() (*project, []string) {
+	p, err := l.findProject()
+	if errors.Is(err, ErrNoProject) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, []string{"Project secrets ignored: " + err.Error()}
+	}
+	trustedNames, ok := l.loadTrustedProjects()[p.Path]
+	if !ok {
+		return nil, []string{p.Path + " is not trusted and was ignored (with-secure-env project trust)"}
+	}
+	if added := addedNames(trustedNames, p.File.Values); added != nil {
+		return nil, []string{fmt.Sprintf("%s has new envs since it was trusted (%s) and was ignored (with-secure-env project trust)", p.Path, strings.Join(added, ", "))}
+	}
+	return p, nil
+}
+
+func projectPath(p *project) string {
+	if p == nil {
+		return ""
+	}
+	return p.Path
+}
+
+// projectScope decrypts the envs of a project for resolving templates.
+func (l *Launcher) projectScope(p *project) (envtemplate.Scope, error) {
+	dataKey, err := l.projectKey(p)
+	if err != nil {
+		return envtemplate.Scope{}, err
+	}
+	return l.templateScope(dataKey, p.File.Values), nil
+}
+
+// withProjectEnvs returns encryptedEnvs overridden by the project's envs.
+func withProjectEnvs(encryptedEnvs map[string]string, p *project) map[string]string {
+	if p == nil {
+		return encryptedEnvs
+	}
+	merged := map[string]string{}
+	for name, encrypted := range encryptedEnvs {
+		merged[name] = encrypted
+	}
+	for name, encrypted := range p.File.Values {
+		merged[name] = encrypted
+	}
+	return merged
+}
+
+// projectKey unwraps the data key of a project with the local identity.
+func (l *Launcher) projectKey(p *project) ([]byte, error) {
+	key, err := l.Keychain.RetrieveEncryptionKey()
+	if err != nil {
+		return nil, err
+	}
+	identity, _, err := l.loadIdentity(key)
+	if err != nil {
+		return nil, err
+	}
+	var stanzas []bundle.Stanza
+	for _, wrapped := range p.File.Recipients {
+		if stanza, err := parseStanza(wrapped); err == nil {
+			stanzas = append(stanzas, stanza)
+		}
+	}
+	dataKey, err := bundle.Unwrap(stanzas, identity)
+	if err != nil {
+		return nil, fmt.Errorf("%s: not encrypted for your identity (ask a recipient to run project add-recipient %s)", p.Path, bundle.FormatRecipient(identity.PublicKey()))
+	}
+	if keyID(dataKey) != p.File.KeyID {
+		return nil, fmt.Errorf("%s: data key does not match @key", p.Path)
+	}
+	return dataKey, nil
+}
+
+func (l *Launcher) findProject() (*project, error) {
+	dir, err := l.workingDir()
+	if err != nil {
+		return nil, err
+	}
+	path, ok := projectfile.Find(dir)
+	if !ok {
+		return nil, ErrNoProject
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	file, err := projectfile.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &project{Path: path, File: file}, nil
+}
+
+func (l *Launcher) workingDir() (string, error) {
+	if l.WorkingDir != nil {
+		return l.WorkingDir()
+	}
+	return os.Getwd()
+}
+
+// addRecipient wraps dataKey for recipient into file.
+func addRecipient(file *projectfile.File, dataKey []byte, recipient string) error {
+	publicKey, err := bundle.ParseRecipient(recipient)
+	if err != nil {
+		return err
+	}
+	stanza, err := bundle.Wrap(dataKey, publicKey)
+	if err != nil {
+		return err
+	}
+	file.Recipients[recipient] = base64.StdEncoding.EncodeToString(stanza.Ephemeral) + " " + stanza.WrappedKey
+	return nil
+}
+
+func parseStanza(wrapped string) (bundle.Stanza, error) {
+	ephemeral, wrappedKey, ok := strings.Cut(wrapped, " ")
+	if !ok {
+		return bundle.Stanza{}, envcrypt.ErrInvalidCiphertext
+	}
+	ephemeralBytes, err := base64.StdEncoding.DecodeString(ephemeral)
+	if err != nil {
+		return bundle.Stanza{}, envcrypt.ErrInvalidCiphertext
+	}
+	return bundle.Stanza{Ephemeral: ephemeralBytes, WrappedKey: wrappedKey}, nil
+}
+
+// keyID identifies a data key without revealing it.
+func keyID(dataKey []byte) string {
+	sum := sha256.Sum256(dataKey)
+	return hex.EncodeToString(sum[:8])
+}
+
+// trustProject records the current env names of the project as trusted.
+func (l *Launcher) trustProject(p *project) {
+	trusted := l.loadTrustedProjects()
+	trusted[p.Path] = sortedNames(p.File.Values)
+	l.saveJSON("projects.json", trusted)
+}
+
+func (l *Launcher) isTrusted(p *project) bool {
+	trustedNames, ok := l.loadTrustedProjects()[p.Path]
+	return ok && addedNames(trustedNames, p.File.Values) == nil
+}
+
+// loadTrustedProjects returns the env names of each trusted project file at
+// the time it was trusted.
+func (l *Launcher) loadTrustedProjects() map[string][]string {
+	trusted := map[string][]string{}
+	l.loadJSON("projects.json", &trusted)
+	return trusted
+}
+
+// addedNames returns the names of envs that are not in trustedNames.
+func addedNames(trustedNames []string, envs map[string]string) []string {
+	var added []string
+	for _, name := range sortedNames(envs) {
+		if !slices.Contains(trustedNames, name) {
+			added = append(added, name)
+		}
+	}
+	return added
+}
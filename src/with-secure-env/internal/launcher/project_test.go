@@ -0,0 +1,178 @@
+package launcher
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kfischer-okarin/with-secure-env/internal/permissiondialog"
+	"github.com/kfischer-okarin/with-secure-env/internal/projectfile"
+)
+
+// newTestProject returns a launcher working in a nested directory of a new
+// project.
+func newTestProject(t *testing.T) (*Launcher, *stubEditDialog, *stubPermissionDialog, string) {
+	launcher, _, editDialog, permDialog := newTestLauncher(t)
+	launcher.Init()
+	root := t.TempDir()
+	launcher.WorkingDir = func() (string, error) { return root, nil }
+	if _, err := launcher.ProjectInit(nil); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	nested := filepath.Join(root, "src")
+	os.Mkdir(nested, 0755)
+	launcher.WorkingDir = func() (string, error) { return nested, nil }
+	return launcher, editDialog, permDialog, filepath.Join(root, projectfile.FileName)
+}
+
+func TestLaunch_InjectsProjectEnvs(t *testing.T) {
+	launcher, editDialog, permDialog, path := newTestProject(t)
+	editDialog.returnOk = true
+	editDialog.returnValues = map[string]string{"API_KEY": "global", "DB_PASSWORD": "global"}
+	launcher.EditEnvs("/path/to/app")
+	editDialog.returnValues = map[string]string{"DB_PASSWORD": "project"}
+	if err := launcher.EditProject(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var executedEnv []string
+	launcher.Exec = func(process Process) (ExitStatus, error) {
+		executedEnv = process.Env
+		return ExitStatus{}, nil
+	}
+
+	permDialog.returnGranted = true
+	_, err := launcher.Launch("/path/to/app", nil, permissiondialog.CallerInfo{})
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !containsEnv(executedEnv, "API_KEY=global") || !containsEnv(executedEnv, "DB_PASSWORD=project") {
+		t.Errorf("expected project envs to override application envs, got %v", executedEnv)
+	}
+	if permDialog.receivedRequest.Project != path {
+		t.Errorf("expected project %s to be shown, got %q", path, permDialog.receivedRequest.Project)
+	}
+}
+
+func TestLaunch_IgnoresUntrustedProject(t *testing.T) {
+	launcher, editDialog, permDialog, _ := newTestProject(t)
+	editDialog.returnOk = true
+	editDialog.returnValues = map[string]string{"NODE_OPTIONS": "--require evil.js"}
+	launcher.EditProject()
+	launcher.saveJSON("projects.json", map[string][]string{})
+	var executedEnv []string
+	launcher.Exec = func(process Process) (ExitStatus, error) {
+		executedEnv = process.Env
+		return ExitStatus{}, nil
+	}
+
+	permDialog.returnGranted = true
+	launcher.Launch("/path/to/app", nil, permissiondialog.CallerInfo{})
+
+	if containsEnv(executedEnv, "NODE_OPTIONS=--require evil.js") {
+		t.Error("expected untrusted project envs not to be injected")
+	}
+	warnings := permDialog.receivedRequest.Warnings
+	if len(warnings) == 0 || !strings.Contains(warnings[0], "not trusted") {
+		t.Errorf("expected warning about untrusted project, got %v", warnings)
+	}
+
+	launcher.TrustProject()
+	launcher.Launch("/path/to/app", nil, permissiondialog.CallerInfo{})
+
+	if !containsEnv(executedEnv, "NODE_OPTIONS=--require evil.js") {
+		t.Errorf("expected trusted project envs to be injected, got %v", executedEnv)
+	}
+}
+
+func TestLaunch_IgnoresProjectWithEnvsAddedSinceTrust(t *testing.T) {
+	launcher, editDialog, permDialog, _ := newTestProject(t)
+	editDialog.returnOk = true
+	editDialog.returnValues = map[string]string{"API_KEY": "secret"}
+	launcher.EditProject()
+	teammate, _, teammateEdit, _ := newTestLauncher(t)
+	teammate.Init()
+	teammate.WorkingDir = launcher.WorkingDir
+	teammateIdentity, _ := teammate.Identity()
+	launcher.ProjectAddRecipient(teammateIdentity.Recipient)
+	teammateEdit.returnOk = true
+	teammateEdit.returnValues = map[string]string{"API_KEY": "secret", "NODE_OPTIONS": "--require evil.js"}
+	teammate.EditProject()
+	var executedEnv []string
+	launcher.Exec = func(process Process) (ExitStatus, error) {
+		executedEnv = process.Env
+		return ExitStatus{}, nil
+	}
+
+	permDialog.returnGranted = true
+	launcher.Launch("/path/to/app", nil, permissiondialog.CallerInfo{})
+
+	if containsEnv(executedEnv, "NODE_OPTIONS=--require evil.js") || containsEnv(executedEnv, "API_KEY=secret") {
+		t.Error("expected project with new envs not to be injected")
+	}
+	warnings := permDialog.receivedRequest.Warnings
+	if len(warnings) == 0 || !strings.Contains(warnings[0], "new envs since it was trusted (NODE_OPTIONS)") {
+		t.Errorf("expected warning about new envs, got %v", warnings)
+	}
+	if info, _ := launcher.Project(); info.Trusted {
+		t.Error("expected project not to be reported as trusted")
+	}
+
+	launcher.TrustProject()
+	launcher.Launch("/path/to/app", nil, permissiondialog.CallerInfo{})
+
+	if !containsEnv(executedEnv, "NODE_OPTIONS=--require evil.js") {
+		t.Errorf("expected re-trusted project envs to be injected, got %v", executedEnv)
+	}
+}
+
+func TestEditProject_KeepsCiphertextsOfUnchangedValues(t *testing.T) {
+	launcher, editDialog, _, path := newTestProject(t)
+	editDialog.returnOk = true
+	editDialog.returnValues = map[string]string{"A": "1", "B": "2"}
+	launcher.EditProject()
+	before, _ := os.ReadFile(path)
+
+	editDialog.returnValues = map[string]string{"A": "1", "B": "changed"}
+	launcher.EditProject()
+	after, _ := os.ReadFile(path)
+
+	beforeLines, afterLines := strings.Split(string(before), "\n"), strings.Split(string(after), "\n")
+	var changed []string
+	for i := range afterLines {
+		if beforeLines[i] != afterLines[i] {
+			changed = append(changed, afterLines[i])
+		}
+	}
+	if len(changed) != 1 || !strings.HasPrefix(changed[0], "B=") {
+		t.Errorf("expected only the line of B to change, got %v", changed)
+	}
+}
+
+func TestProjectAddRecipient_LetsTeammateDecrypt(t *testing.T) {
+	launcher, editDialog, _, path := newTestProject(t)
+	editDialog.returnOk = true
+	editDialog.returnValues = map[string]string{"API_KEY": "secret"}
+	launcher.EditProject()
+	teammate, _, teammateEdit, _ := newTestLauncher(t)
+	teammate.Init()
+	teammate.WorkingDir = launcher.WorkingDir
+	teammateIdentity, _ := teammate.Identity()
+
+	if err := teammate.EditProject(); err == nil || !strings.Contains(err.Error(), "not encrypted for your identity") {
+		t.Errorf("expected teammate not to be able to decrypt yet, got %v", err)
+	}
+	if err := launcher.ProjectAddRecipient(teammateIdentity.Recipient); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	teammateEdit.returnOk = false
+	teammate.EditProject()
+
+	if teammateEdit.receivedCurrentValues["API_KEY"] != "secret" {
+		t.Errorf("expected teammate to decrypt the project, got %v", teammateEdit.receivedCurrentValues)
+	}
+	if info, _ := teammate.Project(); info.Path != path || len(info.Recipients) != 2 || info.Trusted {
+		t.Errorf("expected project with 2 recipients, untrusted for the teammate, got %+v", info)
+	}
+}
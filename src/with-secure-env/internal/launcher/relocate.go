@@ -0,0 +1,218 @@
+package launcher
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Move re-keys the envs (all profiles) of oldPath to newPath, e.g. after a
+// version upgrade moved the binary. Aliases pointing to oldPath are updated as
+// well.
+func (l *Launcher) Move(oldPath string, newPath string) error {
+	if !l.hasEnvs(oldPath) {
+		return fmt.Errorf("no envs configured for %s", oldPath)
+	}
+	if err := l.checkDestination(newPath); err != nil {
+		return err
+	}
+	envNames := profileEnvNames(l.profileEnvs(oldPath))
+
+	fileContent := l.loadFileContent()
+	key, _ := l.Keychain.RetrieveEncryptionKey()
+	if encryptedEnvs, ok := fileContent[oldPath]; ok {
+		fileContent[newPath] = l.reencryptEnvs(key, encryptedEnvs, nil)
+		delete(fileContent, oldPath)
+		l.saveFileContent(fileContent)
+	}
+
+	aliases := l.loadAliases()
+	for alias, target := range aliases {
+		if target == oldPath {
+			aliases[alias] = newPath
+		}
+	}
+	l.saveAliases(aliases)
+
+	profiles := l.loadProfiles()
+	if oldProfiles, ok := profiles[oldPath]; ok {
+		profiles[newPath] = map[string]map[string]string{}
+		for profile, encryptedEnvs := range oldProfiles {
+			profiles[newPath][profile] = l.reencryptEnvs(key, encryptedEnvs, nil)
+		}
+		delete(profiles, oldPath)
+		l.saveProfiles(profiles)
+	}
+
+	settings := l.loadSettings()
+	if oldSettings, ok := settings[oldPath]; ok {
+		settings[newPath] = oldSettings
+		delete(settings, oldPath)
+		l.saveSettings(settings)
+	}
+
+	metadata := l.loadMetadata()
+	if oldMetadata, ok := metadata.Entries[oldPath]; ok {
+		metadata.Entries[newPath] = oldMetadata
+		delete(metadata.Entries, oldPath)
+		l.saveMetadata(metadata)
+	}
+
+	history := l.loadHistory()
+	if oldHistory, ok := history.Entries[oldPath]; ok {
+		for _, variables := range oldHistory {
+			for _, versions := range variables {
+				for i := range versions {
+					versions[i].Value = l.reencrypt(key, versions[i].Value)
+				}
+			}
+		}
+		history.Entries[newPath] = oldHistory
+		delete(history.Entries, oldPath)
+		l.saveHistory(history)
+	}
+
+	l.audit(AuditEntry{Action: "move", Caller: callerChain(l.Caller), App: newPath, Args: []string{oldPath}, EnvNames: envNames})
+	return nil
+}
+
+// Copy copies the envs (all profiles) of srcPath to dstPath, merging them
+// into the same profiles there. If envNames is not empty only those are
+// copied, from every profile that has them.
+func (l *Launcher) Copy(srcPath string, dstPath string, envNames []string) error {
+	srcProfiles := l.profileEnvs(srcPath)
+	if len(srcProfiles) == 0 {
+		return fmt.Errorf("no envs configured for %s", srcPath)
+	}
+	srcNames := profileEnvNames(srcProfiles)
+	for _, name := range envNames {
+		if !slices.Contains(srcNames, name) {
+			return fmt.Errorf("%s is not configured for %s", name, srcPath)
+		}
+	}
+	if _, isAlias := l.loadAliases()[dstPath]; isAlias {
+		return fmt.Errorf("%s is an alias, remove it first", dstPath)
+	}
+
+	key, _ := l.Keychain.RetrieveEncryptionKey()
+	copiedNames := map[string]bool{}
+	for _, profile := range sortedNames(srcProfiles) {
+		srcEnvs := srcProfiles[profile]
+		var copied []string
+		for _, name := range sortedNames(srcEnvs) {
+			if len(envNames) == 0 || slices.Contains(envNames, name) {
+				copied = append(copied, name)
+				copiedNames[name] = true
+			}
+		}
+		if len(copied) == 0 {
+			continue
+		}
+
+		dstScope := secretScope{Entry: dstPath, Profile: profile}
+		l.archiveValues(dstScope, copied)
+		dstEnvs := map[string]string{}
+		for name, encrypted := range l.loadProfileEnvs(dstPath, profile) {
+			dstEnvs[name] = encrypted
+		}
+		for name, encrypted := range l.reencryptEnvs(key, srcEnvs, copied) {
+			dstEnvs[name] = encrypted
+		}
+		l.saveProfileEnvs(dstPath, profile, dstEnvs)
+
+		srcMetadata := l.secretMetadata(secretScope{Entry: srcPath, Profile: profile})
+		dstMetadata := l.secretMetadata(dstScope)
+		for _, name := range copied {
+			if metadata, ok := srcMetadata[name]; ok {
+				dstMetadata[name] = metadata
+			} else {
+				delete(dstMetadata, name)
+			}
+		}
+		l.saveSecretMetadata(dstScope, dstMetadata)
+	}
+
+	l.audit(AuditEntry{Action: "copy", Caller: callerChain(l.Caller), App: dstPath, Args: []string{srcPath}, EnvNames: sortedNames(copiedNames)})
+	return nil
+}
+
+// Alias makes aliasPath resolve to the envs of targetPath.
+func (l *Launcher) Alias(aliasPath string, targetPath string) error {
+	targetPath = l.resolveApplicationPath(targetPath)
+	if !l.hasEnvs(targetPath) {
+		return fmt.Errorf("no envs configured for %s", targetPath)
+	}
+	if l.hasEnvs(aliasPath) {
+		return fmt.Errorf("%s already has its own envs", aliasPath)
+	}
+	if aliasPath == targetPath {
+		return fmt.Errorf("cannot alias %s to itself", aliasPath)
+	}
+
+	aliases := l.loadAliases()
+	aliases[aliasPath] = targetPath
+	l.saveAliases(aliases)
+
+	l.audit(AuditEntry{Action: "alias", Caller: callerChain(l.Caller), App: aliasPath, Args: []string{targetPath}})
+	return nil
+}
+
+// Unalias removes the alias at aliasPath.
+func (l *Launcher) Unalias(aliasPath string) error {
+	aliases := l.loadAliases()
+	if _, ok := aliases[aliasPath]; !ok {
+		return fmt.Errorf("%s is not an alias", aliasPath)
+	}
+	delete(aliases, aliasPath)
+	l.saveAliases(aliases)
+
+	l.audit(AuditEntry{Action: "unalias", Caller: callerChain(l.Caller), App: aliasPath})
+	return nil
+}
+
+// Aliases returns all aliases mapped to their target paths.
+func (l *Launcher) Aliases() map[string]string {
+	return l.loadAliases()
+}
+
+func (l *Launcher) checkDestination(path string) error {
+	if l.hasEnvs(path) {
+		return fmt.Errorf("envs already configured for %s", path)
+	}
+	if _, isAlias := l.loadAliases()[path]; isAlias {
+		return fmt.Errorf("%s is an alias, remove it first", path)
+	}
+	return nil
+}
+
+// reencryptEnvs decrypts and encrypts the values again so that entries stay
+// valid even if ciphertexts get bound to their application path.
+func (l *Launcher) reencryptEnvs(key []byte, encryptedEnvs map[string]string, envNames []string) map[string]string {
+	if len(envNames) == 0 {
+		for name := range encryptedEnvs {
+			envNames = append(envNames, name)
+		}
+	}
+
+	result := make(map[string]string, len(envNames))
+	for _, name := range envNames {
+		result[name] = l.reencrypt(key, encryptedEnvs[name])
+	}
+	return result
+}
+
+func (l *Launcher) resolveApplicationPath(applicationPath string) string {
+	if target, ok := l.loadAliases()[applicationPath]; ok {
+		return target
+	}
+	return applicationPath
+}
+
+func (l *Launcher) loadAliases() map[string]string {
+	aliases := map[string]string{}
+	l.loadJSON("aliases.json", &aliases)
+	return aliases
+}
+
+func (l *Launcher) saveAliases(aliases map[string]string) {
+	l.saveJSON("aliases.json", aliases)
+}
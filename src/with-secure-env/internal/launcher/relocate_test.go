@@ -0,0 +1,278 @@
+package launcher
+
+import (
+	"testing"
+
+	"github.com/kfischer-okarin/with-secure-env/internal/permissiondialog"
+)
+
+func TestMove_ReKeysEnvsToNewPath(t *testing.T) {
+	launcher, _, editDialog, _ := newTestLauncher(t)
+	launcher.Init()
+	editDialog.returnValues = map[string]string{"API_KEY": "secret"}
+	editDialog.returnOk = true
+	launcher.EditEnvs("/old/app")
+
+	err := launcher.Move("/old/app", "/new/app")
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	editDialog.returnOk = false
+	launcher.EditEnvs("/new/app")
+	if editDialog.receivedCurrentValues["API_KEY"] != "secret" {
+		t.Errorf("expected API_KEY 'secret' at new path, got %v", editDialog.receivedCurrentValues)
+	}
+	launcher.EditEnvs("/old/app")
+	if len(editDialog.receivedCurrentValues) != 0 {
+		t.Errorf("expected no envs at old path, got %v", editDialog.receivedCurrentValues)
+	}
+}
+
+func TestMove_FailsWhenSourceHasNoEnvs(t *testing.T) {
+	launcher, _, _, _ := newTestLauncher(t)
+	launcher.Init()
+
+	err := launcher.Move("/old/app", "/new/app")
+
+	if err == nil {
+		t.Error("expected error for unconfigured source")
+	}
+}
+
+func TestMove_DoesNotOverwriteExistingEntry(t *testing.T) {
+	launcher, _, editDialog, _ := newTestLauncher(t)
+	launcher.Init()
+	editDialog.returnOk = true
+	editDialog.returnValues = map[string]string{"API_KEY": "old"}
+	launcher.EditEnvs("/old/app")
+	editDialog.returnValues = map[string]string{"API_KEY": "new"}
+	launcher.EditEnvs("/new/app")
+
+	err := launcher.Move("/old/app", "/new/app")
+
+	if err == nil {
+		t.Error("expected error when destination already has envs")
+	}
+	editDialog.returnOk = false
+	launcher.EditEnvs("/new/app")
+	if editDialog.receivedCurrentValues["API_KEY"] != "new" {
+		t.Errorf("expected destination to be untouched, got %v", editDialog.receivedCurrentValues)
+	}
+}
+
+func TestMove_RetargetsAliases(t *testing.T) {
+	launcher, _, editDialog, _ := newTestLauncher(t)
+	launcher.Init()
+	editDialog.returnValues = map[string]string{"API_KEY": "secret"}
+	editDialog.returnOk = true
+	launcher.EditEnvs("/old/app")
+	launcher.Alias("/usr/local/bin/app", "/old/app")
+
+	launcher.Move("/old/app", "/new/app")
+
+	if launcher.Aliases()["/usr/local/bin/app"] != "/new/app" {
+		t.Errorf("expected alias to point to '/new/app', got %v", launcher.Aliases())
+	}
+}
+
+func TestCopy_CopiesSelectedEnvsAndKeepsSource(t *testing.T) {
+	launcher, _, editDialog, _ := newTestLauncher(t)
+	launcher.Init()
+	editDialog.returnOk = true
+	editDialog.returnValues = map[string]string{"API_KEY": "secret", "DB_PASS": "pass"}
+	launcher.EditEnvs("/src/app")
+	editDialog.returnValues = map[string]string{"OTHER": "value"}
+	launcher.EditEnvs("/dst/app")
+
+	err := launcher.Copy("/src/app", "/dst/app", []string{"API_KEY"})
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	editDialog.returnOk = false
+	launcher.EditEnvs("/dst/app")
+	expected := map[string]string{"API_KEY": "secret", "OTHER": "value"}
+	if !equalEnvs(editDialog.receivedCurrentValues, expected) {
+		t.Errorf("expected %v, got %v", expected, editDialog.receivedCurrentValues)
+	}
+	launcher.EditEnvs("/src/app")
+	if len(editDialog.receivedCurrentValues) != 2 {
+		t.Errorf("expected source to keep its envs, got %v", editDialog.receivedCurrentValues)
+	}
+}
+
+func TestCopy_CopiesAllEnvsWhenNoneSpecified(t *testing.T) {
+	launcher, _, editDialog, _ := newTestLauncher(t)
+	launcher.Init()
+	editDialog.returnOk = true
+	editDialog.returnValues = map[string]string{"API_KEY": "secret", "DB_PASS": "pass"}
+	launcher.EditEnvs("/src/app")
+
+	launcher.Copy("/src/app", "/dst/app", nil)
+
+	editDialog.returnOk = false
+	launcher.EditEnvs("/dst/app")
+	if !equalEnvs(editDialog.receivedCurrentValues, map[string]string{"API_KEY": "secret", "DB_PASS": "pass"}) {
+		t.Errorf("expected all envs copied, got %v", editDialog.receivedCurrentValues)
+	}
+}
+
+func TestCopy_FailsForUnknownEnvName(t *testing.T) {
+	launcher, _, editDialog, _ := newTestLauncher(t)
+	launcher.Init()
+	editDialog.returnOk = true
+	editDialog.returnValues = map[string]string{"API_KEY": "secret"}
+	launcher.EditEnvs("/src/app")
+
+	err := launcher.Copy("/src/app", "/dst/app", []string{"MISSING"})
+
+	if err == nil {
+		t.Error("expected error for unknown env name")
+	}
+}
+
+func TestCopy_CopiesAllProfiles(t *testing.T) {
+	launcher, _, editDialog, _ := newTestLauncher(t)
+	launcher.Init()
+	editDialog.returnOk = true
+	editDialog.returnValues = map[string]string{"API_KEY": "prod-secret", "DB_PASS": "prod-pass"}
+	launcher.EditProfile("/src/app", "prod")
+	editDialog.returnValues = map[string]string{"API_KEY": "staging-secret"}
+	launcher.EditProfile("/src/app", "staging")
+
+	err := launcher.Copy("/src/app", "/dst/app", []string{"API_KEY"})
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	editDialog.returnOk = false
+	launcher.EditProfile("/dst/app", "prod")
+	if !equalEnvs(editDialog.receivedCurrentValues, map[string]string{"API_KEY": "prod-secret"}) {
+		t.Errorf("expected prod API_KEY copied, got %v", editDialog.receivedCurrentValues)
+	}
+	launcher.EditProfile("/dst/app", "staging")
+	if !equalEnvs(editDialog.receivedCurrentValues, map[string]string{"API_KEY": "staging-secret"}) {
+		t.Errorf("expected staging API_KEY copied, got %v", editDialog.receivedCurrentValues)
+	}
+	if profiles := launcher.Profiles("/dst/app"); len(profiles) != 2 {
+		t.Errorf("expected only prod and staging at destination, got %v", profiles)
+	}
+}
+
+func TestMove_AuditsEnvNamesOfAllProfiles(t *testing.T) {
+	launcher, _, editDialog, _ := newTestLauncher(t)
+	launcher.Init()
+	editDialog.returnOk = true
+	editDialog.returnValues = map[string]string{"API_KEY": "secret"}
+	launcher.EditProfile("/old/app", "prod")
+
+	launcher.Move("/old/app", "/new/app")
+
+	entries, _ := launcher.AuditEntries(AuditFilter{Action: "move"})
+	if len(entries) != 1 || len(entries[0].EnvNames) != 1 || entries[0].EnvNames[0] != "API_KEY" {
+		t.Errorf("expected API_KEY to be audited, got %+v", entries)
+	}
+}
+
+func TestAlias_LaunchUsesTargetEnvs(t *testing.T) {
+	launcher, _, editDialog, permDialog := newTestLauncher(t)
+	launcher.Init()
+	editDialog.returnValues = map[string]string{"API_KEY": "secret"}
+	editDialog.returnOk = true
+	launcher.EditEnvs("/opt/app-1.2/bin/app")
+
+	launcher.Alias("/usr/local/bin/app", "/opt/app-1.2/bin/app")
+
+	var executedPath string
+	var executedEnv []string
+	launcher.Exec = func(process Process) (ExitStatus, error) {
+		executedPath = process.Path
+		executedEnv = process.Env
+		return ExitStatus{}, nil
+	}
+	permDialog.returnGranted = true
+	launcher.Launch("/usr/local/bin/app", nil, permissiondialog.CallerInfo{})
+
+	if executedPath != "/usr/local/bin/app" {
+		t.Errorf("expected to execute the alias path, got '%s'", executedPath)
+	}
+	if !containsEnv(executedEnv, "API_KEY=secret") {
+		t.Errorf("expected API_KEY from alias target, got %v", executedEnv)
+	}
+}
+
+func TestAlias_EditUpdatesTargetEnvs(t *testing.T) {
+	launcher, _, editDialog, _ := newTestLauncher(t)
+	launcher.Init()
+	editDialog.returnValues = map[string]string{"API_KEY": "secret"}
+	editDialog.returnOk = true
+	launcher.EditEnvs("/target/app")
+	launcher.Alias("/alias/app", "/target/app")
+
+	editDialog.returnValues = map[string]string{"API_KEY": "updated"}
+	launcher.EditEnvs("/alias/app")
+
+	editDialog.returnOk = false
+	launcher.EditEnvs("/target/app")
+	if editDialog.receivedCurrentValues["API_KEY"] != "updated" {
+		t.Errorf("expected target to be updated, got %v", editDialog.receivedCurrentValues)
+	}
+}
+
+func TestAlias_ResolvesAliasTargets(t *testing.T) {
+	launcher, _, editDialog, _ := newTestLauncher(t)
+	launcher.Init()
+	editDialog.returnValues = map[string]string{"API_KEY": "secret"}
+	editDialog.returnOk = true
+	launcher.EditEnvs("/target/app")
+	launcher.Alias("/alias1/app", "/target/app")
+
+	launcher.Alias("/alias2/app", "/alias1/app")
+
+	if launcher.Aliases()["/alias2/app"] != "/target/app" {
+		t.Errorf("expected alias to point to the final target, got %v", launcher.Aliases())
+	}
+}
+
+func TestAlias_FailsWhenPathHasOwnEnvs(t *testing.T) {
+	launcher, _, editDialog, _ := newTestLauncher(t)
+	launcher.Init()
+	editDialog.returnValues = map[string]string{"API_KEY": "secret"}
+	editDialog.returnOk = true
+	launcher.EditEnvs("/target/app")
+	launcher.EditEnvs("/other/app")
+
+	err := launcher.Alias("/other/app", "/target/app")
+
+	if err == nil {
+		t.Error("expected error when aliasing a path with its own envs")
+	}
+}
+
+func TestUnalias_RemovesAlias(t *testing.T) {
+	launcher, _, editDialog, _ := newTestLauncher(t)
+	launcher.Init()
+	editDialog.returnValues = map[string]string{"API_KEY": "secret"}
+	editDialog.returnOk = true
+	launcher.EditEnvs("/target/app")
+	launcher.Alias("/alias/app", "/target/app")
+
+	launcher.Unalias("/alias/app")
+
+	if len(launcher.Aliases()) != 0 {
+		t.Errorf("expected no aliases, got %v", launcher.Aliases())
+	}
+}
+
+func equalEnvs(a map[string]string, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if b[k] != v {
+			return false
+		}
+	}
+	return true
+}
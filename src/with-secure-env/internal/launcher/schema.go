@@ -0,0 +1,117 @@
+package launcher
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kfischer-okarin/with-secure-env/internal/envschema"
+	"github.com/kfischer-okarin/with-secure-env/internal/secretmeta"
+)
+
+// ErrMissingEnvs is returned by Launch when required envs of the schema are
+// not configured.
+var ErrMissingEnvs = errors.New("missing required envs")
+
+// appSchema returns the schema of the application: the manifest next to the
+// binary, overridden by the inline schema of its settings.
+func (l *Launcher) appSchema(applicationPath string, settings AppSettings) (envschema.Schema, error) {
+	schema, err := readManifest(applicationPath + envschema.ManifestSuffix)
+	if err != nil {
+		return envschema.Schema{}, err
+	}
+	if settings.Schema != nil {
+		schema = schema.Merge(*settings.Schema)
+	}
+	return schema, nil
+}
+
+// launchSchema returns the schema of a launch, including the manifest of the
+// injected project.
+func (l *Launcher) launchSchema(applicationPath string, settings AppSettings, p *project) (envschema.Schema, error) {
+	schema, err := l.appSchema(applicationPath, settings)
+	if err != nil {
+		return envschema.Schema{}, err
+	}
+	projectManifest, err := projectSchema(p)
+	if err != nil {
+		return envschema.Schema{}, err
+	}
+	return schema.Merge(projectManifest), nil
+}
+
+// projectSchema returns the schema of the manifest next to the project file.
+func projectSchema(p *project) (envschema.Schema, error) {
+	if p == nil {
+		return envschema.Schema{}, nil
+	}
+	return readManifest(filepath.Join(filepath.Dir(p.Path), envschema.ProjectManifestName))
+}
+
+// readManifest parses a manifest file, returning an empty schema if there is
+// none.
+func readManifest(path string) (envschema.Schema, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return envschema.Schema{}, nil
+	}
+	if err != nil {
+		return envschema.Schema{}, err
+	}
+	schema, err := envschema.Parse(data)
+	if err != nil {
+		return envschema.Schema{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return schema, nil
+}
+
+// checkEnvs checks the envNames about to be handed out: they must be valid
+// env names, and it returns warnings about the expired secrets among them or
+// an error if the settings block them. Blocked requests are audited as entry
+// with result expired.
+func (l *Launcher) checkEnvs(resolvedPath string, profile string, settings AppSettings, appEnvs map[string]string, envNames []string, entry AuditEntry) ([]string, error) {
+	if err := envschema.ValidateNames(envNames); err != nil {
+		return nil, err
+	}
+	metadata := l.injectedMetadata(resolvedPath, profile, settings, appEnvs)
+	requested := map[string]secretmeta.Metadata{}
+	for _, name := range envNames {
+		if fields, ok := metadata[name]; ok {
+			requested[name] = fields
+		}
+	}
+	expired := l.expiredSecrets(requested)
+	if len(expired) > 0 && settings.BlockExpired {
+		entry.Result = "expired"
+		l.audit(entry)
+		return nil, expiredError(expired)
+	}
+	return expired, nil
+}
+
+// validator returns the validation of edited values against schema, with
+// provided names counting as present.
+func validator(schema envschema.Schema, provided []string) func(values map[string]string) map[string]string {
+	return func(values map[string]string) map[string]string {
+		return schema.Check(values, provided)
+	}
+}
+
+// parseSchemaSetting parses the inline schema of Configure. An empty value
+// resets it.
+func parseSchemaSetting(value string) (*envschema.Schema, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	schema, err := envschema.Parse([]byte(value))
+	if err != nil {
+		return nil, err
+	}
+	return &schema, nil
+}
+
+func missingError(missing []string) error {
+	return fmt.Errorf("%w: %s", ErrMissingEnvs, strings.Join(missing, ", "))
+}
@@ -0,0 +1,165 @@
+package launcher
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kfischer-okarin/with-secure-env/internal/permissiondialog"
+	"github.com/kfischer-okarin/with-secure-env/internal/secretgen"
+)
+
+func TestEditEnvs_RejectsInvalidEnvNames(t *testing.T) {
+	launcher, _, editDialog, _ := newTestLauncher(t)
+	launcher.Init()
+	editDialog.returnOk = true
+
+	for _, name := range []string{"A=B", "API KEY", ""} {
+		editDialog.returnValues = map[string]string{name: "value"}
+		err := launcher.EditProfile("/path/to/app", "")
+
+		if err == nil || !strings.Contains(err.Error(), "invalid env name") {
+			t.Errorf("expected invalid env name error for %q, got %v", name, err)
+		}
+	}
+	if envs := launcher.loadFileContent()["/path/to/app"]; len(envs) != 0 {
+		t.Errorf("expected nothing to be saved, got %v", envs)
+	}
+}
+
+func TestEditEnvs_EnforcesInlineSchema(t *testing.T) {
+	launcher, _, editDialog, _ := newTestLauncher(t)
+	launcher.Init()
+	launcher.Configure("/path/to/app", "schema", `{"variables": {"PORT": {"type": "int"}, "API_KEY": {"required": true}}}`)
+	editDialog.returnOk = true
+	editDialog.returnValues = map[string]string{"PORT": "eighty"}
+
+	err := launcher.EditProfile("/path/to/app", "")
+
+	if err == nil || !strings.Contains(err.Error(), "API_KEY: required") || !strings.Contains(err.Error(), "PORT: expected int") {
+		t.Errorf("expected schema violations, got %v", err)
+	}
+	problems := editDialog.receivedRequest.Validate(map[string]string{"PORT": "80", "API_KEY": "secret"})
+	if problems != nil {
+		t.Errorf("expected dialog validation to accept valid values, got %v", problems)
+	}
+}
+
+func TestEditEnvs_RequiredEnvsMayComeFromGroups(t *testing.T) {
+	launcher, _, editDialog, _ := newTestLauncher(t)
+	launcher.Init()
+	editDialog.returnOk = true
+	editDialog.returnValues = map[string]string{"AWS_SECRET_ACCESS_KEY": "secret"}
+	launcher.EditGroup("aws")
+	launcher.Configure("/path/to/app", "groups", "aws")
+	launcher.Configure("/path/to/app", "schema", `{"variables": {"AWS_SECRET_ACCESS_KEY": {"required": true}}}`)
+	editDialog.returnValues = map[string]string{"PORT": "80"}
+
+	if err := launcher.EditProfile("/path/to/app", ""); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+}
+
+func TestLaunch_ReportsMissingRequiredEnvsBeforeAsking(t *testing.T) {
+	launcher, _, editDialog, permDialog := newTestLauncher(t)
+	launcher.Init()
+	appPath := filepath.Join(t.TempDir(), "app")
+	os.WriteFile(appPath+".secure-env-schema.json", []byte(`{"variables": {"DATABASE_URL": {"required": true, "type": "url"}, "PORT": {"required": true}}}`), 0644)
+	editDialog.returnOk = true
+	editDialog.returnValues = map[string]string{"PORT": "80"}
+	if err := launcher.EditProfile(appPath, ""); err == nil {
+		t.Fatal("expected manifest to be enforced by the edit dialog")
+	}
+	launcher.Import(appPath, "", map[string]string{"PORT": "80"})
+
+	_, err := launcher.Launch(appPath, nil, permissiondialog.CallerInfo{})
+
+	if !errors.Is(err, ErrMissingEnvs) || !strings.Contains(err.Error(), "DATABASE_URL") {
+		t.Errorf("expected missing DATABASE_URL, got %v", err)
+	}
+	if permDialog.receivedAppPath != "" {
+		t.Error("expected no permission request")
+	}
+	entries, _ := launcher.AuditEntries(AuditFilter{})
+	if last := entries[len(entries)-1]; last.Result != "missing" || last.EnvNames[0] != "DATABASE_URL" {
+		t.Errorf("expected missing launch to be audited, got %+v", last)
+	}
+}
+
+func TestLaunch_RejectsStoredInvalidEnvNames(t *testing.T) {
+	launcher, _, _, permDialog := newTestLauncher(t)
+	launcher.Init()
+	key, _ := launcher.Keychain.RetrieveEncryptionKey()
+	launcher.saveProfileEnvs("/path/to/app", DefaultProfile, map[string]string{"A=B": launcher.encrypt(key, "value")})
+
+	_, err := launcher.Launch("/path/to/app", nil, permissiondialog.CallerInfo{})
+
+	if err == nil || !strings.Contains(err.Error(), "invalid env name") {
+		t.Errorf("expected invalid env name error, got %v", err)
+	}
+	if permDialog.receivedAppPath != "" {
+		t.Error("expected no permission request")
+	}
+}
+
+func TestExportFetchShare_RejectStoredInvalidEnvNames(t *testing.T) {
+	launcher, _, _, permDialog := newTestLauncher(t)
+	launcher.Init()
+	key, _ := launcher.Keychain.RetrieveEncryptionKey()
+	launcher.saveProfileEnvs("/path/to/app", DefaultProfile, map[string]string{"A B": launcher.encrypt(key, "value")})
+	identity, _ := launcher.Identity()
+	permDialog.returnGranted = true
+
+	_, exportErr := launcher.Export("/path/to/app", permissiondialog.CallerInfo{}, ExportOptions{Format: "dotenv"})
+	_, fetchErr := launcher.Fetch("/path/to/app", []string{"A B"}, permissiondialog.CallerInfo{})
+	_, shareErr := launcher.Share("/path/to/app", permissiondialog.CallerInfo{}, ShareOptions{Recipients: []string{identity.Recipient}})
+
+	for _, err := range []error{exportErr, fetchErr, shareErr} {
+		if err == nil || !strings.Contains(err.Error(), "invalid env name") {
+			t.Errorf("expected invalid env name error, got %v", err)
+		}
+	}
+	if permDialog.receivedAppPath != "" {
+		t.Error("expected no permission request")
+	}
+}
+
+func TestImportAndGenerate_RejectInvalidEnvNames(t *testing.T) {
+	launcher, _, _, _ := newTestLauncher(t)
+	launcher.Init()
+
+	if _, err := launcher.PlanImport("/path/to/app", "", map[string]string{"export API_KEY": "x"}); err == nil {
+		t.Error("expected invalid env name error")
+	}
+	if _, err := launcher.Generate(GenerateTarget{ApplicationPath: "/path/to/app"}, "DB PASSWORD", secretgen.Options{}); err == nil {
+		t.Error("expected invalid env name error")
+	}
+}
+
+func TestConfigure_RejectsInvalidSchema(t *testing.T) {
+	launcher, _, _, _ := newTestLauncher(t)
+
+	if err := launcher.Configure("/path/to/app", "schema", `{"variables": {"PORT": {"type": "number"}}}`); err == nil {
+		t.Error("expected error for unknown type")
+	}
+	launcher.Configure("/path/to/app", "schema", `{"variables": {"PORT": {"type": "int"}}}`)
+	launcher.Configure("/path/to/app", "schema", "")
+	if schema := launcher.Settings("/path/to/app").Schema; schema != nil {
+		t.Errorf("expected empty value to reset the schema, got %v", schema)
+	}
+}
+
+func TestEditProject_EnforcesProjectManifest(t *testing.T) {
+	launcher, editDialog, _, path := newTestProject(t)
+	os.WriteFile(filepath.Join(filepath.Dir(path), ".secure-env-schema.json"), []byte(`{"variables": {"API_URL": {"type": "url"}}}`), 0644)
+	editDialog.returnOk = true
+	editDialog.returnValues = map[string]string{"API_URL": "localhost"}
+
+	err := launcher.EditProject()
+
+	if err == nil || !strings.Contains(err.Error(), "API_URL: expected url") {
+		t.Errorf("expected schema violation, got %v", err)
+	}
+}
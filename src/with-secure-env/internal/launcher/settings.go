@@ -0,0 +1,225 @@
+package launcher
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/kfischer-okarin/with-secure-env/internal/envschema"
+)
+
+// Ways of handing a secret to the launched application.
+const (
+	// DeliveryEnv sets the variable itself (default).
+	DeliveryEnv = "env"
+	// DeliveryFile writes the value into a private file and sets VAR_FILE to
+	// its path.
+	DeliveryFile = "file"
+	// DeliveryFifo is like DeliveryFile but uses a named pipe that can be read
+	// only once.
+	DeliveryFifo = "fifo"
+)
+
+// Ways of starting the application.
+const (
+	// LaunchModeExec replaces the with-secure-env process (default).
+	LaunchModeExec = "exec"
+	// LaunchModeSupervised runs the application as child process, forwarding
+	// signals and propagating its exit status.
+	LaunchModeSupervised = "supervised"
+)
+
+// AppSettings holds the non-secret configuration of an application entry.
+type AppSettings struct {
+	// Delivery is the delivery mode for all variables of the application.
+	Delivery string `json:"delivery,omitempty"`
+	// EnvDelivery overrides Delivery for single variables.
+	EnvDelivery map[string]string `json:"envDelivery,omitempty"`
+	// LaunchMode is exec or supervised. File delivery always implies
+	// supervised.
+	LaunchMode string `json:"launchMode,omitempty"`
+	// DisableRedaction turns off redacting secret values from the output of
+	// supervised applications.
+	DisableRedaction bool `json:"disableRedaction,omitempty"`
+	// CleanEnv starts the application with an empty environment, except for
+	// the variables in InheritEnv.
+	CleanEnv bool `json:"cleanEnv,omitempty"`
+	// InheritEnv is the allowlist for CleanEnv. Defaults to DefaultInheritEnv.
+	InheritEnv []string `json:"inheritEnv,omitempty"`
+	// DefaultProfile is the profile used when none is specified. Defaults to
+	// DefaultProfile.
+	DefaultProfile string `json:"defaultProfile,omitempty"`
+	// Groups are the shared groups whose envs are injected as well. Later
+	// groups override earlier ones, the application's own envs override all
+	// groups.
+	Groups []string `json:"groups,omitempty"`
+	// BlockExpired refuses launches injecting an expired secret instead of
+	// only warning.
+	BlockExpired bool `json:"blockExpired,omitempty"`
+	// HistoryVersions is the number of previous values kept per variable.
+	// Defaults to DefaultHistoryVersions.
+	HistoryVersions int `json:"historyVersions,omitempty"`
+	// HistoryMaxAge drops previous values replaced longer ago, e.g. "90d".
+	HistoryMaxAge string `json:"historyMaxAge,omitempty"`
+	// Schema declares the expected variables, overriding the manifest next
+	// to the binary.
+	Schema *envschema.Schema `json:"schema,omitempty"`
+}
+
+// Settings returns the settings of the application.
+func (l *Launcher) Settings(applicationPath string) AppSettings {
+	return l.loadSettings()[l.resolveApplicationPath(applicationPath)]
+}
+
+// Configure changes a setting of the application. An empty value resets the
+// setting to its default. Supported keys:
+//
+//	delivery        env, file or fifo for all variables
+//	delivery.VAR    env, file or fifo for variable VAR
+//	launchMode      exec or supervised
+//	redactOutput    true or false, redact secrets from supervised output
+//	cleanEnv        true or false, only inherit variables in inheritEnv
+//	inheritEnv      comma separated allowlist, e.g. PATH,HOME,LC_*
+//	defaultProfile  profile used when none is specified
+//	groups          comma separated shared groups, later ones take precedence
+//	blockExpired    true or false, refuse to inject expired secrets
+//	historyVersions previous values kept per variable (default 10)
+//	historyMaxAge   drop previous values older than this, e.g. 90d
+//	schema          JSON schema of the expected variables
+func (l *Launcher) Configure(applicationPath string, key string, value string) error {
+	applicationPath = l.resolveApplicationPath(applicationPath)
+	if err := validateEntry(applicationPath); err != nil {
+		return err
+	}
+	if entry := l.resolveEntry(applicationPath); entryKind(applicationPath) == EntryExact && entry != applicationPath {
+		return fmt.Errorf("%s has no envs of its own and uses the entry %s, configure that entry instead", applicationPath, entry)
+	}
+	allSettings := l.loadSettings()
+	settings := allSettings[applicationPath]
+
+	switch {
+	case key == "delivery":
+		if err := validateDelivery(value); err != nil {
+			return err
+		}
+		settings.Delivery = value
+	case strings.HasPrefix(key, "delivery."):
+		if err := validateDelivery(value); err != nil {
+			return err
+		}
+		envName := strings.TrimPrefix(key, "delivery.")
+		if err := envschema.ValidateName(envName); err != nil {
+			return err
+		}
+		if settings.EnvDelivery == nil {
+			settings.EnvDelivery = map[string]string{}
+		}
+		if value == "" {
+			delete(settings.EnvDelivery, envName)
+		} else {
+			settings.EnvDelivery[envName] = value
+		}
+	case key == "launchMode":
+		if value != "" && value != LaunchModeExec && value != LaunchModeSupervised {
+			return fmt.Errorf("unknown launch mode %s (expected exec or supervised)", value)
+		}
+		settings.LaunchMode = value
+	case key == "redactOutput":
+		if value != "" && value != "true" && value != "false" {
+			return fmt.Errorf("invalid value %s for redactOutput (expected true or false)", value)
+		}
+		settings.DisableRedaction = value == "false"
+	case key == "cleanEnv":
+		if value != "" && value != "true" && value != "false" {
+			return fmt.Errorf("invalid value %s for cleanEnv (expected true or false)", value)
+		}
+		settings.CleanEnv = value == "true"
+	case key == "blockExpired":
+		if value != "" && value != "true" && value != "false" {
+			return fmt.Errorf("invalid value %s for blockExpired (expected true or false)", value)
+		}
+		settings.BlockExpired = value == "true"
+	case key == "historyVersions":
+		versions := 0
+		if value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n <= 0 {
+				return fmt.Errorf("invalid value %s for historyVersions (expected a positive number)", value)
+			}
+			versions = n
+		}
+		settings.HistoryVersions = versions
+	case key == "historyMaxAge":
+		if _, err := parseAge(value); err != nil {
+			return err
+		}
+		settings.HistoryMaxAge = value
+	case key == "schema":
+		schema, err := parseSchemaSetting(value)
+		if err != nil {
+			return err
+		}
+		settings.Schema = schema
+	case key == "inheritEnv":
+		settings.InheritEnv = splitList(value)
+	case key == "groups":
+		groups := splitList(value)
+		if err := l.validateGroupsExist(groups); err != nil {
+			return err
+		}
+		settings.Groups = groups
+	case key == "defaultProfile":
+		if value != "" {
+			if err := validateName("profile", value); err != nil {
+				return err
+			}
+		}
+		settings.DefaultProfile = value
+	default:
+		return fmt.Errorf("unknown setting %s", key)
+	}
+
+	allSettings[applicationPath] = settings
+	l.saveSettings(allSettings)
+
+	l.audit(AuditEntry{Action: "configure", Caller: callerChain(l.Caller), App: applicationPath, Args: []string{key, value}})
+	return nil
+}
+
+func (s AppSettings) deliveryFor(envName string) string {
+	if delivery, ok := s.EnvDelivery[envName]; ok {
+		return delivery
+	}
+	if s.Delivery != "" {
+		return s.Delivery
+	}
+	return DeliveryEnv
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func validateDelivery(value string) error {
+	switch value {
+	case "", DeliveryEnv, DeliveryFile, DeliveryFifo:
+		return nil
+	}
+	return fmt.Errorf("unknown delivery mode %s (expected env, file or fifo)", value)
+}
+
+func (l *Launcher) loadSettings() map[string]AppSettings {
+	settings := map[string]AppSettings{}
+	l.loadJSON("settings.json", &settings)
+	return settings
+}
+
+func (l *Launcher) saveSettings(settings map[string]AppSettings) {
+	l.saveJSON("settings.json", settings)
+}
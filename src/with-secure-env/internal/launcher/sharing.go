@@ -0,0 +1,204 @@
+package launcher
+
+import (
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/kfischer-okarin/with-secure-env/internal/bundle"
+	"github.com/kfischer-okarin/with-secure-env/internal/permissiondialog"
+)
+
+// ShareIdentity is the public part of the local sharing identity.
+type ShareIdentity struct {
+	// Recipient is the age recipient teammates share bundles to.
+	Recipient string
+	// Fingerprint identifies bundles signed by this identity.
+	Fingerprint string
+}
+
+// identityFile is the content of identity.json. Both keys are encrypted with
+// the encryption key.
+type identityFile struct {
+	Identity   string `json:"identity"`
+	SigningKey string `json:"signingKey"`
+}
+
+// ShareOptions describe a bundle to share.
+type ShareOptions struct {
+	// Profile selects the profile instead of the application's default
+	// profile.
+	Profile string
+	// Recipients are the age recipients ("age1...") able to open the bundle.
+	Recipients []string
+	// Destination is where the bundle goes (file path or "stdout"), recorded
+	// in the audit log.
+	Destination string
+}
+
+// OpenOptions describe how to open a bundle.
+type OpenOptions struct {
+	// Identity is the content of an age identity file to decrypt with instead
+	// of the local identity.
+	Identity string
+	// From is the fingerprint the bundle has to be signed with, if set.
+	From string
+}
+
+// OpenedBundle is the content of a decrypted bundle.
+type OpenedBundle struct {
+	Values map[string]string
+	// Sender is the fingerprint of the signing key.
+	Sender string
+}
+
+// Identity returns the local sharing identity, creating it on first use.
+func (l *Launcher) Identity() (ShareIdentity, error) {
+	key, err := l.Keychain.RetrieveEncryptionKey()
+	if err != nil {
+		return ShareIdentity{}, err
+	}
+	identity, signingKey, err := l.loadIdentity(key)
+	if err != nil {
+		return ShareIdentity{}, err
+	}
+	return ShareIdentity{
+		Recipient:   bundle.FormatRecipient(identity.PublicKey()),
+		Fingerprint: bundle.Fingerprint(signingKey.Public().(ed25519.PublicKey)),
+	}, nil
+}
+
+// Share asks for permission like an export and returns the envs of the
+// application as bundle only the recipients can open, signed with the local
+// identity.
+func (l *Launcher) Share(applicationPath string, caller permissiondialog.CallerInfo, options ShareOptions) ([]byte, error) {
+	var recipients []*ecdh.PublicKey
+	for _, recipient := range options.Recipients {
+		publicKey, err := bundle.ParseRecipient(recipient)
+		if err != nil {
+			return nil, err
+		}
+		recipients = append(recipients, publicKey)
+	}
+	if len(recipients) == 0 {
+		return nil, errors.New("no recipients")
+	}
+	resolvedPath := l.resolveEntry(applicationPath)
+	settings := l.loadSettings()[resolvedPath]
+	profile := profileFor(settings, options.Profile)
+	if err := validateName("profile", profile); err != nil {
+		return nil, err
+	}
+	appEnvs := l.loadProfileEnvs(resolvedPath, profile)
+	encryptedEnvs := l.mergeGroupEnvs(settings, appEnvs)
+	envNames := sortedNames(encryptedEnvs)
+	entry := AuditEntry{Action: "share", Caller: callerChain(caller), App: applicationPath, Entry: matchedPattern(resolvedPath), Profile: profile, Args: append(append([]string{}, options.Recipients...), options.Destination), EnvNames: envNames}
+	expired, err := l.checkEnvs(resolvedPath, profile, settings, appEnvs, envNames, entry)
+	if err != nil {
+		return nil, err
+	}
+
+	granted := l.PermissionDialog.AskPermission(permissiondialog.Request{
+		ApplicationPath: applicationPath,
+		EnvNames:        envNames,
+		Caller:          caller,
+		Warnings:        expired,
+		Profile:         profile,
+		Production:      isProductionProfile(profile),
+		Groups:          settings.Groups,
+		MatchedEntry:    matchedPattern(resolvedPath),
+		Recipients:      options.Recipients,
+		Derived:         derivedNames(encryptedEnvs),
+	})
+	if !granted {
+		entry.Result = "denied"
+		l.audit(entry)
+		return nil, ErrPermissionDenied
+	}
+	entry.Result = "granted"
+	l.audit(entry)
+
+	key, _ := l.Keychain.RetrieveEncryptionKey()
+	_, signingKey, err := l.loadIdentity(key)
+	if err != nil {
+		return nil, err
+	}
+	values, err := l.resolveTemplates(key, l.entryScope(key, settings, encryptedEnvs), envNames)
+	if err != nil {
+		return nil, err
+	}
+	return bundle.Seal(values, recipients, signingKey)
+}
+
+// OpenBundle decrypts a bundle shared to the local identity or the identity
+// in options. The values can then be imported with Import.
+func (l *Launcher) OpenBundle(data []byte, options OpenOptions) (OpenedBundle, error) {
+	var identity *ecdh.PrivateKey
+	if options.Identity != "" {
+		var err error
+		if identity, err = bundle.ParseIdentityFile([]byte(options.Identity)); err != nil {
+			return OpenedBundle{}, err
+		}
+	} else {
+		key, err := l.Keychain.RetrieveEncryptionKey()
+		if err != nil {
+			return OpenedBundle{}, err
+		}
+		if identity, _, err = l.loadIdentity(key); err != nil {
+			return OpenedBundle{}, err
+		}
+	}
+
+	values, sender, err := bundle.Open(data, identity)
+	entry := AuditEntry{Action: "open-bundle", Caller: callerChain(l.Caller), Result: resultOf(err)}
+	if err != nil {
+		l.audit(entry)
+		return OpenedBundle{}, err
+	}
+	fingerprint := bundle.Fingerprint(sender)
+	entry.Args, entry.EnvNames = []string{fingerprint}, sortedNames(values)
+	if options.From != "" && options.From != fingerprint {
+		err = fmt.Errorf("bundle was signed by %s, not %s", fingerprint, options.From)
+		entry.Result = resultOf(err)
+		l.audit(entry)
+		return OpenedBundle{}, err
+	}
+	l.audit(entry)
+	return OpenedBundle{Values: values, Sender: fingerprint}, nil
+}
+
+// loadIdentity returns the local X25519 identity and Ed25519 signing key,
+// creating them on first use.
+func (l *Launcher) loadIdentity(key []byte) (*ecdh.PrivateKey, ed25519.PrivateKey, error) {
+	var file identityFile
+	l.loadJSON("identity.json", &file)
+	if file.Identity == "" {
+		identity, err := ecdh.X25519().GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, nil, err
+		}
+		_, signingKey, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, nil, err
+		}
+		file = identityFile{
+			Identity:   l.encrypt(key, bundle.FormatIdentity(identity)),
+			SigningKey: l.encrypt(key, base64.StdEncoding.EncodeToString(signingKey.Seed())),
+		}
+		l.saveJSON("identity.json", file)
+		l.audit(AuditEntry{Action: "identity", Result: "created", Caller: callerChain(l.Caller), Args: []string{bundle.FormatRecipient(identity.PublicKey()), bundle.Fingerprint(signingKey.Public().(ed25519.PublicKey))}})
+	}
+
+	identity, err := bundle.ParseIdentity(l.decrypt(key, file.Identity))
+	if err != nil {
+		return nil, nil, errors.New("cannot decrypt the sharing identity")
+	}
+	seed, err := base64.StdEncoding.DecodeString(l.decrypt(key, file.SigningKey))
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, nil, errors.New("cannot decrypt the sharing identity")
+	}
+	return identity, ed25519.NewKeyFromSeed(seed), nil
+}
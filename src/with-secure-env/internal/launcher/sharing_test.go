@@ -0,0 +1,131 @@
+package launcher
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/kfischer-okarin/with-secure-env/internal/bundle"
+	"github.com/kfischer-okarin/with-secure-env/internal/permissiondialog"
+)
+
+func TestShare_BundleCanBeImportedByRecipient(t *testing.T) {
+	alice, _, aliceEdit, alicePermission := newTestLauncher(t)
+	alice.Init()
+	aliceEdit.returnOk = true
+	aliceEdit.returnValues = map[string]string{"API_KEY": "secret"}
+	alice.EditEnvs("/path/to/app")
+	bob, _, _, _ := newTestLauncher(t)
+	bob.Init()
+	bobIdentity, _ := bob.Identity()
+	aliceIdentity, _ := alice.Identity()
+
+	alicePermission.returnGranted = true
+	data, err := alice.Share("/path/to/app", permissiondialog.CallerInfo{}, ShareOptions{Recipients: []string{bobIdentity.Recipient}, Destination: "stdout"})
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if strings.Contains(string(data), "secret") {
+		t.Error("expected bundle to be encrypted")
+	}
+	if recipients := alicePermission.receivedRequest.Recipients; len(recipients) != 1 || recipients[0] != bobIdentity.Recipient {
+		t.Errorf("expected recipients to be shown, got %v", recipients)
+	}
+
+	opened, err := bob.OpenBundle(data, OpenOptions{From: aliceIdentity.Fingerprint})
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if opened.Sender != aliceIdentity.Fingerprint || opened.Values["API_KEY"] != "secret" {
+		t.Errorf("expected values signed by alice, got %v", opened)
+	}
+}
+
+func TestShare_DeniedReturnsNoBundle(t *testing.T) {
+	launcher, _, editDialog, permDialog := newTestLauncher(t)
+	launcher.Init()
+	editDialog.returnOk = true
+	editDialog.returnValues = map[string]string{"API_KEY": "secret"}
+	launcher.EditEnvs("/path/to/app")
+	identity, _ := launcher.Identity()
+
+	permDialog.returnGranted = false
+	data, err := launcher.Share("/path/to/app", permissiondialog.CallerInfo{}, ShareOptions{Recipients: []string{identity.Recipient}})
+
+	if !errors.Is(err, ErrPermissionDenied) || data != nil {
+		t.Errorf("expected ErrPermissionDenied, got %v", err)
+	}
+}
+
+func TestShare_RejectsInvalidRecipient(t *testing.T) {
+	launcher, _, _, permDialog := newTestLauncher(t)
+	launcher.Init()
+
+	_, err := launcher.Share("/path/to/app", permissiondialog.CallerInfo{}, ShareOptions{Recipients: []string{"ssh-ed25519 AAAA"}})
+
+	if err == nil {
+		t.Error("expected error for invalid recipient")
+	}
+	if permDialog.receivedAppPath != "" {
+		t.Error("expected no permission request")
+	}
+}
+
+func TestOpenBundle_RejectsUnexpectedSender(t *testing.T) {
+	alice, _, aliceEdit, alicePermission := newTestLauncher(t)
+	alice.Init()
+	aliceEdit.returnOk = true
+	aliceEdit.returnValues = map[string]string{"API_KEY": "secret"}
+	alice.EditEnvs("/path/to/app")
+	bob, _, _, _ := newTestLauncher(t)
+	bob.Init()
+	bobIdentity, _ := bob.Identity()
+	alicePermission.returnGranted = true
+	data, _ := alice.Share("/path/to/app", permissiondialog.CallerInfo{}, ShareOptions{Recipients: []string{bobIdentity.Recipient}})
+
+	_, err := bob.OpenBundle(data, OpenOptions{From: bobIdentity.Fingerprint})
+
+	if err == nil || !strings.Contains(err.Error(), "signed by") {
+		t.Errorf("expected sender mismatch error, got %v", err)
+	}
+}
+
+func TestOpenBundle_UsesGivenAgeIdentity(t *testing.T) {
+	launcher, _, editDialog, permDialog := newTestLauncher(t)
+	launcher.Init()
+	editDialog.returnOk = true
+	editDialog.returnValues = map[string]string{"API_KEY": "secret"}
+	launcher.EditEnvs("/path/to/app")
+	ageKey, _ := ecdh.X25519().GenerateKey(rand.Reader)
+	permDialog.returnGranted = true
+	data, _ := launcher.Share("/path/to/app", permissiondialog.CallerInfo{}, ShareOptions{Recipients: []string{bundle.FormatRecipient(ageKey.PublicKey())}})
+
+	if _, err := launcher.OpenBundle(data, OpenOptions{}); !errors.Is(err, bundle.ErrNotRecipient) {
+		t.Errorf("expected local identity not to open the bundle, got %v", err)
+	}
+	opened, err := launcher.OpenBundle(data, OpenOptions{Identity: "# age-keygen\n" + bundle.FormatIdentity(ageKey) + "\n"})
+	if err != nil || opened.Values["API_KEY"] != "secret" {
+		t.Errorf("expected given identity to open the bundle, got %v, %v", opened, err)
+	}
+}
+
+func TestIdentity_IsStoredEncrypted(t *testing.T) {
+	launcher, _, _, _ := newTestLauncher(t)
+	launcher.Init()
+
+	first, _ := launcher.Identity()
+	second, _ := launcher.Identity()
+
+	if first != second {
+		t.Errorf("expected identity to be kept, got %v and %v", first, second)
+	}
+	var file identityFile
+	launcher.loadJSON("identity.json", &file)
+	if strings.Contains(file.Identity, "AGE-SECRET-KEY") {
+		t.Error("expected identity to be encrypted")
+	}
+}
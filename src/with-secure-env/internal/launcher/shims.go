@@ -0,0 +1,183 @@
+package launcher
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// shimMarker identifies files written by InstallShim, so foreign executables
+// are never overwritten or removed.
+const shimMarker = "# with-secure-env shim for "
+
+// Shim is an executable wrapper launching an application through
+// with-secure-env.
+type Shim struct {
+	Path            string `json:"-"`
+	ApplicationPath string `json:"app"`
+	// Warnings explain why invoking the shim by name might not run it, e.g.
+	// because another executable comes first in PATH.
+	Warnings []string `json:"-"`
+}
+
+// ShimOptions describe a shim to install.
+type ShimOptions struct {
+	// BinDir is the directory to write the shim to, e.g. ~/.local/bin.
+	BinDir string
+	// Name is the file name of the shim. Defaults to the application's file
+	// name.
+	Name string
+	// Executable is the with-secure-env binary the shim calls.
+	Executable string
+}
+
+// InstallShim writes an executable into options.BinDir that launches the
+// application with all its arguments. Symlinks are resolved first, so the shim
+// keeps launching the same binary even if the link is changed later.
+func (l *Launcher) InstallShim(applicationPath string, options ShimOptions) (Shim, error) {
+	if strings.ContainsAny(applicationPath, "\n\r") {
+		return Shim{}, fmt.Errorf("application path %q contains a line break", applicationPath)
+	}
+	if resolved, err := filepath.EvalSymlinks(applicationPath); err == nil && resolved != applicationPath {
+		if len(l.Explain(resolved).Matches) == 0 {
+			return Shim{}, fmt.Errorf("no envs configured for %s (symlink target of %s)", resolved, applicationPath)
+		}
+		applicationPath = resolved
+	}
+	if len(l.Explain(applicationPath).Matches) == 0 {
+		return Shim{}, fmt.Errorf("no envs configured for %s", applicationPath)
+	}
+	name := options.Name
+	if name == "" {
+		name = filepath.Base(applicationPath)
+	}
+	if name == "" || strings.ContainsRune(name, '/') {
+		return Shim{}, fmt.Errorf("invalid shim name %q", name)
+	}
+	shimPath := filepath.Join(options.BinDir, name)
+	if shimPath == applicationPath {
+		return Shim{}, fmt.Errorf("shim would replace the application %s itself", applicationPath)
+	}
+	if _, err := os.Stat(shimPath); err == nil && !isShim(shimPath) {
+		return Shim{}, fmt.Errorf("%s already exists and is not a with-secure-env shim", shimPath)
+	}
+
+	if err := os.MkdirAll(options.BinDir, 0755); err != nil {
+		return Shim{}, err
+	}
+	script := "#!/bin/sh\n" +
+		shimMarker + shellQuote(applicationPath) + "\n" +
+		"exec " + shellQuote(options.Executable) + " launch " + shellQuote(applicationPath) + " \"$@\"\n"
+	if err := os.WriteFile(shimPath, []byte(script), 0755); err != nil {
+		return Shim{}, err
+	}
+
+	shims := l.loadShims()
+	shims[shimPath] = Shim{ApplicationPath: applicationPath}
+	l.saveShims(shims)
+
+	l.audit(AuditEntry{Action: "shim-install", Caller: callerChain(l.Caller), App: applicationPath, Args: []string{shimPath}})
+	shim := Shim{Path: shimPath, ApplicationPath: applicationPath}
+	shim.Warnings = l.shimWarnings(shim)
+	return shim, nil
+}
+
+// Shims returns all installed shims sorted by path.
+func (l *Launcher) Shims() []Shim {
+	var result []Shim
+	shims := l.loadShims()
+	for _, path := range sortedNames(shims) {
+		shim := shims[path]
+		shim.Path = path
+		shim.Warnings = l.shimWarnings(shim)
+		result = append(result, shim)
+	}
+	return result
+}
+
+// RemoveShim deletes an installed shim, given by path or by name if only one
+// shim has that name.
+func (l *Launcher) RemoveShim(pathOrName string) error {
+	shims := l.loadShims()
+	shimPath := pathOrName
+	if !strings.ContainsRune(pathOrName, '/') {
+		var candidates []string
+		for path := range shims {
+			if filepath.Base(path) == pathOrName {
+				candidates = append(candidates, path)
+			}
+		}
+		if len(candidates) > 1 {
+			sort.Strings(candidates)
+			return fmt.Errorf("several shims are named %s, specify the path: %s", pathOrName, strings.Join(candidates, ", "))
+		}
+		if len(candidates) == 1 {
+			shimPath = candidates[0]
+		}
+	}
+	shim, ok := shims[shimPath]
+	if !ok {
+		return fmt.Errorf("no shim installed at %s", pathOrName)
+	}
+
+	if isShim(shimPath) {
+		if err := os.Remove(shimPath); err != nil {
+			return err
+		}
+	}
+	delete(shims, shimPath)
+	l.saveShims(shims)
+
+	l.audit(AuditEntry{Action: "shim-remove", Caller: callerChain(l.Caller), App: shim.ApplicationPath, Args: []string{shimPath}})
+	return nil
+}
+
+// shimWarnings checks whether invoking the shim by name finds it in PATH.
+func (l *Launcher) shimWarnings(shim Shim) []string {
+	if !isShim(shim.Path) {
+		return []string{"shim file is missing or was replaced"}
+	}
+
+	name := filepath.Base(shim.Path)
+	shimDir := filepath.Dir(shim.Path)
+	for _, dir := range filepath.SplitList(l.getenv("PATH")) {
+		if dir == "" {
+			dir = "."
+		}
+		if filepath.Clean(dir) == shimDir {
+			return nil
+		}
+		candidate := filepath.Join(dir, name)
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() && info.Mode()&0111 != 0 {
+			return []string{fmt.Sprintf("shadowed by %s, which comes first in PATH", candidate)}
+		}
+	}
+	return []string{fmt.Sprintf("%s is not in PATH", shimDir)}
+}
+
+func isShim(path string) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+	header := make([]byte, 4096)
+	n, _ := file.Read(header)
+	return strings.HasPrefix(string(header[:n]), "#!/bin/sh\n"+shimMarker)
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func (l *Launcher) loadShims() map[string]Shim {
+	shims := map[string]Shim{}
+	l.loadJSON("shims.json", &shims)
+	return shims
+}
+
+func (l *Launcher) saveShims(shims map[string]Shim) {
+	l.saveJSON("shims.json", shims)
+}
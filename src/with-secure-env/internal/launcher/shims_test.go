@@ -0,0 +1,143 @@
+package launcher
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInstallShim_WritesWrapperCallingLaunch(t *testing.T) {
+	launcher := newShimTestLauncher(t)
+	binDir := t.TempDir()
+	recorder := filepath.Join(t.TempDir(), "with-secure-env")
+	os.WriteFile(recorder, []byte("#!/bin/sh\nprintf '%s|' \"$@\"\n"), 0755)
+
+	shim, err := launcher.InstallShim("/path/to/app", ShimOptions{BinDir: binDir, Executable: recorder})
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if shim.Path != filepath.Join(binDir, "app") {
+		t.Errorf("expected shim named after the application, got %s", shim.Path)
+	}
+	output, err := exec.Command(shim.Path, "--flag", "two words").Output()
+	if err != nil {
+		t.Fatalf("failed to run shim: %v", err)
+	}
+	if string(output) != "launch|/path/to/app|--flag|two words|" {
+		t.Errorf("expected shim to call launch with all args, got %q", output)
+	}
+}
+
+func TestInstallShim_ResolvesSymlinksAndQuotesPath(t *testing.T) {
+	launcher := newShimTestLauncher(t)
+	appDir, _ := filepath.EvalSymlinks(t.TempDir())
+	target := filepath.Join(appDir, "it's app")
+	os.WriteFile(target, []byte("#!/bin/sh\n"), 0755)
+	link := filepath.Join(appDir, "link")
+	os.Symlink(target, link)
+	launcher.EditEnvs(target)
+	recorder := filepath.Join(t.TempDir(), "with-secure-env")
+	os.WriteFile(recorder, []byte("#!/bin/sh\nprintf '%s|' \"$@\"\n"), 0755)
+
+	shim, err := launcher.InstallShim(link, ShimOptions{BinDir: t.TempDir(), Executable: recorder})
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if shim.ApplicationPath != target {
+		t.Errorf("expected symlink target %s, got %s", target, shim.ApplicationPath)
+	}
+	output, err := exec.Command(shim.Path).Output()
+	if err != nil {
+		t.Fatalf("failed to run shim: %v", err)
+	}
+	if string(output) != "launch|"+target+"|" {
+		t.Errorf("expected shim to launch the symlink target, got %q", output)
+	}
+
+	_, err = launcher.InstallShim("/path/to/app\nrm -rf ~", ShimOptions{BinDir: t.TempDir(), Executable: recorder})
+
+	if err == nil {
+		t.Error("expected error for path with line break")
+	}
+}
+
+func TestInstallShim_FailsForUnconfiguredApplication(t *testing.T) {
+	launcher := newShimTestLauncher(t)
+
+	_, err := launcher.InstallShim("/path/to/unknown", ShimOptions{BinDir: t.TempDir(), Executable: "/bin/true"})
+
+	if err == nil {
+		t.Error("expected error for application without envs")
+	}
+}
+
+func TestInstallShim_DoesNotOverwriteForeignExecutable(t *testing.T) {
+	launcher := newShimTestLauncher(t)
+	binDir := t.TempDir()
+	os.WriteFile(filepath.Join(binDir, "app"), []byte("#!/bin/sh\necho real\n"), 0755)
+
+	_, err := launcher.InstallShim("/path/to/app", ShimOptions{BinDir: binDir, Executable: "/bin/true"})
+
+	if err == nil {
+		t.Error("expected error for existing executable")
+	}
+	data, _ := os.ReadFile(filepath.Join(binDir, "app"))
+	if !strings.Contains(string(data), "echo real") {
+		t.Error("expected existing executable to be kept")
+	}
+}
+
+func TestShims_WarnsWhenShimIsShadowedOrNotInPath(t *testing.T) {
+	launcher := newShimTestLauncher(t)
+	binDir := t.TempDir()
+	earlierDir := t.TempDir()
+	os.WriteFile(filepath.Join(earlierDir, "app"), []byte("#!/bin/sh\n"), 0755)
+	launcher.InstallShim("/path/to/app", ShimOptions{BinDir: binDir, Executable: "/bin/true"})
+
+	launcher.Environ = func() []string { return []string{"PATH=" + earlierDir + ":" + binDir} }
+	shims := launcher.Shims()
+	if len(shims) != 1 || len(shims[0].Warnings) != 1 || !strings.Contains(shims[0].Warnings[0], "shadowed by "+earlierDir) {
+		t.Errorf("expected shadowing warning, got %+v", shims)
+	}
+
+	launcher.Environ = func() []string { return []string{"PATH=" + binDir + ":" + earlierDir} }
+	if shims := launcher.Shims(); len(shims[0].Warnings) != 0 {
+		t.Errorf("expected no warnings, got %v", shims[0].Warnings)
+	}
+
+	launcher.Environ = func() []string { return []string{"PATH=/usr/bin"} }
+	if shims := launcher.Shims(); !containsWarning(shims[0].Warnings, "not in PATH") {
+		t.Errorf("expected not in PATH warning, got %v", shims[0].Warnings)
+	}
+}
+
+func TestRemoveShim_DeletesShimByName(t *testing.T) {
+	launcher := newShimTestLauncher(t)
+	binDir := t.TempDir()
+	shim, _ := launcher.InstallShim("/path/to/app", ShimOptions{BinDir: binDir, Name: "myapp", Executable: "/bin/true"})
+
+	err := launcher.RemoveShim("myapp")
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, err := os.Stat(shim.Path); !os.IsNotExist(err) {
+		t.Errorf("expected shim file to be removed, got %v", err)
+	}
+	if len(launcher.Shims()) != 0 {
+		t.Errorf("expected no shims, got %+v", launcher.Shims())
+	}
+}
+
+func newShimTestLauncher(t *testing.T) *Launcher {
+	launcher, _, editDialog, _ := newTestLauncher(t)
+	launcher.Init()
+	editDialog.returnOk = true
+	editDialog.returnValues = map[string]string{"API_KEY": "secret"}
+	launcher.EditEnvs("/path/to/app")
+	return launcher
+}
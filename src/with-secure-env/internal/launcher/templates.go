@@ -0,0 +1,99 @@
+package launcher
+
+import (
+	"strings"
+
+	"github.com/kfischer-okarin/with-secure-env/internal/envcrypt"
+	"github.com/kfischer-okarin/with-secure-env/internal/envtemplate"
+)
+
+// templatePrefix marks ciphertexts of values entered with ${NAME} references,
+// so they are expanded and the permission dialog can show derived envs before
+// anything is decrypted. Values without it are always used literally.
+const templatePrefix = "template:"
+
+// derivedNames returns the names of the envs whose values reference others.
+func derivedNames(encryptedEnvs map[string]string) []string {
+	var names []string
+	for _, name := range sortedNames(encryptedEnvs) {
+		if strings.HasPrefix(encryptedEnvs[name], templatePrefix) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// encryptEdited encrypts the value of name returned by an edit dialog.
+// Unchanged values keep their ciphertext, so imported values stay literal.
+// Entered values with references are marked as template, all others are
+// stored literally without escapes.
+func (l *Launcher) encryptEdited(key []byte, currentEnvs map[string]string, currentValues map[string]string, name string, value string) string {
+	if current, ok := currentValues[name]; ok && current == value {
+		return currentEnvs[name]
+	}
+	if envtemplate.HasReferences(value) {
+		return templatePrefix + envcrypt.Encrypt(key, value)
+	}
+	return l.encrypt(key, envtemplate.Unescape(value))
+}
+
+// reencrypt decrypts and encrypts a value again, keeping its template marker.
+func (l *Launcher) reencrypt(key []byte, encrypted string) string {
+	value := l.encrypt(key, l.decrypt(key, encrypted))
+	if strings.HasPrefix(encrypted, templatePrefix) {
+		return templatePrefix + value
+	}
+	return value
+}
+
+// templateScope decrypts envs for resolving templates, which are recognized
+// by their ciphertexts.
+func (l *Launcher) templateScope(key []byte, encryptedEnvs map[string]string) envtemplate.Scope {
+	scope := envtemplate.Scope{Values: l.decryptEnvs(key, encryptedEnvs), Templates: map[string]bool{}}
+	for name, encrypted := range encryptedEnvs {
+		if strings.HasPrefix(encrypted, templatePrefix) {
+			scope.Templates[name] = true
+		}
+	}
+	return scope
+}
+
+// entryScope returns the template scope of envs merged by mergeGroupEnvs.
+// Values taken from a group resolve within that group; they are recognized by
+// their ciphertext, since the application's own envs have different ones.
+func (l *Launcher) entryScope(key []byte, settings AppSettings, encryptedEnvs map[string]string) envtemplate.Scope {
+	scope := l.templateScope(key, encryptedEnvs)
+	scope.Groups = map[string]string{}
+	groups := l.loadGroups()
+	for _, group := range settings.Groups {
+		for name, encrypted := range groups[group] {
+			if encryptedEnvs[name] == encrypted {
+				scope.Groups[name] = group
+			}
+		}
+	}
+	return scope
+}
+
+// overrideScope returns scope with the values of other replacing its own.
+func overrideScope(scope envtemplate.Scope, other envtemplate.Scope) envtemplate.Scope {
+	for name, value := range other.Values {
+		scope.Values[name] = value
+		scope.Templates[name] = other.Templates[name]
+		delete(scope.Groups, name)
+	}
+	return scope
+}
+
+// resolveTemplates returns the values of names in scope with the ${NAME} and
+// ${group:NAME} references of templates replaced.
+func (l *Launcher) resolveTemplates(key []byte, scope envtemplate.Scope, names []string) (map[string]string, error) {
+	groups := l.loadGroups()
+	return envtemplate.Resolve(scope, names, func(group string) (envtemplate.Scope, bool) {
+		encryptedEnvs, ok := groups[group]
+		if !ok {
+			return envtemplate.Scope{}, false
+		}
+		return l.templateScope(key, encryptedEnvs), true
+	})
+}
@@ -0,0 +1,145 @@
+package launcher
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kfischer-okarin/with-secure-env/internal/permissiondialog"
+)
+
+func TestLaunch_ResolvesReferencesAfterDecryption(t *testing.T) {
+	launcher, _, editDialog, permDialog := newTestLauncher(t)
+	launcher.Init()
+	editDialog.returnOk = true
+	editDialog.returnValues = map[string]string{"PASSWORD": "cache-secret"}
+	launcher.EditGroup("cache")
+	editDialog.returnValues = map[string]string{
+		"DB_USER":      "app",
+		"DB_PASSWORD":  "secret",
+		"DATABASE_URL": "postgres://${DB_USER}:${DB_PASSWORD}@db/app",
+		"REDIS_URL":    "redis://:${cache:PASSWORD}@cache",
+		"LITERAL":      "$${NOT_A_REFERENCE}",
+	}
+	launcher.EditEnvs("/path/to/app")
+	var executedEnv []string
+	launcher.Exec = func(process Process) (ExitStatus, error) {
+		executedEnv = process.Env
+		return ExitStatus{}, nil
+	}
+
+	permDialog.returnGranted = true
+	_, err := launcher.Launch("/path/to/app", nil, permissiondialog.CallerInfo{})
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for _, expected := range []string{"DATABASE_URL=postgres://app:secret@db/app", "REDIS_URL=redis://:cache-secret@cache", "LITERAL=${NOT_A_REFERENCE}"} {
+		if !containsEnv(executedEnv, expected) {
+			t.Errorf("expected %s, got %v", expected, executedEnv)
+		}
+	}
+	derived := permDialog.receivedRequest.Derived
+	if len(derived) != 2 || derived[0] != "DATABASE_URL" || derived[1] != "REDIS_URL" {
+		t.Errorf("expected DATABASE_URL and REDIS_URL to be shown as derived, got %v", derived)
+	}
+}
+
+func TestLaunch_FailsForReferenceCycle(t *testing.T) {
+	launcher, _, editDialog, permDialog := newTestLauncher(t)
+	launcher.Init()
+	editDialog.returnOk = true
+	editDialog.returnValues = map[string]string{"A": "${B}", "B": "${A}"}
+	launcher.EditEnvs("/path/to/app")
+	executed := false
+	launcher.Exec = func(process Process) (ExitStatus, error) {
+		executed = true
+		return ExitStatus{}, nil
+	}
+
+	permDialog.returnGranted = true
+	_, err := launcher.Launch("/path/to/app", nil, permissiondialog.CallerInfo{})
+
+	if err == nil || !strings.Contains(err.Error(), "reference cycle") {
+		t.Errorf("expected cycle error, got %v", err)
+	}
+	if executed {
+		t.Error("expected application not to be launched")
+	}
+}
+
+func TestMove_KeepsTemplateMarker(t *testing.T) {
+	launcher, _, editDialog, _ := newTestLauncher(t)
+	launcher.Init()
+	editDialog.returnOk = true
+	editDialog.returnValues = map[string]string{"USER": "app", "URL": "https://${USER}@host"}
+	launcher.EditEnvs("/old/app")
+
+	launcher.Move("/old/app", "/new/app")
+
+	if derived := derivedNames(launcher.loadFileContent()["/new/app"]); len(derived) != 1 || derived[0] != "URL" {
+		t.Errorf("expected URL to stay derived, got %v", derived)
+	}
+}
+
+func TestLaunchExportFetch_KeepImportedValuesLiteral(t *testing.T) {
+	launcher, _, _, permDialog := newTestLauncher(t)
+	launcher.Init()
+	launcher.Import("/path/to/app", "", map[string]string{"PASSWORD": "pa${ss$${word}"})
+	var executedEnv []string
+	launcher.Exec = func(process Process) (ExitStatus, error) {
+		executedEnv = process.Env
+		return ExitStatus{}, nil
+	}
+	permDialog.returnGranted = true
+
+	if _, err := launcher.Launch("/path/to/app", nil, permissiondialog.CallerInfo{}); err != nil || !containsEnv(executedEnv, "PASSWORD=pa${ss$${word}") {
+		t.Errorf("expected literal value to be injected, got %v, %v", executedEnv, err)
+	}
+	if data, err := launcher.Export("/path/to/app", permissiondialog.CallerInfo{}, ExportOptions{Format: "json"}); err != nil || !strings.Contains(string(data), `"pa${ss$${word}"`) {
+		t.Errorf("expected literal value to be exported, got %s, %v", data, err)
+	}
+	if values, err := launcher.Fetch("/path/to/app", []string{"PASSWORD"}, permissiondialog.CallerInfo{}); err != nil || values["PASSWORD"] != "pa${ss$${word}" {
+		t.Errorf("expected literal value to be fetched, got %v, %v", values, err)
+	}
+	if derived := permDialog.receivedRequest.Derived; len(derived) != 0 {
+		t.Errorf("expected no derived envs, got %v", derived)
+	}
+}
+
+func TestFetch_IgnoresTemplatesOfOtherEnvs(t *testing.T) {
+	launcher, _, editDialog, permDialog := newTestLauncher(t)
+	launcher.Init()
+	editDialog.returnOk = true
+	editDialog.returnValues = map[string]string{"API_KEY": "secret", "BROKEN": "${MISSING}"}
+	launcher.EditEnvs("/path/to/app")
+	permDialog.returnGranted = true
+
+	values, err := launcher.Fetch("/path/to/app", []string{"API_KEY"}, permissiondialog.CallerInfo{})
+
+	if err != nil || values["API_KEY"] != "secret" || len(values) != 1 {
+		t.Errorf("unexpected result %v, %v", values, err)
+	}
+}
+
+func TestLaunch_ResolvesGroupValuesInTheirGroup(t *testing.T) {
+	launcher, _, editDialog, permDialog := newTestLauncher(t)
+	launcher.Init()
+	editDialog.returnOk = true
+	editDialog.returnValues = map[string]string{"DB_PASSWORD": "group-secret", "DATABASE_URL": "postgres://app:${DB_PASSWORD}@db"}
+	launcher.EditGroup("db")
+	launcher.Configure("/path/to/app", "groups", "db")
+	editDialog.returnValues = map[string]string{"DB_PASSWORD": "app-secret"}
+	launcher.EditEnvs("/path/to/app")
+	var executedEnv []string
+	launcher.Exec = func(process Process) (ExitStatus, error) {
+		executedEnv = process.Env
+		return ExitStatus{}, nil
+	}
+	permDialog.returnGranted = true
+
+	launcher.Launch("/path/to/app", nil, permissiondialog.CallerInfo{})
+
+	if !containsEnv(executedEnv, "DATABASE_URL=postgres://app:group-secret@db") || !containsEnv(executedEnv, "DB_PASSWORD=app-secret") {
+		t.Errorf("expected group value to resolve in its group, got %v", executedEnv)
+	}
+}
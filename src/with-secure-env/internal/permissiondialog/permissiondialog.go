@@ -4,11 +4,52 @@ package permissiondialog
 type CallerInfo struct {
 	Name string
 	PID  int
+	// Executable is the resolved path of the caller's executable, empty if
+	// unknown. Unlike Name it can't be chosen by the caller.
+	Executable string
+	// Parents lists the ancestors of the caller, closest first.
+	Parents []CallerInfo
+}
+
+// Request describes a launch the user has to approve.
+type Request struct {
+	ApplicationPath string
+	Args            []string
+	EnvNames        []string
+	Caller          CallerInfo
+	// Profile is the name of the injected profile of the application.
+	Profile string
+	// Production is set if the profile holds production credentials.
+	Production bool
+	// Groups are the shared groups whose envs are injected as well.
+	Groups []string
+	// Export is the output format if the values are requested as plaintext
+	// instead of launching the application.
+	Export string
+	// Credential names the credential helper protocol (e.g. "git") if the
+	// values are handed to a tool as credentials instead of launching an
+	// application. Args are then the operation and the requested URL or host.
+	Credential string
+	// Recipients are set if the values are encrypted into a bundle for these
+	// age recipients instead of launching an application.
+	Recipients []string
+	// Derived are the env names whose values are assembled from other values
+	// with ${NAME} references.
+	Derived []string
+	// Project is the path of the project file whose envs are injected as
+	// well.
+	Project string
+	// MatchedEntry is the glob or directory entry providing the envs, empty
+	// for an entry of the application path itself.
+	MatchedEntry string
+	// Warnings are shown prominently above the details, e.g. about variables
+	// shadowing each other.
+	Warnings []string
 }
 
 // PermissionDialog asks the user for permission to inject environment variables.
 type PermissionDialog interface {
-	// AskPermission shows a dialog asking whether to inject the given env names
-	// into the application. Returns true if the user grants permission.
-	AskPermission(applicationPath string, args []string, envNames []string, caller CallerInfo) bool
+	// AskPermission shows a dialog asking whether to inject the requested env
+	// names into the application. Returns true if the user grants permission.
+	AskPermission(request Request) bool
 }
@@ -12,7 +12,7 @@ import (
 
 type WebViewPermissionDialog struct{}
 
-func (d *WebViewPermissionDialog) AskPermission(applicationPath string, args []string, envNames []string, caller CallerInfo) bool {
+func (d *WebViewPermissionDialog) AskPermission(request Request) bool {
 	runtime.LockOSThread()
 
 	allowed := false
@@ -33,9 +33,18 @@ func (d *WebViewPermissionDialog) AskPermission(applicationPath string, args []s
 		w.Terminate()
 	})
 
-	argsJSON, _ := json.Marshal(args)
-	envNamesJSON, _ := json.Marshal(envNames)
-	html := buildPermissionHTML(applicationPath, string(argsJSON), string(envNamesJSON), caller.Name, strconv.Itoa(caller.PID))
+	argsJSON, _ := json.Marshal(request.Args)
+	envNamesJSON, _ := json.Marshal(request.EnvNames)
+	warningsJSON, _ := json.Marshal(request.Warnings)
+	profileJSON, _ := json.Marshal(request.Profile)
+	groupsJSON, _ := json.Marshal(request.Groups)
+	matchedEntryJSON, _ := json.Marshal(request.MatchedEntry)
+	exportJSON, _ := json.Marshal(request.Export)
+	credentialJSON, _ := json.Marshal(request.Credential)
+	derivedJSON, _ := json.Marshal(request.Derived)
+	recipientsJSON, _ := json.Marshal(request.Recipients)
+	projectJSON, _ := json.Marshal(request.Project)
+	html := buildPermissionHTML(request.ApplicationPath, string(argsJSON), string(envNamesJSON), string(derivedJSON), string(warningsJSON), string(profileJSON), string(groupsJSON), string(matchedEntryJSON), string(exportJSON), string(credentialJSON), string(recipientsJSON), string(projectJSON), request.Production, request.Caller.Name, strconv.Itoa(request.Caller.PID))
 	w.SetHtml(html)
 
 	w.Run()
@@ -43,7 +52,12 @@ func (d *WebViewPermissionDialog) AskPermission(applicationPath string, args []s
 	return allowed
 }
 
-func buildPermissionHTML(applicationPath string, argsJSON string, envNamesJSON string, callerName string, callerPID string) string {
+func buildPermissionHTML(applicationPath string, argsJSON string, envNamesJSON string, derivedJSON string, warningsJSON string, profileJSON string, groupsJSON string, matchedEntryJSON string, exportJSON string, credentialJSON string, recipientsJSON string, projectJSON string, production bool, callerName string, callerPID string) string {
+	bodyClass := ""
+	if production {
+		bodyClass = "production"
+	}
+
 	return `<!DOCTYPE html>
 <html>
 <head>
@@ -92,6 +106,21 @@ h1 {
 	margin-bottom: 20px;
 	line-height: 1.4;
 }
+.warnings {
+	background: #fff4e5;
+	border: 1px solid #ff9500;
+	border-radius: 8px;
+	padding: 12px;
+	margin-bottom: 12px;
+	font-size: 13px;
+	color: #1d1d1f;
+}
+.warnings:empty {
+	display: none;
+}
+.warning + .warning {
+	margin-top: 6px;
+}
 .section {
 	background: white;
 	border-radius: 8px;
@@ -120,6 +149,50 @@ h1 {
 	flex-wrap: wrap;
 	gap: 6px;
 }
+.profile-tag {
+	display: inline-block;
+	background: #e5e5ea;
+	padding: 4px 8px;
+	border-radius: 4px;
+	font-family: ui-monospace, monospace;
+	font-size: 12px;
+}
+.production-badge {
+	display: none;
+	margin-left: 8px;
+	background: #ff3b30;
+	color: white;
+	padding: 4px 8px;
+	border-radius: 4px;
+	font-size: 11px;
+	font-weight: 700;
+	letter-spacing: 0.5px;
+}
+body.production .production-badge {
+	display: inline-block;
+}
+body.production .shield {
+	background: #ff3b30;
+}
+body.production .profile-section {
+	border: 2px solid #ff3b30;
+}
+.matched-entry {
+	margin-top: 6px;
+	font-size: 12px;
+	color: #8e8e93;
+}
+.matched-entry:empty {
+	display: none;
+}
+.groups {
+	margin-top: 8px;
+	font-size: 12px;
+	color: #8e8e93;
+}
+.groups:empty {
+	display: none;
+}
 .env-tag {
 	background: #e5e5ea;
 	padding: 4px 8px;
@@ -127,6 +200,11 @@ h1 {
 	font-family: ui-monospace, monospace;
 	font-size: 12px;
 }
+
+.env-tag.derived {
+	background: #e8f0fe;
+	font-style: italic;
+}
 .caller-info {
 	display: flex;
 	gap: 20px;
@@ -169,17 +247,19 @@ button {
 }
 </style>
 </head>
-<body>
+<body class="` + bodyClass + `">
 <div class="content">
 	<div class="header">
 		<div class="shield">🛡️</div>
-		<h1>Permission Required</h1>
+		<h1 id="title">Permission Required</h1>
 	</div>
-	<p class="description">
+	<p class="description" id="description">
 		An application is requesting to launch with secure environment variables.
 		Review the details below and decide whether to allow this action.
 	</p>
 
+	<div class="warnings" id="warnings"></div>
+
 	<div class="section">
 		<div class="section-title">Requested By</div>
 		<div class="caller-info">
@@ -197,6 +277,14 @@ button {
 	<div class="section">
 		<div class="section-title">Command</div>
 		<div class="section-content mono" id="commandContent"></div>
+		<div class="matched-entry" id="matchedEntry"></div>
+	</div>
+
+	<div class="section profile-section">
+		<div class="section-title">Profile</div>
+		<span class="profile-tag" id="profile"></span><span class="production-badge">PRODUCTION</span>
+		<div class="groups" id="groups"></div>
+		<div class="groups" id="project"></div>
 	</div>
 
 	<div class="section">
@@ -214,18 +302,76 @@ button {
 const applicationPath = ` + "`" + applicationPath + "`" + `;
 const args = ` + argsJSON + `;
 const envNames = ` + envNamesJSON + `;
+const derived = ` + derivedJSON + ` || [];
+const warnings = ` + warningsJSON + ` || [];
+const profile = ` + profileJSON + `;
+const groups = ` + groupsJSON + ` || [];
+const matchedEntry = ` + matchedEntryJSON + `;
+const exportFormat = ` + exportJSON + `;
+const credential = ` + credentialJSON + `;
+const recipients = ` + recipientsJSON + ` || [];
+const project = ` + projectJSON + `;
 
 const commandParts = [applicationPath, ...args];
 document.getElementById('commandContent').textContent = commandParts.join(' ');
 
+if (exportFormat) {
+	document.getElementById('title').textContent = 'Plaintext Export Requested';
+	document.getElementById('description').textContent =
+		'A process is requesting the secure environment variables of this application as plaintext (' +
+		exportFormat + '). Only allow this if you expect the values to leave the encrypted storage.';
+	document.getElementById('commandContent').textContent = 'export --format ' + exportFormat + ' ' + applicationPath;
+}
+
+if (credential) {
+	document.getElementById('title').textContent = 'Credentials Requested';
+	document.getElementById('description').textContent =
+		'A ' + credential + ' process is requesting the credentials stored for ' + applicationPath +
+		'. Only allow this if you just ran a ' + credential + ' command that needs them.';
+	document.getElementById('commandContent').textContent = credential + ' credential ' + args.join(' ');
+}
+
+if (recipients.length > 0) {
+	document.getElementById('title').textContent = 'Sharing Requested';
+	document.getElementById('description').textContent =
+		'A process is requesting the secure environment variables of this application encrypted for ' +
+		recipients.length + ' recipient(s). Anyone holding one of their keys can read the values.';
+	document.getElementById('commandContent').textContent = 'share ' + applicationPath + ' ' + recipients.map(r => '--to ' + r).join(' ');
+}
+
+if (matchedEntry) {
+	document.getElementById('matchedEntry').textContent = 'Secrets of entry ' + matchedEntry + ' (matches this application)';
+}
+
+document.getElementById('profile').textContent = profile;
+if (groups.length > 0) {
+	document.getElementById('groups').textContent = '+ shared groups: ' + groups.join(', ');
+}
+if (project) {
+	document.getElementById('project').textContent = '+ project secrets: ' + project;
+}
+
 const envList = document.getElementById('envList');
 envNames.forEach(name => {
 	const tag = document.createElement('span');
 	tag.className = 'env-tag';
 	tag.textContent = name;
+	if (derived.includes(name)) {
+		tag.className += ' derived';
+		tag.textContent = name + ' (derived)';
+		tag.title = 'Assembled from other values with ${NAME} references';
+	}
 	envList.appendChild(tag);
 });
 
+const warningList = document.getElementById('warnings');
+warnings.forEach(text => {
+	const warning = document.createElement('div');
+	warning.className = 'warning';
+	warning.textContent = '⚠️ ' + text;
+	warningList.appendChild(warning);
+});
+
 function doAllow() {
 	window.allow().then(() => {});
 }
@@ -0,0 +1,19 @@
+// Package procpath identifies processes by the executable they run, which,
+// unlike their name, a process can't choose.
+package procpath
+
+import "os"
+
+// SameExecutable reports whether the process pid runs executable.
+func SameExecutable(pid int, executable string) bool {
+	path, err := Executable(pid)
+	if err != nil {
+		return false
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	other, err := os.Stat(executable)
+	return err == nil && os.SameFile(info, other)
+}
@@ -0,0 +1,28 @@
+package procpath
+
+import (
+	"bytes"
+	"syscall"
+	"unsafe"
+)
+
+// From <sys/proc_info.h>.
+const (
+	procInfoCallPIDInfo = 2
+	procPIDPathInfo     = 11
+	procPIDPathInfoSize = 4 * 1024
+)
+
+// Executable returns the resolved path of the executable of the process pid,
+// like proc_pidpath.
+func Executable(pid int) (string, error) {
+	buffer := make([]byte, procPIDPathInfoSize)
+	_, _, errno := syscall.Syscall6(syscall.SYS_PROC_INFO, procInfoCallPIDInfo, uintptr(pid), procPIDPathInfo, 0, uintptr(unsafe.Pointer(&buffer[0])), uintptr(len(buffer)))
+	if errno != 0 {
+		return "", errno
+	}
+	if end := bytes.IndexByte(buffer, 0); end >= 0 {
+		buffer = buffer[:end]
+	}
+	return string(buffer), nil
+}
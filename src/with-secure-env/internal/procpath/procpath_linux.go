@@ -0,0 +1,11 @@
+package procpath
+
+import (
+	"fmt"
+	"os"
+)
+
+// Executable returns the resolved path of the executable of the process pid.
+func Executable(pid int) (string, error) {
+	return os.Readlink(fmt.Sprintf("/proc/%d/exe", pid))
+}
@@ -0,0 +1,31 @@
+package procpath
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExecutable_ReturnsPathOfRunningExecutable(t *testing.T) {
+	expected, _ := os.Executable()
+	expected, _ = filepath.EvalSymlinks(expected)
+
+	path, err := Executable(os.Getpid())
+
+	if err != nil || path != expected {
+		t.Errorf("expected %s, got %s, %v", expected, path, err)
+	}
+}
+
+func TestSameExecutable_ComparesFiles(t *testing.T) {
+	self, _ := os.Executable()
+	link := filepath.Join(t.TempDir(), "link")
+	os.Symlink(self, link)
+
+	if !SameExecutable(os.Getpid(), link) {
+		t.Error("expected symlink to the executable to match")
+	}
+	if SameExecutable(os.Getpid(), "/bin/sh") {
+		t.Error("expected other executable not to match")
+	}
+}
@@ -0,0 +1,224 @@
+// Package projectfile reads and writes the encrypted secrets file of a
+// project, meant to be committed to the project's repository.
+//
+// The file has one line per recipient and variable, sorted, so diffs show
+// which variables changed and concurrent changes merge line by line:
+//
+//	# with-secure-env project secrets, edit with: with-secure-env edit --project
+//	@key 3f9a0c1d2e4b5a6c
+//	@recipient age1... <ephemeral key> <wrapped data key>
+//	API_KEY=<ciphertext>
+//
+// The package treats key material and ciphertexts as opaque strings.
+package projectfile
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// FileName is the name of a project file.
+const FileName = ".secure-env"
+
+const header = "# with-secure-env project secrets, edit with: with-secure-env edit --project"
+
+// File is the content of a project file.
+type File struct {
+	// KeyID identifies the data key the values are encrypted with.
+	KeyID string
+	// Recipients maps age recipients to the data key wrapped for them.
+	Recipients map[string]string
+	// Values maps env names to ciphertexts.
+	Values map[string]string
+}
+
+// Parse parses a project file.
+func Parse(data []byte) (File, error) {
+	file := File{Recipients: map[string]string{}, Values: map[string]string{}}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, 1024*1024)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+		case strings.HasPrefix(line, "@key "):
+			file.KeyID = strings.TrimSpace(strings.TrimPrefix(line, "@key "))
+		case strings.HasPrefix(line, "@recipient "):
+			recipient, wrapped, ok := strings.Cut(strings.TrimPrefix(line, "@recipient "), " ")
+			if !ok {
+				return File{}, fmt.Errorf("line %d: recipient without key", lineNumber)
+			}
+			file.Recipients[recipient] = strings.TrimSpace(wrapped)
+		case strings.HasPrefix(line, "<<<<<<<") || strings.HasPrefix(line, "=======") || strings.HasPrefix(line, ">>>>>>>"):
+			return File{}, fmt.Errorf("line %d: unresolved merge conflict", lineNumber)
+		default:
+			name, value, ok := strings.Cut(line, "=")
+			if !ok || name == "" {
+				return File{}, fmt.Errorf("line %d: expected NAME=ciphertext", lineNumber)
+			}
+			file.Values[name] = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return File{}, err
+	}
+	if file.KeyID == "" {
+		return File{}, fmt.Errorf("missing @key line")
+	}
+	return file, nil
+}
+
+// Format renders the file with sorted lines.
+func (f File) Format() []byte {
+	var buf bytes.Buffer
+	buf.WriteString(header + "\n")
+	for _, line := range f.lines() {
+		buf.WriteString(line + "\n")
+	}
+	return buf.Bytes()
+}
+
+// lines returns the content lines by key, in file order.
+func (f File) lines() []string {
+	entries := f.entries()
+	keys := make([]string, 0, len(entries))
+	for key := range entries {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return lineOrder(keys[i], keys[j]) })
+	lines := make([]string, len(keys))
+	for i, key := range keys {
+		lines[i] = entries[key]
+	}
+	return lines
+}
+
+// entries maps a key identifying each line to the line.
+func (f File) entries() map[string]string {
+	entries := map[string]string{"@key": "@key " + f.KeyID}
+	for recipient, wrapped := range f.Recipients {
+		entries["@recipient "+recipient] = "@recipient " + recipient + " " + wrapped
+	}
+	for name, value := range f.Values {
+		entries[name] = name + "=" + value
+	}
+	return entries
+}
+
+// lineOrder sorts @key first, then recipients, then variables.
+func lineOrder(a string, b string) bool {
+	rank := func(key string) int {
+		switch {
+		case key == "@key":
+			return 0
+		case strings.HasPrefix(key, "@"):
+			return 1
+		}
+		return 2
+	}
+	if rank(a) != rank(b) {
+		return rank(a) < rank(b)
+	}
+	return a < b
+}
+
+// Find returns the path of the project file in dir or the closest parent
+// directory containing one.
+func Find(dir string) (string, bool) {
+	for {
+		path := filepath.Join(dir, FileName)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// Merge merges the changes of current and other relative to their common
+// ancestor base line by line, without decrypting anything. base is empty if
+// both sides added the file. Lines changed differently on both sides are
+// conflicts: the result contains conflict markers for them and their keys are
+// returned.
+func Merge(baseData []byte, currentData []byte, otherData []byte) ([]byte, []string, error) {
+	current, err := Parse(currentData)
+	if err != nil {
+		return nil, nil, fmt.Errorf("current version: %w", err)
+	}
+	other, err := Parse(otherData)
+	if err != nil {
+		return nil, nil, fmt.Errorf("other version: %w", err)
+	}
+	var base File
+	if len(bytes.TrimSpace(baseData)) > 0 {
+		if base, err = Parse(baseData); err != nil {
+			return nil, nil, fmt.Errorf("common ancestor: %w", err)
+		}
+	}
+	if current.KeyID != other.KeyID {
+		return nil, nil, fmt.Errorf("the project files use different data keys (%s and %s), re-add the variables of one side", current.KeyID, other.KeyID)
+	}
+	currentEntries, otherEntries := current.entries(), other.entries()
+	// Without a common data key the ancestor's lines are meaningless.
+	baseEntries := map[string]string{}
+	if base.KeyID == current.KeyID {
+		baseEntries = base.entries()
+	}
+
+	keys := map[string]bool{}
+	for _, entries := range []map[string]string{baseEntries, currentEntries, otherEntries} {
+		for key := range entries {
+			keys[key] = true
+		}
+	}
+	sortedKeys := make([]string, 0, len(keys))
+	for key := range keys {
+		sortedKeys = append(sortedKeys, key)
+	}
+	sort.Slice(sortedKeys, func(i, j int) bool { return lineOrder(sortedKeys[i], sortedKeys[j]) })
+
+	var buf bytes.Buffer
+	var conflicts []string
+	buf.WriteString(header + "\n")
+	for _, key := range sortedKeys {
+		baseLine, inBase := baseEntries[key]
+		currentLine, inCurrent := currentEntries[key]
+		otherLine, inOther := otherEntries[key]
+		switch {
+		case inCurrent == inOther && currentLine == otherLine:
+			if inCurrent {
+				buf.WriteString(currentLine + "\n")
+			}
+		case inBase == inOther && baseLine == otherLine:
+			if inCurrent {
+				buf.WriteString(currentLine + "\n")
+			}
+		case inBase == inCurrent && baseLine == currentLine:
+			if inOther {
+				buf.WriteString(otherLine + "\n")
+			}
+		default:
+			conflicts = append(conflicts, key)
+			buf.WriteString("<<<<<<< current\n")
+			if inCurrent {
+				buf.WriteString(currentLine + "\n")
+			}
+			buf.WriteString("=======\n")
+			if inOther {
+				buf.WriteString(otherLine + "\n")
+			}
+			buf.WriteString(">>>>>>> other\n")
+		}
+	}
+	return buf.Bytes(), conflicts, nil
+}
@@ -0,0 +1,107 @@
+package projectfile
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFormat_SortsLines(t *testing.T) {
+	file := File{
+		KeyID:      "abc",
+		Recipients: map[string]string{"age1b": "e2 w2", "age1a": "e1 w1"},
+		Values:     map[string]string{"ZED": "c1", "API_KEY": "c2"},
+	}
+
+	expected := header + "\n@key abc\n@recipient age1a e1 w1\n@recipient age1b e2 w2\nAPI_KEY=c2\nZED=c1\n"
+	if formatted := string(file.Format()); formatted != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, formatted)
+	}
+}
+
+func TestParse_RoundTrip(t *testing.T) {
+	file := File{KeyID: "abc", Recipients: map[string]string{"age1a": "e1 w1"}, Values: map[string]string{"API_KEY": "template:c2=="}}
+
+	parsed, err := Parse(file.Format())
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !reflect.DeepEqual(parsed, file) {
+		t.Errorf("expected %v, got %v", file, parsed)
+	}
+}
+
+func TestParse_RejectsConflictMarkers(t *testing.T) {
+	_, err := Parse([]byte("@key abc\n<<<<<<< current\nA=1\n=======\nA=2\n>>>>>>> other\n"))
+
+	if err == nil || !strings.Contains(err.Error(), "merge conflict") {
+		t.Errorf("expected merge conflict error, got %v", err)
+	}
+}
+
+func TestMerge_CombinesIndependentChanges(t *testing.T) {
+	base := "@key abc\n@recipient age1a e1 w1\nKEEP=k\nCHANGED=old\nREMOVED=r\n"
+	current := "@key abc\n@recipient age1a e1 w1\nKEEP=k\nCHANGED=new\nREMOVED=r\nADDED_HERE=h\n"
+	other := "@key abc\n@recipient age1a e1 w1\n@recipient age1b e2 w2\nKEEP=k\nCHANGED=old\nADDED_THERE=t\n"
+
+	merged, conflicts, err := Merge([]byte(base), []byte(current), []byte(other))
+
+	if err != nil || len(conflicts) != 0 {
+		t.Fatalf("expected clean merge, got %v, %v", conflicts, err)
+	}
+	expected := header + "\n@key abc\n@recipient age1a e1 w1\n@recipient age1b e2 w2\nADDED_HERE=h\nADDED_THERE=t\nCHANGED=new\nKEEP=k\n"
+	if string(merged) != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, merged)
+	}
+}
+
+func TestMerge_ReportsConflictingChanges(t *testing.T) {
+	base := "@key abc\nAPI_KEY=old\n"
+	current := "@key abc\nAPI_KEY=mine\n"
+	other := "@key abc\nAPI_KEY=theirs\n"
+
+	merged, conflicts, err := Merge([]byte(base), []byte(current), []byte(other))
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(conflicts) != 1 || conflicts[0] != "API_KEY" {
+		t.Errorf("expected API_KEY conflict, got %v", conflicts)
+	}
+	if !strings.Contains(string(merged), "<<<<<<< current\nAPI_KEY=mine\n=======\nAPI_KEY=theirs\n>>>>>>> other\n") {
+		t.Errorf("expected conflict markers, got\n%s", merged)
+	}
+}
+
+func TestMerge_AcceptsFileAddedOnBothSides(t *testing.T) {
+	merged, conflicts, err := Merge(nil, []byte("@key abc\nA=1\n"), []byte("@key abc\nB=2\n"))
+
+	if err != nil || len(conflicts) != 0 {
+		t.Fatalf("expected clean merge, got %v, %v", conflicts, err)
+	}
+	if !strings.Contains(string(merged), "A=1\nB=2\n") {
+		t.Errorf("expected both additions, got\n%s", merged)
+	}
+}
+
+func TestMerge_FailsForDifferentDataKeys(t *testing.T) {
+	if _, _, err := Merge(nil, []byte("@key abc\nA=1\n"), []byte("@key def\nB=2\n")); err == nil {
+		t.Error("expected error for different data keys")
+	}
+}
+
+func TestFind_WalksUp(t *testing.T) {
+	root := t.TempDir()
+	nested := filepath.Join(root, "a", "b")
+	os.MkdirAll(nested, 0755)
+	os.WriteFile(filepath.Join(root, FileName), []byte("@key abc\n"), 0644)
+
+	path, ok := Find(nested)
+
+	if !ok || path != filepath.Join(root, FileName) {
+		t.Errorf("expected %s, got %s", filepath.Join(root, FileName), path)
+	}
+}
@@ -0,0 +1,78 @@
+// Package pty allocates pseudo terminals and switches terminals into raw mode
+// without depending on cgo.
+package pty
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// Open allocates a new pseudo terminal and returns its master and slave side.
+func Open() (master *os.File, slave *os.File, err error) {
+	master, err = os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	name, err := unlockSlave(master)
+	if err != nil {
+		master.Close()
+		return nil, nil, err
+	}
+
+	slave, err = os.OpenFile(name, os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		master.Close()
+		return nil, nil, err
+	}
+	return master, slave, nil
+}
+
+// IsTerminal reports whether the file is a terminal.
+func IsTerminal(file *os.File) bool {
+	var termios syscall.Termios
+	return ioctl(file.Fd(), ioctlGetTermios, unsafe.Pointer(&termios)) == nil
+}
+
+// MakeRaw puts the terminal into raw mode and returns a function restoring the
+// previous mode.
+func MakeRaw(file *os.File) (restore func(), err error) {
+	var original syscall.Termios
+	if err := ioctl(file.Fd(), ioctlGetTermios, unsafe.Pointer(&original)); err != nil {
+		return nil, err
+	}
+
+	raw := original
+	raw.Iflag &^= syscall.IGNBRK | syscall.BRKINT | syscall.PARMRK | syscall.ISTRIP | syscall.INLCR | syscall.IGNCR | syscall.ICRNL | syscall.IXON
+	raw.Oflag &^= syscall.OPOST
+	raw.Lflag &^= syscall.ECHO | syscall.ECHONL | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+	raw.Cflag &^= syscall.CSIZE | syscall.PARENB
+	raw.Cflag |= syscall.CS8
+	raw.Cc[vmin] = 1
+	raw.Cc[vtime] = 0
+	if err := ioctl(file.Fd(), ioctlSetTermios, unsafe.Pointer(&raw)); err != nil {
+		return nil, err
+	}
+
+	return func() {
+		ioctl(file.Fd(), ioctlSetTermios, unsafe.Pointer(&original))
+	}, nil
+}
+
+// CopySize sets the window size of terminal to that of source.
+func CopySize(terminal *os.File, source *os.File) error {
+	var size [4]uint16 // rows, columns, x pixels, y pixels
+	if err := ioctl(source.Fd(), syscall.TIOCGWINSZ, unsafe.Pointer(&size)); err != nil {
+		return err
+	}
+	return ioctl(terminal.Fd(), syscall.TIOCSWINSZ, unsafe.Pointer(&size))
+}
+
+func ioctl(fd uintptr, request uintptr, arg unsafe.Pointer) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, request, uintptr(arg))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
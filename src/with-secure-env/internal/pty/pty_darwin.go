@@ -0,0 +1,30 @@
+package pty
+
+import (
+	"bytes"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	ioctlGetTermios = syscall.TIOCGETA
+	ioctlSetTermios = syscall.TIOCSETA
+	vmin            = syscall.VMIN
+	vtime           = syscall.VTIME
+)
+
+func unlockSlave(master *os.File) (string, error) {
+	if err := ioctl(master.Fd(), syscall.TIOCPTYGRANT, nil); err != nil {
+		return "", err
+	}
+	if err := ioctl(master.Fd(), syscall.TIOCPTYUNLK, nil); err != nil {
+		return "", err
+	}
+
+	name := make([]byte, 128)
+	if err := ioctl(master.Fd(), syscall.TIOCPTYGNAME, unsafe.Pointer(&name[0])); err != nil {
+		return "", err
+	}
+	return string(name[:bytes.IndexByte(name, 0)]), nil
+}
@@ -0,0 +1,28 @@
+package pty
+
+import (
+	"os"
+	"strconv"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	ioctlGetTermios = 0x5401 // TCGETS
+	ioctlSetTermios = 0x5402 // TCSETS
+	vmin            = 6
+	vtime           = 5
+)
+
+func unlockSlave(master *os.File) (string, error) {
+	unlock := int32(0)
+	if err := ioctl(master.Fd(), syscall.TIOCSPTLCK, unsafe.Pointer(&unlock)); err != nil {
+		return "", err
+	}
+
+	var number uint32
+	if err := ioctl(master.Fd(), syscall.TIOCGPTN, unsafe.Pointer(&number)); err != nil {
+		return "", err
+	}
+	return "/dev/pts/" + strconv.Itoa(int(number)), nil
+}
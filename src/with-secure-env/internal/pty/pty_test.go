@@ -0,0 +1,61 @@
+package pty
+
+import (
+	"io"
+	"os"
+	"testing"
+)
+
+func TestOpen_ConnectsMasterAndSlave(t *testing.T) {
+	master, slave, err := Open()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	defer master.Close()
+	defer slave.Close()
+
+	slave.Write([]byte("hello"))
+
+	buffer := make([]byte, 5)
+	io.ReadFull(master, buffer)
+	if string(buffer) != "hello" {
+		t.Errorf("expected 'hello', got '%s'", buffer)
+	}
+}
+
+func TestIsTerminal(t *testing.T) {
+	master, slave, _ := Open()
+	defer master.Close()
+	defer slave.Close()
+	file, _ := os.CreateTemp(t.TempDir(), "file")
+	defer file.Close()
+
+	if !IsTerminal(slave) {
+		t.Error("expected pty slave to be a terminal")
+	}
+	if IsTerminal(file) {
+		t.Error("expected regular file to not be a terminal")
+	}
+}
+
+func TestMakeRaw_DisablesEchoUntilRestored(t *testing.T) {
+	master, slave, _ := Open()
+	defer master.Close()
+	defer slave.Close()
+
+	restore, err := MakeRaw(slave)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	master.Write([]byte("x"))
+	buffer := make([]byte, 1)
+	slave.Read(buffer)
+	restore()
+	master.Write([]byte("y\n"))
+
+	echoed := make([]byte, 3)
+	io.ReadFull(master, echoed)
+	if string(echoed) != "y\r\n" {
+		t.Errorf("expected only input after restore to be echoed, got %q", echoed)
+	}
+}
@@ -0,0 +1,191 @@
+// Package redact removes secret values from output streams.
+package redact
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MinSecretLength is the minimum length of values that are redacted. Shorter
+// values would cause too many false positives.
+const MinSecretLength = 4
+
+// FlushDelay is how long a trailing partial match is held back before it is
+// written anyway, so prompts of interactive programs still appear.
+var FlushDelay = 50 * time.Millisecond
+
+type pattern struct {
+	text        []byte
+	replacement []byte
+}
+
+// Writer replaces secret values and their common encodings (base64,
+// URL-encoded) with ***NAME*** while streaming to the underlying writer.
+// Matches split across several writes are detected as well.
+type Writer struct {
+	mu       sync.Mutex
+	out      io.Writer
+	patterns []pattern
+	maxLen   int
+	pending  []byte
+	timer    *time.Timer
+}
+
+// NewWriter returns a Writer redacting the values of secrets (by name).
+func NewWriter(out io.Writer, secrets map[string]string) *Writer {
+	seen := map[string]bool{}
+	var patterns []pattern
+	for name, value := range secrets {
+		if len(value) < MinSecretLength {
+			continue
+		}
+		for _, text := range encodings(value) {
+			if seen[text] {
+				continue
+			}
+			seen[text] = true
+			patterns = append(patterns, pattern{text: []byte(text), replacement: []byte("***" + name + "***")})
+		}
+	}
+	// Prefer the longest match when several start at the same position
+	sort.Slice(patterns, func(i, j int) bool {
+		return len(patterns[i].text) > len(patterns[j].text)
+	})
+
+	maxLen := 0
+	if len(patterns) > 0 {
+		maxLen = len(patterns[0].text)
+	}
+	return &Writer{out: out, patterns: patterns, maxLen: maxLen}
+}
+
+func encodings(value string) []string {
+	data := []byte(value)
+	texts := []string{value, url.QueryEscape(value), url.PathEscape(value)}
+	for _, encoding := range []*base64.Encoding{base64.StdEncoding, base64.URLEncoding} {
+		raw := encoding.WithPadding(base64.NoPadding)
+		texts = append(texts, encoding.EncodeToString(data), raw.EncodeToString(data))
+		texts = append(texts, base64Cores(raw, data)...)
+	}
+	return texts
+}
+
+// base64Cores returns the part of the base64 encoding of data that doesn't
+// depend on the surrounding bytes, for each of the three offsets data can have
+// within a longer encoded input, e.g. the password in "user:password".
+func base64Cores(encoding *base64.Encoding, data []byte) []string {
+	var cores []string
+	for offset := 0; offset < 3; offset++ {
+		encoded := encoding.EncodeToString(append(make([]byte, offset), data...))
+		// Skip characters mixing bits of the preceding or following bytes
+		start := (offset*8 + 5) / 6
+		end := len(encoded)
+		if (offset+len(data))%3 != 0 {
+			end--
+		}
+		if end-start >= MinSecretLength {
+			cores = append(cores, encoded[start:end])
+		}
+	}
+	return cores
+}
+
+// Write redacts p and writes everything that can't be the start of a secret
+// anymore. It always reports len(p) bytes as written on success.
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.timer != nil {
+		w.timer.Stop()
+		w.timer = nil
+	}
+
+	w.pending = append(w.pending, p...)
+	if err := w.process(false); err != nil {
+		return 0, err
+	}
+	if len(w.pending) > 0 {
+		w.timer = time.AfterFunc(FlushDelay, func() { w.Flush() })
+	}
+	return len(p), nil
+}
+
+// Flush writes held back output, even if it might be the start of a secret.
+func (w *Writer) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.process(true)
+}
+
+// Close flushes the held back output.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	if w.timer != nil {
+		w.timer.Stop()
+		w.timer = nil
+	}
+	w.mu.Unlock()
+
+	return w.Flush()
+}
+
+func (w *Writer) process(flush bool) error {
+	var output []byte
+	for {
+		holdFrom := len(w.pending)
+		if !flush {
+			holdFrom = w.partialMatchStart()
+		}
+
+		position, match := w.firstMatch()
+		if match == nil || position >= holdFrom {
+			output = append(output, w.pending[:holdFrom]...)
+			w.pending = append([]byte(nil), w.pending[holdFrom:]...)
+			break
+		}
+
+		output = append(output, w.pending[:position]...)
+		output = append(output, match.replacement...)
+		w.pending = w.pending[position+len(match.text):]
+	}
+
+	if len(output) == 0 {
+		return nil
+	}
+	_, err := w.out.Write(output)
+	return err
+}
+
+func (w *Writer) firstMatch() (int, *pattern) {
+	position := -1
+	var match *pattern
+	for i := range w.patterns {
+		index := bytes.Index(w.pending, w.patterns[i].text)
+		if index >= 0 && (position < 0 || index < position) {
+			position = index
+			match = &w.patterns[i]
+		}
+	}
+	return position, match
+}
+
+// partialMatchStart returns the position of the earliest suffix of the
+// pending output that could grow into a match with more input.
+func (w *Writer) partialMatchStart() int {
+	for start := max(0, len(w.pending)-w.maxLen+1); start < len(w.pending); start++ {
+		suffix := w.pending[start:]
+		for _, pattern := range w.patterns {
+			if len(suffix) < len(pattern.text) && bytes.HasPrefix(pattern.text, suffix) {
+				return start
+			}
+		}
+	}
+	return len(w.pending)
+}
@@ -0,0 +1,135 @@
+package redact
+
+import (
+	"bytes"
+	"encoding/base64"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestWriter_RedactsValues(t *testing.T) {
+	var out bytes.Buffer
+	w := NewWriter(&out, map[string]string{"API_KEY": "s3cr3t-value"})
+
+	w.Write([]byte("connecting with s3cr3t-value now\n"))
+	w.Close()
+
+	if out.String() != "connecting with ***API_KEY*** now\n" {
+		t.Errorf("expected value to be redacted, got %q", out.String())
+	}
+}
+
+func TestWriter_RedactsEncodedValues(t *testing.T) {
+	var out bytes.Buffer
+	value := "p@ss/word+1?"
+	w := NewWriter(&out, map[string]string{"DB_PASS": value})
+
+	w.Write([]byte("basic " + base64.StdEncoding.EncodeToString([]byte(value)) + "\n"))
+	w.Write([]byte("url ?p=" + url.QueryEscape(value) + "\n"))
+	w.Write([]byte("path /" + url.PathEscape(value) + "\n"))
+	w.Close()
+
+	expected := "basic ***DB_PASS***\nurl ?p=***DB_PASS***\npath /***DB_PASS***\n"
+	if out.String() != expected {
+		t.Errorf("expected %q, got %q", expected, out.String())
+	}
+}
+
+func TestWriter_RedactsPaddedBase64(t *testing.T) {
+	var out bytes.Buffer
+	value := "s3cr3t-value!" // 13 bytes, encoded with "=="
+	w := NewWriter(&out, map[string]string{"API_KEY": value})
+
+	w.Write([]byte("std " + base64.StdEncoding.EncodeToString([]byte(value)) + "\n"))
+	w.Write([]byte("url " + base64.URLEncoding.EncodeToString([]byte(value)) + "\n"))
+	w.Write([]byte("raw " + base64.RawStdEncoding.EncodeToString([]byte(value)) + "\n"))
+	w.Close()
+
+	expected := "std ***API_KEY***\nurl ***API_KEY***\nraw ***API_KEY***\n"
+	if out.String() != expected {
+		t.Errorf("expected %q, got %q", expected, out.String())
+	}
+}
+
+func TestWriter_RedactsValuesWithinLongerBase64(t *testing.T) {
+	value := "p4ssw0rd-value"
+	for _, prefix := range []string{"user:", "admin:", "bob:"} {
+		var out bytes.Buffer
+		w := NewWriter(&out, map[string]string{"PASSWORD": value})
+
+		w.Write([]byte("Authorization: Basic " + base64.StdEncoding.EncodeToString([]byte(prefix+value+"!")) + "\n"))
+		w.Close()
+
+		if !bytes.Contains(out.Bytes(), []byte("***PASSWORD***")) {
+			t.Errorf("expected value after %q to be redacted, got %q", prefix, out.String())
+		}
+	}
+}
+
+func TestWriter_RedactsMatchesSplitAcrossWrites(t *testing.T) {
+	var out bytes.Buffer
+	w := NewWriter(&out, map[string]string{"TOKEN": "abcdef123456"})
+
+	w.Write([]byte("token=abc"))
+	w.Write([]byte("def"))
+	w.Write([]byte("123456!"))
+	w.Close()
+
+	if out.String() != "token=***TOKEN***!" {
+		t.Errorf("expected split value to be redacted, got %q", out.String())
+	}
+}
+
+func TestWriter_WritesNonMatchingOutputImmediately(t *testing.T) {
+	var out bytes.Buffer
+	w := NewWriter(&out, map[string]string{"TOKEN": "abcdef123456"})
+
+	w.Write([]byte("Password: "))
+
+	if out.String() != "Password: " {
+		t.Errorf("expected output to be written without delay, got %q", out.String())
+	}
+}
+
+func TestWriter_FlushesHeldBackPartialMatchAfterDelay(t *testing.T) {
+	var out bytes.Buffer
+	w := NewWriter(&out, map[string]string{"TOKEN": "abcdef123456"})
+
+	w.Write([]byte("prompt ab"))
+	w.mu.Lock()
+	heldBack := out.String()
+	w.mu.Unlock()
+	time.Sleep(FlushDelay * 3)
+
+	w.mu.Lock()
+	flushed := out.String()
+	w.mu.Unlock()
+	if heldBack != "prompt " || flushed != "prompt ab" {
+		t.Errorf("expected 'ab' to be held back and flushed later, got %q then %q", heldBack, flushed)
+	}
+}
+
+func TestWriter_PrefersLongestMatch(t *testing.T) {
+	var out bytes.Buffer
+	w := NewWriter(&out, map[string]string{"SHORT": "secret", "LONG": "secret-extended"})
+
+	w.Write([]byte("secret-extended secret"))
+	w.Close()
+
+	if out.String() != "***LONG*** ***SHORT***" {
+		t.Errorf("expected longest match to win, got %q", out.String())
+	}
+}
+
+func TestWriter_IgnoresVeryShortValues(t *testing.T) {
+	var out bytes.Buffer
+	w := NewWriter(&out, map[string]string{"DEBUG": "1"})
+
+	w.Write([]byte("version 1.0"))
+	w.Close()
+
+	if out.String() != "version 1.0" {
+		t.Errorf("expected short value to not be redacted, got %q", out.String())
+	}
+}
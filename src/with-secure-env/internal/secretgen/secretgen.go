@@ -0,0 +1,157 @@
+// Package secretgen generates random secrets: passwords, hex and base64
+// tokens, UUIDs and Ed25519 or RSA key pairs in PEM.
+package secretgen
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+)
+
+// Kinds of secrets.
+const (
+	KindPassword = "password"
+	KindHex      = "hex"
+	KindBase64   = "base64"
+	KindUUID     = "uuid"
+	KindEd25519  = "ed25519"
+	KindRSA      = "rsa"
+)
+
+// Kinds lists all kinds.
+var Kinds = []string{KindPassword, KindHex, KindBase64, KindUUID, KindEd25519, KindRSA}
+
+// Charsets for passwords by name.
+var Charsets = map[string]string{
+	"alnum":   "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789",
+	"alpha":   "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz",
+	"numeric": "0123456789",
+	"symbols": "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789!#%&*+-.:=?@^_~",
+}
+
+// PublicKeySuffix is appended to the name of a key pair's private key for the
+// name of its public key.
+const PublicKeySuffix = "_PUBLIC"
+
+// Options select what to generate.
+type Options struct {
+	// Kind is one of Kinds. Defaults to KindPassword.
+	Kind string `json:"kind"`
+	// Length is the number of characters of a password (default 32), the
+	// number of random bytes of a hex or base64 token (default 32) or the key
+	// size in bits of an RSA key (default 3072).
+	Length int `json:"length"`
+	// Charset names one of Charsets for passwords. Defaults to alnum.
+	Charset string `json:"charset"`
+	// Characters are the characters of a password, overriding Charset.
+	Characters string `json:"characters"`
+}
+
+// Generate returns the generated values by env name: name alone, or for key
+// pairs name with the private and name+PublicKeySuffix with the public key.
+func Generate(name string, options Options) (map[string]string, error) {
+	switch options.Kind {
+	case KindPassword, "":
+		value, err := password(options)
+		return map[string]string{name: value}, err
+	case KindHex:
+		data, err := randomBytes(options.Length)
+		return map[string]string{name: hex.EncodeToString(data)}, err
+	case KindBase64:
+		data, err := randomBytes(options.Length)
+		return map[string]string{name: base64.StdEncoding.EncodeToString(data)}, err
+	case KindUUID:
+		data, err := randomBytes(16)
+		if err != nil {
+			return nil, err
+		}
+		data[6] = data[6]&0x0f | 0x40
+		data[8] = data[8]&0x3f | 0x80
+		return map[string]string{name: fmt.Sprintf("%x-%x-%x-%x-%x", data[0:4], data[4:6], data[6:8], data[8:10], data[10:])}, nil
+	case KindEd25519:
+		public, private, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		return keyPair(name, private, public)
+	case KindRSA:
+		bits := options.Length
+		if bits == 0 {
+			bits = 3072
+		}
+		if bits < 2048 {
+			return nil, fmt.Errorf("RSA keys need at least 2048 bits, got %d", bits)
+		}
+		private, err := rsa.GenerateKey(rand.Reader, bits)
+		if err != nil {
+			return nil, err
+		}
+		return keyPair(name, private, &private.PublicKey)
+	}
+	return nil, fmt.Errorf("unknown kind %q (one of %v)", options.Kind, Kinds)
+}
+
+func password(options Options) (string, error) {
+	characters := options.Characters
+	if characters == "" {
+		charset := options.Charset
+		if charset == "" {
+			charset = "alnum"
+		}
+		var ok bool
+		if characters, ok = Charsets[charset]; !ok {
+			return "", fmt.Errorf("unknown charset %q", charset)
+		}
+	}
+	length := options.Length
+	if length == 0 {
+		length = 32
+	}
+	if length < 8 {
+		return "", fmt.Errorf("passwords need at least 8 characters, got %d", length)
+	}
+
+	alphabet := []rune(characters)
+	result := make([]rune, length)
+	for i := range result {
+		index, err := rand.Int(rand.Reader, big.NewInt(int64(len(alphabet))))
+		if err != nil {
+			return "", err
+		}
+		result[i] = alphabet[index.Int64()]
+	}
+	return string(result), nil
+}
+
+func randomBytes(length int) ([]byte, error) {
+	if length == 0 {
+		length = 32
+	}
+	if length < 16 {
+		return nil, fmt.Errorf("tokens need at least 16 bytes, got %d", length)
+	}
+	data := make([]byte, length)
+	_, err := rand.Read(data)
+	return data, err
+}
+
+func keyPair(name string, private any, public any) (map[string]string, error) {
+	privateDER, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return nil, err
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{
+		name:                   string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER})),
+		name + PublicKeySuffix: string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})),
+	}, nil
+}
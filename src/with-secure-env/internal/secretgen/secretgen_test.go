@@ -0,0 +1,112 @@
+package secretgen
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestGenerate_PasswordUsesLengthAndCharset(t *testing.T) {
+	values, err := Generate("DB_PASSWORD", Options{Length: 40, Charset: "numeric"})
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !regexp.MustCompile(`^[0-9]{40}$`).MatchString(values["DB_PASSWORD"]) {
+		t.Errorf("expected 40 digits, got %q", values["DB_PASSWORD"])
+	}
+
+	values, _ = Generate("PIN", Options{Length: 12, Characters: "ab"})
+	if !regexp.MustCompile(`^[ab]{12}$`).MatchString(values["PIN"]) {
+		t.Errorf("expected custom characters, got %q", values["PIN"])
+	}
+}
+
+func TestGenerate_PasswordsDiffer(t *testing.T) {
+	first, _ := Generate("A", Options{})
+	second, _ := Generate("A", Options{})
+
+	if first["A"] == second["A"] || len(first["A"]) != 32 {
+		t.Errorf("expected distinct 32 character passwords, got %q and %q", first["A"], second["A"])
+	}
+}
+
+func TestGenerate_Tokens(t *testing.T) {
+	hexValues, _ := Generate("TOKEN", Options{Kind: KindHex, Length: 16})
+	if data, err := hex.DecodeString(hexValues["TOKEN"]); err != nil || len(data) != 16 {
+		t.Errorf("expected 16 byte hex token, got %q", hexValues["TOKEN"])
+	}
+
+	base64Values, _ := Generate("TOKEN", Options{Kind: KindBase64})
+	if data, err := base64.StdEncoding.DecodeString(base64Values["TOKEN"]); err != nil || len(data) != 32 {
+		t.Errorf("expected 32 byte base64 token, got %q", base64Values["TOKEN"])
+	}
+
+	uuidValues, _ := Generate("ID", Options{Kind: KindUUID})
+	if !regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(uuidValues["ID"]) {
+		t.Errorf("expected UUID v4, got %q", uuidValues["ID"])
+	}
+}
+
+func TestGenerate_Ed25519KeyPair(t *testing.T) {
+	values, err := Generate("SIGNING_KEY", Options{Kind: KindEd25519})
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	private := parsePEM(t, values["SIGNING_KEY"], "PRIVATE KEY")
+	privateKey, err := x509.ParsePKCS8PrivateKey(private)
+	if err != nil {
+		t.Fatalf("failed to parse private key: %v", err)
+	}
+	publicKey, err := x509.ParsePKIXPublicKey(parsePEM(t, values["SIGNING_KEY_PUBLIC"], "PUBLIC KEY"))
+	if err != nil {
+		t.Fatalf("failed to parse public key: %v", err)
+	}
+	if !privateKey.(ed25519.PrivateKey).Public().(ed25519.PublicKey).Equal(publicKey) {
+		t.Error("expected public key to belong to private key")
+	}
+}
+
+func TestGenerate_RSAKeyPair(t *testing.T) {
+	values, err := Generate("KEY", Options{Kind: KindRSA, Length: 2048})
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	privateKey, err := x509.ParsePKCS8PrivateKey(parsePEM(t, values["KEY"], "PRIVATE KEY"))
+	if err != nil || privateKey.(*rsa.PrivateKey).N.BitLen() != 2048 {
+		t.Errorf("expected 2048 bit RSA key, got %v", err)
+	}
+	if !strings.HasPrefix(values["KEY_PUBLIC"], "-----BEGIN PUBLIC KEY-----") {
+		t.Errorf("expected public key PEM, got %q", values["KEY_PUBLIC"])
+	}
+}
+
+func TestGenerate_RejectsWeakOrUnknownOptions(t *testing.T) {
+	for _, options := range []Options{
+		{Length: 4},
+		{Charset: "emoji"},
+		{Kind: KindHex, Length: 8},
+		{Kind: KindRSA, Length: 1024},
+		{Kind: "otp"},
+	} {
+		if _, err := Generate("NAME", options); err == nil {
+			t.Errorf("expected error for %+v", options)
+		}
+	}
+}
+
+func parsePEM(t *testing.T, data string, blockType string) []byte {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil || block.Type != blockType {
+		t.Fatalf("expected %s PEM block, got %q", blockType, data)
+	}
+	return block.Bytes
+}
@@ -0,0 +1,27 @@
+// Package secretmeta describes secrets without revealing them: where they
+// came from, when they changed and when they expire. Metadata is stored in
+// plaintext next to the encrypted values.
+package secretmeta
+
+import "time"
+
+// Metadata of a single variable. All fields are optional.
+type Metadata struct {
+	Description string `json:"description,omitempty"`
+	// Source is where the secret was issued, e.g. the URL of a token page.
+	Source string `json:"source,omitempty"`
+	// Created and Updated are maintained when values are saved.
+	Created time.Time `json:"created,omitzero"`
+	Updated time.Time `json:"updated,omitzero"`
+	Expires time.Time `json:"expires,omitzero"`
+}
+
+// Expired reports whether the secret has an expiry that is not after now.
+func (m Metadata) Expired(now time.Time) bool {
+	return !m.Expires.IsZero() && !now.Before(m.Expires)
+}
+
+// IsZero reports whether no field is set.
+func (m Metadata) IsZero() bool {
+	return m == Metadata{}
+}
@@ -0,0 +1,29 @@
+package secretmeta
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestMetadata_Expired(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+	if (Metadata{}).Expired(now) {
+		t.Error("expected metadata without expiry not to expire")
+	}
+	if !(Metadata{Expires: now}).Expired(now) {
+		t.Error("expected secret to be expired at its expiry")
+	}
+	if (Metadata{Expires: now.Add(time.Hour)}).Expired(now) {
+		t.Error("expected secret expiring later not to be expired")
+	}
+}
+
+func TestMetadata_OmitsUnsetFieldsInJSON(t *testing.T) {
+	data, _ := json.Marshal(Metadata{Description: "CI token"})
+
+	if string(data) != `{"description":"CI token"}` {
+		t.Errorf("unexpected JSON %s", data)
+	}
+}
@@ -0,0 +1,282 @@
+// Package supervisor runs an application as child process while behaving as
+// transparently as if the application had been exec'd directly.
+package supervisor
+
+import (
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/kfischer-okarin/with-secure-env/internal/pty"
+)
+
+// Options adjust how the child process is run.
+type Options struct {
+	// OutputFilter wraps the writers the child's stdout and stderr are copied
+	// to. If nil, the child writes to stdout and stderr directly.
+	//
+	// Outputs that are terminals are connected to a pseudo terminal, so the
+	// child still sees a terminal and interactive programs keep working.
+	OutputFilter func(out io.Writer) io.WriteCloser
+}
+
+// outputGracePeriod is how long the supervisor waits for remaining output
+// after the child exited, in case grandchildren keep the output open.
+const outputGracePeriod = time.Second
+
+type child struct {
+	pid int
+	// ownsTerminal is set if the child runs in the foreground of the
+	// terminal of the supervisor.
+	ownsTerminal bool
+	ttyFd        int
+	// pty is the master of the pseudo terminal the child runs in, if any.
+	pty        *os.File
+	restoreTty func()
+	copies     sync.WaitGroup
+	filters    []io.Closer
+}
+
+// Run starts the application as child process and waits for it to exit.
+//
+// All signals received in the meantime are forwarded to the child. If stdin
+// is the controlling terminal and with-secure-env runs in the foreground, the
+// child gets its own process group which is made the foreground group, so
+// terminal signals like Ctrl-C only reach the child. When the child is stopped
+// (Ctrl-Z) the supervisor stops itself as well, so the shell's job control
+// keeps working, and resumes the child when it is continued.
+func Run(path string, args []string, env []string, options Options) (syscall.WaitStatus, error) {
+	signals := make(chan os.Signal, 32)
+	signal.Notify(signals)
+	defer signal.Stop(signals)
+
+	c := &child{}
+	attr := &os.ProcAttr{
+		Env:   env,
+		Files: []*os.File{os.Stdin, os.Stdout, os.Stderr},
+		Sys:   &syscall.SysProcAttr{},
+	}
+
+	var childEnds []*os.File
+	if options.OutputFilter != nil {
+		var err error
+		childEnds, err = c.filterOutput(attr, options.OutputFilter)
+		if err != nil {
+			return 0, err
+		}
+	}
+	if c.pty == nil {
+		c.ttyFd, c.ownsTerminal = foregroundTerminal()
+	}
+	if c.ownsTerminal {
+		attr.Sys.Setpgid = true
+		attr.Sys.Foreground = true
+		attr.Sys.Ctty = 0
+	}
+
+	process, err := os.StartProcess(path, append([]string{path}, args...), attr)
+	for _, file := range childEnds {
+		file.Close()
+	}
+	if err != nil {
+		c.finishOutput()
+		return 0, err
+	}
+	c.pid = process.Pid
+
+	if c.ownsTerminal {
+		// Taking the terminal back from the background group would otherwise
+		// raise SIGTTOU. Ignored only now so the child doesn't inherit it.
+		signal.Ignore(syscall.SIGTTOU)
+		defer signal.Reset(syscall.SIGTTOU)
+		defer setForegroundProcessGroup(c.ttyFd, syscall.Getpgrp())
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go c.forwardSignals(signals, done)
+
+	for {
+		var status syscall.WaitStatus
+		_, err := syscall.Wait4(c.pid, &status, syscall.WUNTRACED, nil)
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil {
+			c.finishOutput()
+			return 0, err
+		}
+
+		if status.Stopped() {
+			c.suspend()
+			continue
+		}
+		c.finishOutput()
+		return status, nil
+	}
+}
+
+// filterOutput routes the child's outputs through the filter: Terminal outputs
+// via a pseudo terminal, others via pipes. It returns the child's ends, which
+// have to be closed after the child started.
+func (c *child) filterOutput(attr *os.ProcAttr, filter func(io.Writer) io.WriteCloser) ([]*os.File, error) {
+	var childEnds []*os.File
+	var slave *os.File
+	for fd := 1; fd <= 2; fd++ {
+		out := attr.Files[fd]
+		if pty.IsTerminal(out) {
+			if slave == nil {
+				var err error
+				slave, err = c.openPty(attr, out, fd)
+				if err != nil {
+					return childEnds, err
+				}
+				childEnds = append(childEnds, slave)
+				c.copyOutput(c.pty, out, filter)
+			}
+			attr.Files[fd] = slave
+			continue
+		}
+
+		reader, writer, err := os.Pipe()
+		if err != nil {
+			return childEnds, err
+		}
+		childEnds = append(childEnds, writer)
+		attr.Files[fd] = writer
+		c.copyOutput(reader, out, filter)
+	}
+	return childEnds, nil
+}
+
+// openPty runs the child in a new session on a pseudo terminal with the size
+// of terminal, which replaces the child's fd. If stdin is a terminal too, it
+// is switched to raw mode and forwarded, so the pseudo terminal handles line
+// editing and Ctrl-C & co.
+func (c *child) openPty(attr *os.ProcAttr, terminal *os.File, fd int) (*os.File, error) {
+	master, slave, err := pty.Open()
+	if err != nil {
+		return nil, err
+	}
+	c.pty = master
+	pty.CopySize(master, terminal)
+
+	attr.Sys.Setsid = true
+	attr.Sys.Setctty = true
+	attr.Sys.Ctty = fd
+	if pty.IsTerminal(os.Stdin) {
+		attr.Files[0] = slave
+		attr.Sys.Ctty = 0
+		if restore, err := pty.MakeRaw(os.Stdin); err == nil {
+			c.restoreTty = restore
+		}
+		go io.Copy(master, os.Stdin)
+	}
+	return slave, nil
+}
+
+func (c *child) copyOutput(source *os.File, out io.Writer, filter func(io.Writer) io.WriteCloser) {
+	filtered := filter(out)
+	c.filters = append(c.filters, filtered)
+	c.copies.Add(1)
+	go func() {
+		defer c.copies.Done()
+		// Reading a pty master fails with EIO once the child closed it
+		io.Copy(filtered, source)
+		source.Close()
+	}()
+}
+
+// finishOutput waits for the remaining output and restores the terminal.
+func (c *child) finishOutput() {
+	copied := make(chan struct{})
+	go func() {
+		c.copies.Wait()
+		close(copied)
+	}()
+	select {
+	case <-copied:
+	case <-time.After(outputGracePeriod):
+	}
+
+	for _, filter := range c.filters {
+		filter.Close()
+	}
+	if c.restoreTty != nil {
+		c.restoreTty()
+	}
+}
+
+func (c *child) forwardSignals(signals chan os.Signal, done chan struct{}) {
+	for {
+		select {
+		case sig := <-signals:
+			switch sig {
+			// Notifications about the child itself and Go runtime preemption
+			case syscall.SIGCHLD, syscall.SIGURG:
+				continue
+			// The child runs in the foreground group, so these would only
+			// reach the supervisor when it takes the terminal back.
+			case syscall.SIGTTIN, syscall.SIGTTOU:
+				continue
+			// Resizing the pseudo terminal notifies the child by itself
+			case syscall.SIGWINCH:
+				if c.pty != nil {
+					pty.CopySize(c.pty, os.Stdout)
+					continue
+				}
+			}
+			syscall.Kill(c.pid, sig.(syscall.Signal))
+		case <-done:
+			return
+		}
+	}
+}
+
+// suspend stops the supervisor after the child was stopped and resumes the
+// child once the supervisor itself was continued.
+func (c *child) suspend() {
+	if c.ownsTerminal {
+		setForegroundProcessGroup(c.ttyFd, syscall.Getpgrp())
+	}
+	if c.restoreTty != nil {
+		c.restoreTty()
+	}
+
+	syscall.Kill(os.Getpid(), syscall.SIGSTOP)
+
+	if c.restoreTty != nil {
+		if restore, err := pty.MakeRaw(os.Stdin); err == nil {
+			c.restoreTty = restore
+		}
+	}
+	if c.ownsTerminal {
+		setForegroundProcessGroup(c.ttyFd, c.pid)
+	}
+	if c.ownsTerminal || c.pty != nil {
+		syscall.Kill(-c.pid, syscall.SIGCONT)
+	} else {
+		syscall.Kill(c.pid, syscall.SIGCONT)
+	}
+}
+
+// foregroundTerminal reports whether stdin is a terminal on which the current
+// process group is the foreground group.
+func foregroundTerminal() (int, bool) {
+	fd := int(os.Stdin.Fd())
+	var pgrp int32
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), syscall.TIOCGPGRP, uintptr(unsafe.Pointer(&pgrp)))
+	if errno != 0 {
+		return fd, false
+	}
+	return fd, int(pgrp) == syscall.Getpgrp()
+}
+
+func setForegroundProcessGroup(fd int, pgrp int) {
+	value := int32(pgrp)
+	syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), syscall.TIOCSPGRP, uintptr(unsafe.Pointer(&value)))
+}
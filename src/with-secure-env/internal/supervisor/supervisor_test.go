@@ -0,0 +1,110 @@
+package supervisor
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRun_PropagatesExitCode(t *testing.T) {
+	status, err := Run("/bin/sh", []string{"-c", "exit 42"}, nil, Options{})
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !status.Exited() || status.ExitStatus() != 42 {
+		t.Errorf("expected exit code 42, got %v", status)
+	}
+}
+
+func TestRun_PropagatesTerminatingSignal(t *testing.T) {
+	status, _ := Run("/bin/sh", []string{"-c", "kill -TERM $$"}, nil, Options{})
+
+	if !status.Signaled() || status.Signal() != syscall.SIGTERM {
+		t.Errorf("expected termination by SIGTERM, got %v", status)
+	}
+}
+
+func TestRun_PassesArgsAndEnv(t *testing.T) {
+	status, _ := Run("/bin/sh", []string{"-c", `test "$1" = arg && test "$SECRET" = value`, "sh", "arg"}, []string{"SECRET=value"}, Options{})
+
+	if status.ExitStatus() != 0 {
+		t.Errorf("expected args and env to be passed, got %v", status)
+	}
+}
+
+func TestRun_ForwardsSignalsToChild(t *testing.T) {
+	readyFile := filepath.Join(t.TempDir(), "ready")
+	script := `trap "exit 7" USR1; touch ` + readyFile + `; while true; do sleep 0.01; done`
+
+	go func() {
+		for {
+			if _, err := os.Stat(readyFile); err == nil {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		syscall.Kill(os.Getpid(), syscall.SIGUSR1)
+	}()
+	status, _ := Run("/bin/sh", []string{"-c", script}, nil, Options{})
+
+	if status.ExitStatus() != 7 {
+		t.Errorf("expected child to exit via forwarded SIGUSR1, got %v", status)
+	}
+}
+
+func TestRun_FailsForMissingBinary(t *testing.T) {
+	_, err := Run("/does/not/exist", nil, nil, Options{})
+
+	if err == nil {
+		t.Error("expected error for missing binary")
+	}
+}
+
+func TestRun_FiltersOutput(t *testing.T) {
+	stdout := captureFile(t, &os.Stdout)
+	stderr := captureFile(t, &os.Stderr)
+
+	Run("/bin/sh", []string{"-c", "echo out; echo err >&2"}, nil, Options{OutputFilter: upcase})
+
+	if readFile(t, stdout) != "OUT\n" || readFile(t, stderr) != "ERR\n" {
+		t.Errorf("expected filtered output 'OUT' and 'ERR', got %q and %q", readFile(t, stdout), readFile(t, stderr))
+	}
+}
+
+type upcaseWriter struct {
+	out io.Writer
+}
+
+func (w upcaseWriter) Write(p []byte) (int, error) {
+	return w.out.Write(bytes.ToUpper(p))
+}
+
+func (w upcaseWriter) Close() error {
+	return nil
+}
+
+func upcase(out io.Writer) io.WriteCloser {
+	return upcaseWriter{out: out}
+}
+
+func captureFile(t *testing.T, file **os.File) string {
+	path := filepath.Join(t.TempDir(), "output")
+	captured, _ := os.Create(path)
+	original := *file
+	*file = captured
+	t.Cleanup(func() {
+		*file = original
+		captured.Close()
+	})
+	return path
+}
+
+func readFile(t *testing.T, path string) string {
+	data, _ := os.ReadFile(path)
+	return string(data)
+}
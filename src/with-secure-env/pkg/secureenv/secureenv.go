@@ -0,0 +1,183 @@
+// Package secureenv lets Go programs request their secrets from
+// with-secure-env at runtime instead of reading them from the environment.
+// Each request goes through the same permission dialog as a launch.
+//
+//	secrets, err := secureenv.Get("DATABASE_URL", "API_KEY")
+//	if err != nil {
+//		return err
+//	}
+//	defer secrets.Zero()
+//	connect(string(secrets["DATABASE_URL"]))
+package secureenv
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/kfischer-okarin/with-secure-env/internal/agent"
+)
+
+// Secrets are values by env name. Call Zero once they are not needed anymore.
+type Secrets map[string][]byte
+
+// Zero overwrites all values with zeros.
+func (s Secrets) Zero() {
+	for _, value := range s {
+		clear(value)
+	}
+}
+
+// Backend fetches secrets for an application after asking for permission.
+type Backend interface {
+	Fetch(applicationPath string, envNames []string) (Secrets, error)
+}
+
+// Client requests secrets for an application.
+type Client struct {
+	// Backend defaults to DefaultBackend().
+	Backend Backend
+	// ApplicationPath is the entry to request secrets for. Defaults to the
+	// path of the running executable; the agent rejects any other path.
+	ApplicationPath string
+}
+
+// Get requests envNames for the running executable with the default backend.
+func Get(envNames ...string) (Secrets, error) {
+	return (&Client{}).Get(envNames...)
+}
+
+// Get requests the values of envNames.
+func (c *Client) Get(envNames ...string) (Secrets, error) {
+	if len(envNames) == 0 {
+		return nil, errors.New("no env names requested")
+	}
+	applicationPath := c.ApplicationPath
+	if applicationPath == "" {
+		var err error
+		if applicationPath, err = Executable(); err != nil {
+			return nil, err
+		}
+	}
+	backend := c.Backend
+	if backend == nil {
+		backend = DefaultBackend()
+	}
+	return backend.Fetch(applicationPath, envNames)
+}
+
+// Executable returns the resolved path of the running executable, which
+// identifies it to with-secure-env.
+func Executable() (string, error) {
+	path, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(path)
+}
+
+// DefaultBackend uses a running agent, otherwise the with-secure-env command.
+func DefaultBackend() Backend {
+	agentBackend := &AgentBackend{}
+	if _, err := agentBackend.client().Status(); err == nil {
+		return agentBackend
+	}
+	return &CommandBackend{}
+}
+
+// AgentBackend fetches secrets from a running `with-secure-env agent`, which
+// shows the permission dialog.
+type AgentBackend struct {
+	// SocketPath defaults to the agent socket in the user's config directory.
+	SocketPath string
+}
+
+// Fetch implements Backend.
+func (b *AgentBackend) Fetch(applicationPath string, envNames []string) (Secrets, error) {
+	values, err := b.client().Fetch(applicationPath, envNames)
+	if err != nil {
+		return nil, err
+	}
+	return toSecrets(values), nil
+}
+
+func (b *AgentBackend) client() *agent.Client {
+	socketPath := b.SocketPath
+	if socketPath == "" {
+		home, _ := os.UserHomeDir()
+		socketPath = filepath.Join(home, ".config", "with-secure-env", "agent.sock")
+	}
+	return &agent.Client{SocketPath: socketPath}
+}
+
+// CommandBackend runs `with-secure-env fetch`, which shows the permission
+// dialog itself.
+type CommandBackend struct {
+	// Path of the with-secure-env binary. Defaults to looking it up in PATH.
+	Path string
+}
+
+// Fetch implements Backend.
+func (b *CommandBackend) Fetch(applicationPath string, envNames []string) (Secrets, error) {
+	path := b.Path
+	if path == "" {
+		path = "with-secure-env"
+	}
+	cmd := exec.Command(path, append([]string{"fetch", applicationPath}, envNames...)...)
+	cmd.Stderr = os.Stderr
+	output, err := cmd.Output()
+	defer clear(output)
+	if err != nil {
+		return nil, fmt.Errorf("with-secure-env fetch: %w", err)
+	}
+
+	var values map[string][]byte
+	if err := json.Unmarshal(output, &values); err != nil {
+		return nil, fmt.Errorf("with-secure-env fetch: %w", err)
+	}
+	return values, nil
+}
+
+// Fake is a Backend for tests that serves Values without asking.
+type Fake struct {
+	Values map[string]string
+	// Denied makes Fetch fail like a denied permission dialog.
+	Denied bool
+	// Requests records the requested env names by application path.
+	Requests map[string][]string
+}
+
+// ErrPermissionDenied is returned by Fake when Denied is set.
+var ErrPermissionDenied = errors.New("permission denied")
+
+// Fetch implements Backend.
+func (f *Fake) Fetch(applicationPath string, envNames []string) (Secrets, error) {
+	if f.Requests == nil {
+		f.Requests = map[string][]string{}
+	}
+	f.Requests[applicationPath] = envNames
+	if f.Denied {
+		return nil, ErrPermissionDenied
+	}
+
+	values := map[string]string{}
+	for _, name := range envNames {
+		value, ok := f.Values[name]
+		if !ok {
+			return nil, fmt.Errorf("%s is not configured for %s", name, applicationPath)
+		}
+		values[name] = value
+	}
+	return toSecrets(values), nil
+}
+
+func toSecrets(values map[string]string) Secrets {
+	secrets := Secrets{}
+	for name, value := range values {
+		secrets[name] = []byte(value)
+	}
+	return secrets
+}
@@ -0,0 +1,84 @@
+package secureenv
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kfischer-okarin/with-secure-env/internal/agent"
+)
+
+func TestClient_RequestsValuesForRunningExecutable(t *testing.T) {
+	fake := &Fake{Values: map[string]string{"API_KEY": "secret"}}
+	client := &Client{Backend: fake}
+
+	secrets, err := client.Get("API_KEY")
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if string(secrets["API_KEY"]) != "secret" {
+		t.Errorf("expected API_KEY=secret, got %q", secrets["API_KEY"])
+	}
+	executable, _ := Executable()
+	if names := fake.Requests[executable]; len(names) != 1 || names[0] != "API_KEY" {
+		t.Errorf("expected request for %s, got %v", executable, fake.Requests)
+	}
+}
+
+func TestClient_ReturnsPermissionErrors(t *testing.T) {
+	client := &Client{Backend: &Fake{Denied: true}, ApplicationPath: "/path/to/app"}
+
+	_, err := client.Get("API_KEY")
+
+	if !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("expected ErrPermissionDenied, got %v", err)
+	}
+}
+
+func TestSecrets_ZeroOverwritesValues(t *testing.T) {
+	secrets := Secrets{"API_KEY": []byte("secret")}
+	value := secrets["API_KEY"]
+
+	secrets.Zero()
+
+	if string(value) != "\x00\x00\x00\x00\x00\x00" {
+		t.Errorf("expected zeroed value, got %q", value)
+	}
+}
+
+func TestAgentBackend_FetchesFromAgent(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "agent.sock")
+	server := &agent.Server{Fetch: func(applicationPath string, envNames []string, peerPID int) (map[string]string, error) {
+		return map[string]string{"API_KEY": "from-agent"}, nil
+	}}
+	go server.ListenAndServe(socketPath)
+	agentClient := &agent.Client{SocketPath: socketPath}
+	t.Cleanup(func() { agentClient.Stop() })
+	for i := 0; i < 100; i++ {
+		if _, err := agentClient.Status(); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	applicationPath, _ := Executable()
+	secrets, err := (&AgentBackend{SocketPath: socketPath}).Fetch(applicationPath, []string{"API_KEY"})
+
+	if err != nil || string(secrets["API_KEY"]) != "from-agent" {
+		t.Errorf("expected value from agent, got %q, %v", secrets, err)
+	}
+}
+
+func TestCommandBackend_ParsesFetchOutput(t *testing.T) {
+	command := filepath.Join(t.TempDir(), "with-secure-env")
+	os.WriteFile(command, []byte("#!/bin/sh\n[ \"$1 $2 $3\" = 'fetch /path/to/app API_KEY' ] || exit 1\necho '{\"API_KEY\":\"c2VjcmV0\"}'\n"), 0755)
+
+	secrets, err := (&CommandBackend{Path: command}).Fetch("/path/to/app", []string{"API_KEY"})
+
+	if err != nil || string(secrets["API_KEY"]) != "secret" {
+		t.Errorf("expected API_KEY=secret, got %q, %v", secrets, err)
+	}
+}